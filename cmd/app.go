@@ -1,14 +1,61 @@
+// Команда cache - сервер кеша и клиент к нему:
+//
+//	cache serve [-http :8080] [-addr :6379] [-grpc :9090] - запуск сервера
+//	cache get <key>                                       - чтение значения
+//	cache set [-ttl 30s] <key> <value>                    - запись значения
+//	cache del <key>                                       - удаление
+//	cache stats                                           - статистика
+//	cache repl                                            - интерактивный режим
+//
+// Настройки serve читаются из YAML-файла -config (или CACHE_CONFIG) и переменных CACHE_*,
+// явно заданные флаги важнее их, см. config. Клиентские команды работают через HTTP API сервера,
+// адрес задается флагом -server
 package main
 
 import (
-	"InMemoryCache/internal"
-	"time"
+	"fmt"
+	"os"
 )
 
+const usage = `usage: cache <command> [flags] [args]
+
+commands:
+  serve                 start the cache server
+  get <key>             print the value of key
+  set <key> <value>     store value (JSON or plain string)
+  del <key>             delete key
+  stats                 print cache statistics
+  repl                  interactive shell
+
+run "cache <command> -h" for command flags
+`
+
 func main() {
-	cache := internal.NewInMemoryCache(
-		time.Duration(10), time.Duration(20),
-	)
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cmd, args := os.Args[1], os.Args[2:]
+
+	var err error
+	switch cmd {
+	case "serve":
+		err = serve(args)
+	case "get", "set", "del", "stats":
+		err = runClient(cmd, args)
+	case "repl":
+		err = repl(args)
+	case "-h", "-help", "--help", "help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
 
-	cache.Set("server", "https://www.google.comd", time.Duration(20))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cache:", err)
+		os.Exit(1)
+	}
 }
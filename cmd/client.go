@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultServer - адрес HTTP API, который слушает cache serve по-умолчанию
+const defaultServer = "http://127.0.0.1:8080"
+
+// client - клиент HTTP API кеша (см. internal/httpapi)
+type client struct {
+	base string
+	http *http.Client
+}
+
+func newClient(server string) *client {
+	if !strings.Contains(server, "://") {
+		server = "http://" + server
+	}
+
+	return &client{base: strings.TrimRight(server, "/"), http: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// runClient выполняет одну клиентскую команду, разобрав ее флаги
+func runClient(cmd string, args []string) error {
+	flags := flag.NewFlagSet(cmd, flag.ExitOnError)
+	server := flags.String("server", defaultServer, "адрес HTTP API сервера")
+	ttl := flags.Duration("ttl", 0, "время жизни для set, 0 - время жизни сервера по-умолчанию")
+	flags.Parse(args)
+
+	return newClient(*server).exec(os.Stdout, cmd, flags.Args(), *ttl)
+}
+
+// exec выполняет команду cmd с аргументами args и печатает результат в out
+func (c *client) exec(out io.Writer, cmd string, args []string, ttl time.Duration) error {
+	switch cmd {
+	case "get":
+		if len(args) != 1 {
+			return errors.New("usage: get <key>")
+		}
+		return c.get(out, args[0])
+	case "set":
+		if len(args) < 2 {
+			return errors.New("usage: set <key> <value>")
+		}
+		return c.set(args[0], strings.Join(args[1:], " "), ttl)
+	case "del":
+		if len(args) != 1 {
+			return errors.New("usage: del <key>")
+		}
+		return c.del(args[0])
+	case "stats":
+		if len(args) != 0 {
+			return errors.New("usage: stats")
+		}
+		return c.stats(out)
+	}
+
+	return fmt.Errorf("unknown command %q", cmd)
+}
+
+func (c *client) get(out io.Writer, key string) error {
+	var entry struct {
+		Value json.RawMessage `json:"value"`
+		TTL   *float64        `json:"ttl"`
+	}
+	if err := c.do(http.MethodGet, "/cache/"+url.PathEscape(key), nil, &entry); err != nil {
+		return err
+	}
+
+	if entry.TTL != nil {
+		fmt.Fprintf(out, "%s (ttl %s)\n", entry.Value, time.Duration(*entry.TTL*float64(time.Second)).Round(time.Second))
+	} else {
+		fmt.Fprintf(out, "%s\n", entry.Value)
+	}
+
+	return nil
+}
+
+// set записывает value как JSON, а если это не JSON - как строку
+func (c *client) set(key, value string, ttl time.Duration) error {
+	body := []byte(value)
+	if !json.Valid(body) {
+		body, _ = json.Marshal(value)
+	}
+
+	path := "/cache/" + url.PathEscape(key)
+	if ttl > 0 {
+		path += "?ttl=" + url.QueryEscape(ttl.String())
+	}
+
+	return c.do(http.MethodPut, path, body, nil)
+}
+
+func (c *client) del(key string) error {
+	return c.do(http.MethodDelete, "/cache/"+url.PathEscape(key), nil, nil)
+}
+
+func (c *client) stats(out io.Writer) error {
+	var stats json.RawMessage
+	if err := c.do(http.MethodGet, "/stats", nil, &stats); err != nil {
+		return err
+	}
+
+	var indented bytes.Buffer
+	json.Indent(&indented, stats, "", "  ")
+	fmt.Fprintln(out, indented.String())
+
+	return nil
+}
+
+// do выполняет запрос к API и разбирает JSON-ответ в result, ответ с ошибкой возвращается как error
+func (c *client) do(method, path string, body []byte, result interface{}) error {
+	req, err := http.NewRequest(method, c.base+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&e) == nil && e.Error != "" {
+			return errors.New(e.Error)
+		}
+		return errors.New(resp.Status)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"InMemoryCache/internal"
+
+	"gopkg.in/yaml.v3"
+)
+
+// config - настройки cache serve. Значения берутся по возрастанию приоритета из defaultConfig,
+// YAML-файла (-config или CACHE_CONFIG), переменных окружения CACHE_* и явно заданных флагов
+type config struct {
+	// DefaultExpiration - время жизни по-умолчанию, 0 - записи без явного срока бессрочны
+	DefaultExpiration time.Duration `yaml:"default_expiration"`
+	CleanupInterval   time.Duration `yaml:"cleanup_interval"`
+	MaxEntries        int           `yaml:"max_entries"`
+	MaxBytes          int64         `yaml:"max_bytes"`
+	// EvictionPolicy - имя политики: lru, lfu, fifo, arc или reject, пустое - по-умолчанию
+	EvictionPolicy string `yaml:"eviction_policy"`
+
+	Listen struct {
+		Redis string `yaml:"redis"`
+		HTTP  string `yaml:"http"`
+		GRPC  string `yaml:"grpc"`
+	} `yaml:"listen"`
+
+	Persistence struct {
+		// Snapshot - файл, из которого кеш загружается при запуске и в который сохраняется при остановке
+		Snapshot string `yaml:"snapshot"`
+		// WAL - журнал, в который пишется каждое изменение, см. internal.NewInMemoryCacheFromWAL
+		WAL string `yaml:"wal"`
+	} `yaml:"persistence"`
+
+	DebugToken string `yaml:"debug_token"`
+}
+
+func defaultConfig() config {
+	var cfg config
+	cfg.DefaultExpiration = time.Hour
+	cfg.CleanupInterval = time.Minute
+	cfg.Listen.HTTP = ":8080"
+
+	return cfg
+}
+
+// loadFile дополняет cfg настройками из YAML-файла path, неизвестные поля считаются ошибкой
+func (cfg *config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// loadEnv дополняет cfg заданными переменными окружения. Пустая строковая переменная
+// сбрасывает значение, например CACHE_LISTEN_HTTP= отключает HTTP
+func (cfg *config) loadEnv(lookup func(string) (string, bool)) error {
+	var errs []error
+
+	duration := func(name string, dst *time.Duration) {
+		if s, _ := lookup(name); s != "" {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+			*dst = d
+		}
+	}
+	integer := func(name string, dst *int64) {
+		if s, _ := lookup(name); s != "" {
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+			*dst = n
+		}
+	}
+	str := func(name string, dst *string) {
+		if s, ok := lookup(name); ok {
+			*dst = s
+		}
+	}
+
+	maxEntries := int64(cfg.MaxEntries)
+
+	duration("CACHE_DEFAULT_EXPIRATION", &cfg.DefaultExpiration)
+	duration("CACHE_CLEANUP_INTERVAL", &cfg.CleanupInterval)
+	integer("CACHE_MAX_ENTRIES", &maxEntries)
+	integer("CACHE_MAX_BYTES", &cfg.MaxBytes)
+	str("CACHE_EVICTION_POLICY", &cfg.EvictionPolicy)
+	str("CACHE_LISTEN_REDIS", &cfg.Listen.Redis)
+	str("CACHE_LISTEN_HTTP", &cfg.Listen.HTTP)
+	str("CACHE_LISTEN_GRPC", &cfg.Listen.GRPC)
+	str("CACHE_SNAPSHOT", &cfg.Persistence.Snapshot)
+	str("CACHE_WAL", &cfg.Persistence.WAL)
+	str("CACHE_DEBUG_TOKEN", &cfg.DebugToken)
+
+	cfg.MaxEntries = int(maxEntries)
+
+	return errors.Join(errs...)
+}
+
+// validate проверяет настройки, не зависящие от сочетания опций кеша: его проверяет сборка кеша
+func (cfg config) validate() error {
+	switch {
+	case cfg.DefaultExpiration < 0:
+		return errors.New("default_expiration must not be negative")
+	case cfg.CleanupInterval < 0:
+		return errors.New("cleanup_interval must not be negative")
+	case cfg.Listen.Redis == "" && cfg.Listen.HTTP == "" && cfg.Listen.GRPC == "":
+		return errors.New("at least one listen address is required")
+	case cfg.Persistence.Snapshot != "" && cfg.Persistence.WAL != "":
+		return errors.New("persistence: snapshot and wal are mutually exclusive")
+	}
+
+	return nil
+}
+
+// options возвращает опции кеша по настройкам
+func (cfg config) options() ([]internal.Option, error) {
+	opts := []internal.Option{internal.WithMaxEntries(cfg.MaxEntries)}
+
+	if cfg.MaxBytes > 0 {
+		opts = append(opts, internal.WithMaxBytes(cfg.MaxBytes))
+	}
+
+	if cfg.EvictionPolicy != "" {
+		policy, err := internal.ParseEvictionPolicy(cfg.EvictionPolicy)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, internal.WithEvictionPolicy(policy))
+	}
+
+	if cfg.Persistence.Snapshot != "" {
+		opts = append(opts, internal.WithPersistOnClose(cfg.Persistence.Snapshot))
+	}
+
+	// Самые читаемые ключи в /debug/cache считаются только с WithHitCounting
+	if cfg.Listen.HTTP != "" {
+		opts = append(opts, internal.WithHitCounting())
+	}
+
+	return opts, nil
+}
+
+// defaultTTL возвращает время жизни по-умолчанию для кеша, 0 в настройках означает NoExpiration
+func (cfg config) defaultTTL() time.Duration {
+	if cfg.DefaultExpiration == 0 {
+		return internal.NoExpiration
+	}
+
+	return cfg.DefaultExpiration
+}
+
+// newCache создает кеш по настройкам, при заданном snapshot загружает его содержимое
+func (cfg config) newCache() (*internal.InMemoryCache, error) {
+	opts, err := cfg.options()
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Persistence.WAL != "" {
+		return internal.NewInMemoryCacheFromWAL(cfg.Persistence.WAL, cfg.defaultTTL(), cfg.CleanupInterval, opts...)
+	}
+
+	cache, err := internal.NewBuilder().
+		DefaultTTL(cfg.defaultTTL()).
+		CleanupInterval(cfg.CleanupInterval).
+		With(opts...).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+
+	c := cache.(*internal.InMemoryCache)
+	if path := cfg.Persistence.Snapshot; path != "" {
+		// Кеш не закрываем: Close сохранил бы пустой кеш поверх снимка
+		if err := c.LoadFile(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
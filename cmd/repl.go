@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const replHelp = `commands:
+  get <key>
+  set <key> <value> [ttl]   ttl is a Go duration, e.g. 30s
+  del <key>
+  stats
+  help
+  quit
+`
+
+// repl читает команды со стандартного ввода и выполняет их на сервере до quit или конца ввода
+func repl(args []string) error {
+	flags := flag.NewFlagSet("repl", flag.ExitOnError)
+	server := flags.String("server", defaultServer, "адрес HTTP API сервера")
+	flags.Parse(args)
+
+	c := newClient(*server)
+	in := bufio.NewScanner(os.Stdin)
+
+	fmt.Printf("connected to %s, type help for commands\n", c.base)
+	for {
+		fmt.Print("> ")
+		if !in.Scan() {
+			fmt.Println()
+			return in.Err()
+		}
+
+		fields := strings.Fields(in.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		cmd, cmdArgs := strings.ToLower(fields[0]), fields[1:]
+		switch cmd {
+		case "quit", "exit":
+			return nil
+		case "help":
+			fmt.Print(replHelp)
+			continue
+		}
+
+		var ttl time.Duration
+		// Последний аргумент set, похожий на длительность, считается временем жизни
+		if cmd == "set" && len(cmdArgs) > 2 {
+			if d, err := time.ParseDuration(cmdArgs[len(cmdArgs)-1]); err == nil {
+				ttl, cmdArgs = d, cmdArgs[:len(cmdArgs)-1]
+			}
+		}
+
+		if err := c.exec(os.Stdout, cmd, cmdArgs, ttl); err != nil {
+			fmt.Println("error:", err)
+		} else if cmd == "set" || cmd == "del" {
+			fmt.Println("OK")
+		}
+	}
+}
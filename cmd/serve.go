@@ -0,0 +1,121 @@
+package main
+
+import (
+	"expvar"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"InMemoryCache/internal/debugapi"
+	"InMemoryCache/internal/grpcapi"
+	"InMemoryCache/internal/httpapi"
+	"InMemoryCache/internal/server"
+
+	"google.golang.org/grpc"
+)
+
+// serve запускает сервер кеша на заданных адресах и работает до SIGINT или SIGTERM
+func serve(args []string) error {
+	cfg := defaultConfig()
+
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	configPath := flags.String("config", os.Getenv("CACHE_CONFIG"), "YAML-файл настроек")
+	flags.StringVar(&cfg.Listen.Redis, "addr", cfg.Listen.Redis, "адрес сервера Redis, например :6379")
+	flags.StringVar(&cfg.Listen.HTTP, "http", cfg.Listen.HTTP, "адрес HTTP API, пустой - без HTTP")
+	flags.StringVar(&cfg.Listen.GRPC, "grpc", cfg.Listen.GRPC, "адрес сервиса gRPC, например :9090")
+	flags.DurationVar(&cfg.DefaultExpiration, "ttl", cfg.DefaultExpiration, "время жизни по-умолчанию для записей без явного срока, 0 - бессрочно")
+	flags.DurationVar(&cfg.CleanupInterval, "cleanup", cfg.CleanupInterval, "интервал GC")
+	flags.StringVar(&cfg.DebugToken, "debug-token", cfg.DebugToken, "токен для POST /debug/cache/*, без него очистка через HTTP запрещена")
+	flags.Parse(args)
+
+	// Флаги важнее файла и окружения, поэтому заданные явно применяются повторно после них
+	explicit := make(map[string]string)
+	flags.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = f.Value.String()
+	})
+
+	if *configPath != "" {
+		if err := cfg.loadFile(*configPath); err != nil {
+			return err
+		}
+	}
+	if err := cfg.loadEnv(os.LookupEnv); err != nil {
+		return err
+	}
+	for name, value := range explicit {
+		flags.Set(name, value)
+	}
+
+	if err := cfg.validate(); err != nil {
+		return fmt.Errorf("serve: %w", err)
+	}
+
+	cache, err := cfg.newCache()
+	if err != nil {
+		return fmt.Errorf("serve: %w", err)
+	}
+	defer cache.Close()
+
+	srv := server.New(cache)
+
+	debugapi.Publish("cache", cache)
+	debug := debugapi.New(cache, cfg.DebugToken)
+
+	mux := http.NewServeMux()
+	mux.Handle("/", httpapi.New(cache))
+	mux.Handle("/debug/cache", debug)
+	mux.Handle("/debug/cache/", debug)
+	mux.Handle("/debug/vars", expvar.Handler())
+	api := &http.Server{Addr: cfg.Listen.HTTP, Handler: mux}
+
+	rpc := grpc.NewServer()
+	grpcapi.Register(rpc, cache)
+
+	errs := make(chan error, 3)
+
+	if cfg.Listen.Redis != "" {
+		go func() {
+			log.Printf("serving Redis protocol on %s", cfg.Listen.Redis)
+			errs <- srv.ListenAndServe(cfg.Listen.Redis)
+		}()
+	}
+
+	if cfg.Listen.HTTP != "" {
+		go func() {
+			log.Printf("serving HTTP API on %s", cfg.Listen.HTTP)
+			errs <- api.ListenAndServe()
+		}()
+	}
+
+	if cfg.Listen.GRPC != "" {
+		go func() {
+			l, err := net.Listen("tcp", cfg.Listen.GRPC)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			log.Printf("serving gRPC on %s", cfg.Listen.GRPC)
+			errs <- rpc.Serve(l)
+		}()
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	select {
+	case <-signals:
+	case err = <-errs:
+	}
+
+	srv.Close()
+	api.Close()
+	rpc.GracefulStop()
+
+	return err
+}
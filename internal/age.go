@@ -0,0 +1,63 @@
+package internal
+
+import "time"
+
+// Oldest возвращает живой элемент с самым ранним временем записи,
+// ok = false для пустого кеша
+func (c *InMemoryCache) Oldest() (key string, value interface{}, ok bool) {
+	return c.extremeByCreation(func(a, b Item) bool {
+		return a.createdAt.Before(b.createdAt)
+	})
+}
+
+// Newest возвращает живой элемент с самым поздним временем записи,
+// ok = false для пустого кеша
+func (c *InMemoryCache) Newest() (key string, value interface{}, ok bool) {
+	return c.extremeByCreation(func(a, b Item) bool {
+		return a.createdAt.After(b.createdAt)
+	})
+}
+
+// DeleteCreatedBefore удаляет под одной блокировкой все элементы, записанные раньше t,
+// и возвращает их количество
+func (c *InMemoryCache) DeleteCreatedBefore(t time.Time) int {
+	c.rmu.Lock()
+	defer c.unlock()
+
+	deleted := 0
+	for k, i := range c.cache {
+		if i.createdAt.Before(t) && c.evict(k, ReasonDeleted) {
+			deleted++
+		}
+	}
+
+	return deleted
+}
+
+// extremeByCreation ищет живой элемент, для которого better истинна относительно всех остальных
+func (c *InMemoryCache) extremeByCreation(better func(a, b Item) bool) (string, interface{}, bool) {
+	c.rmu.RLock()
+	defer c.rmu.RUnlock()
+
+	var (
+		bestKey  string
+		bestItem Item
+		found    bool
+	)
+
+	for k, i := range c.cache {
+		if c.expired(i) {
+			continue
+		}
+
+		if !found || better(i, bestItem) {
+			bestKey, bestItem, found = k, i, true
+		}
+	}
+
+	if !found {
+		return "", nil, false
+	}
+
+	return bestKey, c.itemValue(bestItem), true
+}
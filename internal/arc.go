@@ -0,0 +1,127 @@
+package internal
+
+import "container/list"
+
+// arcPolicy - Adaptive Replacement Cache (Megiddo, Modha). t1 и t2 - хранящиеся ключи,
+// прочитанные один и несколько раз, b1 и b2 - недавно вытесненные из них ключи без значений.
+// Повторная запись ключа из b1 увеличивает целевой размер t1, из b2 - уменьшает,
+// так политика подстраивается под соотношение давности и частоты обращений
+type arcPolicy struct {
+	capacity int
+	// target - целевой размер t1
+	target         int
+	t1, t2, b1, b2 *list.List
+	entries        map[string]*arcEntry
+}
+
+type arcEntry struct {
+	elem *list.Element
+	list *list.List
+}
+
+func newARCPolicy(capacity int) *arcPolicy {
+	p := &arcPolicy{capacity: capacity}
+	p.reset()
+
+	return p
+}
+
+func (p *arcPolicy) reset() {
+	p.target = 0
+	p.t1, p.t2, p.b1, p.b2 = list.New(), list.New(), list.New(), list.New()
+	p.entries = make(map[string]*arcEntry)
+}
+
+func (p *arcPolicy) added(key string) {
+	entry, found := p.entries[key]
+	if !found {
+		p.trimGhosts()
+		p.push(key, p.t1)
+		return
+	}
+
+	// Ключ недавно вытеснен: адаптируем целевой размер t1 и считаем ключ частым
+	switch entry.list {
+	case p.b1:
+		p.target = min(p.capacity, p.target+max(p.b2.Len()/p.b1.Len(), 1))
+	case p.b2:
+		p.target = max(0, p.target-max(p.b1.Len()/p.b2.Len(), 1))
+	}
+
+	p.move(entry, p.t2)
+}
+
+func (p *arcPolicy) accessed(key string) {
+	if entry, found := p.entries[key]; found && (entry.list == p.t1 || entry.list == p.t2) {
+		p.move(entry, p.t2)
+	}
+}
+
+func (p *arcPolicy) removed(key string) {
+	// Вытесненный victim ключ уже перенесен в b1 или b2 и должен там остаться
+	if entry, found := p.entries[key]; found && (entry.list == p.t1 || entry.list == p.t2) {
+		entry.list.Remove(entry.elem)
+		delete(p.entries, key)
+	}
+}
+
+func (p *arcPolicy) victim(incoming string, skip func(key string) bool) (string, bool) {
+	from, other := p.t2, p.t1
+
+	entry, found := p.entries[incoming]
+	inB2 := found && entry.list == p.b2
+	if p.t1.Len() > 0 && (p.t1.Len() > p.target || (inB2 && p.t1.Len() == p.target)) {
+		from, other = p.t1, p.t2
+	}
+
+	for _, l := range []*list.List{from, other} {
+		for e := l.Back(); e != nil; e = e.Prev() {
+			key := e.Value.(string)
+			if skip(key) {
+				continue
+			}
+
+			ghost := p.b1
+			if l == p.t2 {
+				ghost = p.b2
+			}
+			p.move(p.entries[key], ghost)
+
+			return key, true
+		}
+	}
+
+	return "", false
+}
+
+// trimGhosts ограничивает списки вытесненных ключей: |t1|+|b1| <= capacity, всего ключей <= 2*capacity
+func (p *arcPolicy) trimGhosts() {
+	if p.t1.Len()+p.b1.Len() >= p.capacity && p.b1.Len() > 0 {
+		p.drop(p.b1)
+	}
+
+	if p.t1.Len()+p.t2.Len()+p.b1.Len()+p.b2.Len() >= 2*p.capacity && p.b2.Len() > 0 {
+		p.drop(p.b2)
+	}
+}
+
+// push добавляет ключ в начало списка l
+func (p *arcPolicy) push(key string, l *list.List) {
+	p.entries[key] = &arcEntry{elem: l.PushFront(key), list: l}
+}
+
+// move переносит ключ в начало списка l
+func (p *arcPolicy) move(entry *arcEntry, l *list.List) {
+	if entry.list == l {
+		l.MoveToFront(entry.elem)
+		return
+	}
+
+	key := entry.list.Remove(entry.elem).(string)
+	entry.elem, entry.list = l.PushFront(key), l
+}
+
+// drop забывает самый старый ключ списка l
+func (p *arcPolicy) drop(l *list.List) {
+	delete(p.entries, l.Remove(l.Back()).(string))
+}
@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// scanTrace возвращает последовательность ключей, в которой обращения к горячему набору
+// из hot ключей прерываются длинными однократными сканированиями
+func scanTrace(hot, scan, length int) []string {
+	r := rand.New(rand.NewSource(1))
+	trace := make([]string, 0, length)
+	scanned := 0
+	for len(trace) < length {
+		if r.Intn(200) == 0 {
+			for i := 0; i < scan && len(trace) < length; i++ {
+				trace = append(trace, fmt.Sprintf("scan:%d", scanned))
+				scanned++
+			}
+			continue
+		}
+		trace = append(trace, fmt.Sprintf("hot:%d", r.Intn(hot)))
+	}
+
+	return trace
+}
+
+// benchmarkHitRatio воспроизводит трассу на кеше емкостью 100 и сообщает долю попаданий
+func benchmarkHitRatio(b *testing.B, policy EvictionPolicy) {
+	trace := scanTrace(80, 150, 100000)
+	c := NewInMemoryCache(0, 0, WithMaxEntries(100), WithEvictionPolicy(policy))
+	defer c.Close()
+
+	hits := 0
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := trace[i%len(trace)]
+		if _, found := c.Get(key); found {
+			hits++
+		} else {
+			c.Set(key, i, DefaultExpiration)
+		}
+	}
+
+	b.ReportMetric(float64(hits)/float64(b.N), "hits/op")
+}
+
+func BenchmarkHitRatioLRU(b *testing.B) {
+	benchmarkHitRatio(b, PolicyLRU)
+}
+
+func BenchmarkHitRatioARC(b *testing.B) {
+	benchmarkHitRatio(b, PolicyARC)
+}
@@ -0,0 +1,84 @@
+package internal
+
+import "sync"
+
+// asyncWriter переносит в хранилище элементы, записанные Set в режиме WithAsyncWrites
+type asyncWriter struct {
+	mu     sync.RWMutex
+	closed bool
+	queue  chan queuedWrite
+	done   chan struct{}
+}
+
+type queuedWrite struct {
+	key  string
+	item Item
+	// barrier закрывается, когда все записи перед ним применены (см. flush)
+	barrier chan struct{}
+}
+
+func newAsyncWriter(c *InMemoryCache, bufferSize int) *asyncWriter {
+	w := &asyncWriter{
+		queue: make(chan queuedWrite, bufferSize),
+		done:  make(chan struct{}),
+	}
+
+	go w.run(c)
+
+	return w
+}
+
+// enqueue ставит запись в буфер, возвращает false, если буфер уже закрыт
+func (w *asyncWriter) enqueue(key string, item Item) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return false
+	}
+
+	w.queue <- queuedWrite{key: key, item: item}
+
+	return true
+}
+
+func (w *asyncWriter) run(c *InMemoryCache) {
+	defer close(w.done)
+
+	for write := range w.queue {
+		if write.barrier != nil {
+			close(write.barrier)
+			continue
+		}
+
+		c.store(write.key, write.item)
+	}
+}
+
+// flush дожидается применения всех записей, поставленных в буфер до вызова
+func (w *asyncWriter) flush() {
+	w.mu.RLock()
+	if w.closed {
+		w.mu.RUnlock()
+		<-w.done
+		return
+	}
+
+	barrier := make(chan struct{})
+	w.queue <- queuedWrite{barrier: barrier}
+	w.mu.RUnlock()
+
+	<-barrier
+}
+
+// close закрывает буфер и ждет, пока все записи из него будут применены
+func (w *asyncWriter) close() {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+
+	<-w.done
+}
@@ -0,0 +1,241 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultBatchConcurrency - число параллельных вычислений в пакетных операциях по-умолчанию
+const defaultBatchConcurrency = 8
+
+// BatchOption настраивает пакетные вычисления GetMultiCompute и LoadAll
+type BatchOption func(*batchOptions)
+
+type batchOptions struct {
+	concurrency  int
+	failFast     bool
+	allOrNothing bool
+}
+
+// WithConcurrency ограничивает число одновременных вычислений
+func WithConcurrency(n int) BatchOption {
+	return func(o *batchOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithFailFast прекращает запуск новых вычислений после первой ошибки и возвращает только ее.
+// Без опции вычисляются все ключи, а ошибки объединяются через errors.Join
+func WithFailFast() BatchOption {
+	return func(o *batchOptions) {
+		o.failFast = true
+	}
+}
+
+// WithAllOrNothing заставляет LoadAll записывать загруженные значения в кеш, только если
+// загрузка всех ключей завершилась успешно. Без опции успешно загруженные значения
+// записываются сразу, даже если загрузка другого ключа затем завершится ошибкой
+func WithAllOrNothing() BatchOption {
+	return func(o *batchOptions) {
+		o.allOrNothing = true
+	}
+}
+
+func newBatchOptions(opts []BatchOption) batchOptions {
+	o := batchOptions{concurrency: defaultBatchConcurrency}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.concurrency < 1 {
+		o.concurrency = 1
+	}
+
+	return o
+}
+
+// GetMultiCompute возвращает значения ключей keys. Найденные в кеше значения
+// возвращаются без пересчета, отсутствующие вычисляются функцией compute параллельно
+// (см. WithConcurrency) и записываются в кеш. Вычисления одного ключа
+// не дублируются, как в GetOrCompute. В результат попадают все успешно полученные значения,
+// даже если часть вычислений завершилась ошибкой
+func (c *InMemoryCache) GetMultiCompute(
+	keys []string,
+	compute func(key string) (interface{}, time.Duration, error),
+	opts ...BatchOption,
+) (map[string]interface{}, error) {
+	o := newBatchOptions(opts)
+	results, missing := c.getMany(keys)
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		errs   []error
+		sem    = make(chan struct{}, o.concurrency)
+		failed bool
+	)
+
+	for _, key := range missing {
+		mu.Lock()
+		stop := o.failFast && failed
+		mu.Unlock()
+
+		if stop {
+			break
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+
+		go func(key string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			value, err := c.GetOrCompute(key, func() (interface{}, time.Duration, error) {
+				return compute(key)
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				failed = true
+				errs = append(errs, fmt.Errorf("key '%s': %w", key, err))
+				return
+			}
+
+			results[key] = value
+		}(key)
+	}
+
+	wg.Wait()
+
+	if len(errs) == 0 {
+		return results, nil
+	}
+
+	if o.failFast {
+		return results, errs[0]
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// LoadAll возвращает значения ключей keys, загружая отсутствующие в кеше функцией load параллельно
+// (см. WithConcurrency). Одновременные загрузки одного ключа, в том числе из разных вызовов LoadAll,
+// выполняются один раз. Первая ошибка загрузки отменяет контекст остальных загрузок и возвращается
+// вместе с уже полученными значениями, а при WithAllOrNothing - без них.
+// Отмена ctx прекращает ожидание и возвращает ctx.Err()
+func (c *InMemoryCache) LoadAll(
+	ctx context.Context,
+	keys []string,
+	load func(ctx context.Context, key string) (interface{}, time.Duration, error),
+	opts ...BatchOption,
+) (map[string]interface{}, error) {
+	o := newBatchOptions(opts)
+	results, missing := c.getMany(keys)
+
+	loadCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type loaded struct {
+		key   string
+		item  Item
+		value interface{}
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		items    []loaded
+		sem      = make(chan struct{}, o.concurrency)
+	)
+
+spawn:
+	for _, key := range missing {
+		select {
+		case sem <- struct{}{}:
+		case <-loadCtx.Done():
+			break spawn
+		}
+
+		wg.Add(1)
+
+		go func(key string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			value, duration, err := c.flights.do(loadCtx, key, func() (interface{}, time.Duration, error) {
+				return load(loadCtx, key)
+			})
+
+			if err != nil {
+				mu.Lock()
+				defer mu.Unlock()
+
+				if firstErr == nil {
+					firstErr = fmt.Errorf("key '%s': %w", key, err)
+					cancel()
+				}
+				return
+			}
+
+			l := loaded{key: key, item: c.newItem(key, value, duration), value: value}
+			if !o.allOrNothing {
+				c.store(l.key, l.item)
+			}
+
+			mu.Lock()
+			items = append(items, l)
+			mu.Unlock()
+		}(key)
+	}
+
+	wg.Wait()
+
+	// Отмена ctx останавливает запуск загрузок без ошибки самих загрузок
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+
+	if firstErr != nil && o.allOrNothing {
+		return nil, firstErr
+	}
+
+	for _, l := range items {
+		if o.allOrNothing {
+			c.store(l.key, l.item)
+		}
+		results[l.key] = l.value
+	}
+
+	return results, firstErr
+}
+
+// getMany читает ключи под одной блокировкой, возвращает найденные значения и отсутствующие ключи
+func (c *InMemoryCache) getMany(keys []string) (map[string]interface{}, []string) {
+	c.rmu.RLock()
+	defer c.rmu.RUnlock()
+
+	found := make(map[string]interface{}, len(keys))
+	var missing []string
+
+	for _, k := range keys {
+		if value, ok := c.get(k); ok {
+			found[k] = value
+		} else {
+			missing = append(missing, k)
+		}
+	}
+
+	return found, missing
+}
@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"hash/maphash"
+	"sync/atomic"
+)
+
+const (
+	// bloomBitsPerKey и bloomHashes дают около 1% ложных срабатываний при расчетном количестве ключей
+	bloomBitsPerKey = 10
+	bloomHashes     = 7
+)
+
+// bloomFilter - фильтр Блума по записанным ключам. Биты выставляются под блокировкой
+// на запись кеша, а читаются без нее, поэтому хранятся в атомарных словах
+type bloomFilter struct {
+	seed     maphash.Seed
+	bits     []atomic.Uint64
+	size     uint64
+	capacity int
+	// added - количество ключей, добавленных с момента создания, изменяется под блокировкой на запись
+	added int
+}
+
+func newBloomFilter(capacity int) *bloomFilter {
+	words := (capacity*bloomBitsPerKey + 63) / 64
+	if words == 0 {
+		words = 1
+	}
+
+	return &bloomFilter{
+		seed:     maphash.MakeSeed(),
+		bits:     make([]atomic.Uint64, words),
+		size:     uint64(words) * 64,
+		capacity: capacity,
+	}
+}
+
+// add добавляет ключ, вызывается под блокировкой на запись
+func (f *bloomFilter) add(key string) {
+	h1, h2 := f.hash(key)
+	for i := uint64(0); i < bloomHashes; i++ {
+		bit := (h1 + i*h2) % f.size
+		f.bits[bit/64].Or(1 << (bit % 64))
+	}
+
+	f.added++
+}
+
+// mayContain возвращает false, только если ключ точно не добавлялся
+func (f *bloomFilter) mayContain(key string) bool {
+	h1, h2 := f.hash(key)
+	for i := uint64(0); i < bloomHashes; i++ {
+		bit := (h1 + i*h2) % f.size
+		if f.bits[bit/64].Load()&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// saturated сообщает, что в фильтр добавлено больше ключей, чем он рассчитан,
+// и доля ложных срабатываний выросла
+func (f *bloomFilter) saturated() bool {
+	return f.added > f.capacity
+}
+
+// hash возвращает два хеша ключа для двойного хеширования
+func (f *bloomFilter) hash(key string) (uint64, uint64) {
+	h := maphash.String(f.seed, key)
+	return h & 0xffffffff, h>>32 | 1
+}
+
+// definitelyAbsent сообщает, что ключ точно не записывался и его можно не искать.
+// Без WithBloomFilter всегда возвращает false
+func (c *InMemoryCache) definitelyAbsent(key string) bool {
+	f := c.bloom.Load()
+	return f != nil && !f.mayContain(key)
+}
+
+// addToBloom добавляет ключ в фильтр Блума, вызывается под блокировкой на запись
+func (c *InMemoryCache) addToBloom(key string) {
+	if f := c.bloom.Load(); f != nil {
+		f.add(key)
+	}
+}
+
+// rebuildBloom пересоздает фильтр Блума по ключам хранилища, отбрасывая удаленные ключи.
+// Если force не задан, фильтр пересоздается только переполненным. Вызывается под блокировкой на запись
+func (c *InMemoryCache) rebuildBloom(force bool) {
+	f := c.bloom.Load()
+	if f == nil || !force && !f.saturated() {
+		return
+	}
+
+	// Запас, чтобы растущий кеш не пересоздавал фильтр на каждом проходе GC
+	capacity := c.bloomKeys
+	if n := 2 * len(c.cache); n > capacity {
+		capacity = n
+	}
+
+	rebuilt := newBloomFilter(capacity)
+	for key := range c.cache {
+		rebuilt.add(key)
+	}
+
+	c.bloom.Store(rebuilt)
+}
@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"fmt"
+	"time"
+)
+
+// CacheBuilder собирает кеш пошагово как альтернатива опциям NewInMemoryCache:
+//
+//	cache, err := NewBuilder().DefaultTTL(time.Minute).CleanupInterval(time.Second).LockStriping(16).Build()
+//
+// Настройки проверяются в Build
+type CacheBuilder struct {
+	defaultExpiration time.Duration
+	cleanupInterval   time.Duration
+	opts              []Option
+}
+
+// NewBuilder создает построитель кеша без времени жизни по-умолчанию и без GC
+func NewBuilder() *CacheBuilder {
+	return &CacheBuilder{}
+}
+
+// DefaultTTL задает время жизни элементов, записанных с нулевой продолжительностью, NoExpiration - бессрочно
+func (b *CacheBuilder) DefaultTTL(d time.Duration) *CacheBuilder {
+	b.defaultExpiration = d
+	return b
+}
+
+// CleanupInterval задает период GC, 0 отключает GC
+func (b *CacheBuilder) CleanupInterval(d time.Duration) *CacheBuilder {
+	b.cleanupInterval = d
+	return b
+}
+
+// LockStriping - см. WithLockStriping
+func (b *CacheBuilder) LockStriping(stripes int) *CacheBuilder {
+	return b.With(WithLockStriping(stripes))
+}
+
+// InitialCapacity - см. WithInitialCapacity
+func (b *CacheBuilder) InitialCapacity(capacity int) *CacheBuilder {
+	return b.With(WithInitialCapacity(capacity))
+}
+
+// MaxEntries - см. WithMaxEntries
+func (b *CacheBuilder) MaxEntries(maxEntries int) *CacheBuilder {
+	return b.With(WithMaxEntries(maxEntries))
+}
+
+// Policy - см. WithEvictionPolicy
+func (b *CacheBuilder) Policy(policy EvictionPolicy) *CacheBuilder {
+	return b.With(WithEvictionPolicy(policy))
+}
+
+// MaxKeyLength - см. WithMaxKeyLength
+func (b *CacheBuilder) MaxKeyLength(maxLength int) *CacheBuilder {
+	return b.With(WithMaxKeyLength(maxLength))
+}
+
+// AsyncWrites - см. WithAsyncWrites
+func (b *CacheBuilder) AsyncWrites(bufferSize int) *CacheBuilder {
+	return b.With(WithAsyncWrites(bufferSize))
+}
+
+// KeyLocking - см. WithKeyLocking
+func (b *CacheBuilder) KeyLocking() *CacheBuilder {
+	return b.With(WithKeyLocking())
+}
+
+// HitCounting - см. WithHitCounting
+func (b *CacheBuilder) HitCounting() *CacheBuilder {
+	return b.With(WithHitCounting())
+}
+
+// PrefixIndex - см. WithPrefixIndex
+func (b *CacheBuilder) PrefixIndex() *CacheBuilder {
+	return b.With(WithPrefixIndex())
+}
+
+// With добавляет произвольные опции, для которых нет отдельного метода
+func (b *CacheBuilder) With(opts ...Option) *CacheBuilder {
+	b.opts = append(b.opts, opts...)
+	return b
+}
+
+// Build проверяет настройки и создает кеш. Для противоречивых или недопустимых настроек
+// возвращает ошибку, оборачивающую ErrInvalidConfig
+func (b *CacheBuilder) Build() (Cache, error) {
+	if (b.defaultExpiration < 0 && b.defaultExpiration != NoExpiration) || b.cleanupInterval < 0 {
+		return nil, fmt.Errorf("%w: negative default TTL or cleanup interval", ErrInvalidConfig)
+	}
+
+	if err := newOptions(b.opts).validate(); err != nil {
+		return nil, err
+	}
+
+	return NewInMemoryCache(b.defaultExpiration, b.cleanupInterval, b.opts...), nil
+}
+
+// validate проверяет сочетание опций
+func (o options) validate() error {
+	switch {
+	case o.configErr != nil:
+		return o.configErr
+	case o.lockStripes < 0, o.initialCapacity < 0, o.maxKeyLength < 0, o.gcBatchSize < 0, o.asyncWrites < 0, o.maxEntries < 0,
+		o.coarseTick < 0, o.gcBackoffMax < 0, o.expirationGrace < 0, o.bloomKeys < 0, o.staleWindow < 0, o.ttlJitter < 0,
+		o.maxBytes < 0, o.memoryCheckInterval < 0:
+		return fmt.Errorf("%w: negative option value", ErrInvalidConfig)
+	case o.evictionPolicy != 0 && !o.evictionPolicy.known():
+		return fmt.Errorf("%w: unknown eviction policy %d", ErrInvalidConfig, o.evictionPolicy)
+	case o.evictionPolicy != 0 && o.evictionPolicy != PolicyReject && o.maxEntries == 0 && o.maxBytes == 0:
+		return fmt.Errorf("%w: eviction policy %s requires max entries or max bytes", ErrInvalidConfig, o.evictionPolicy)
+	case o.evictionPolicy == PolicyARC && o.maxEntries == 0:
+		return fmt.Errorf("%w: eviction policy %s requires max entries", ErrInvalidConfig, o.evictionPolicy)
+	case o.memoryWatermark > 0 && (o.memoryEvictFraction <= 0 || o.memoryEvictFraction > 1):
+		return fmt.Errorf("%w: memory evict fraction must be in (0, 1]", ErrInvalidConfig)
+	case o.ttlJitter >= 1:
+		return fmt.Errorf("%w: TTL jitter fraction must be in [0, 1)", ErrInvalidConfig)
+	case o.refreshLoader != nil && (o.refreshAhead <= 0 || o.refreshAhead >= 1):
+		return fmt.Errorf("%w: refresh-ahead fraction must be in (0, 1)", ErrInvalidConfig)
+	case o.asyncWrites > 0 && o.keyLocking:
+		// Асинхронная запись применяется после снятия блокировки ключа
+		return fmt.Errorf("%w: async writes cannot be combined with key locking", ErrInvalidConfig)
+	}
+
+	return nil
+}
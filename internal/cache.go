@@ -1,14 +1,32 @@
 package internal
 
 import (
-	"errors"
+	"crypto/cipher"
 	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+const (
+	// NoExpiration - продолжительность жизни бессрочного элемента
+	NoExpiration time.Duration = -1
+	// DefaultExpiration - продолжительность жизни, заданная кешу по-умолчанию.
+	// Без WithStrictTTL то же самое означает 0
+	DefaultExpiration time.Duration = -2
+)
+
+// Cache - основной интерфейс кеша. Close останавливает GC и другие фоновые горутины,
+// брошенный без Close кеш с cleanupInterval > 0 продолжает работать до завершения программы
 type Cache interface {
+	io.Closer
+
 	Get(key string) (interface{}, bool)
+	Has(key string) bool
+	Count() int
 	Set(key string, value interface{}, duration time.Duration)
 	Delete(key string) error
 	Flush()
@@ -18,73 +36,494 @@ type Item struct {
 	value      interface{}
 	createdAt  time.Time
 	expiration int64
+	size       int64
+	compressed bool
+	encrypted  bool
+	encoded    bool
+	access     *itemAccess
+	pinned     bool
+	idle       time.Duration
+	ttl        time.Duration
+}
+
+// Value возвращает значение в том виде, в каком оно хранится,
+// например сжатым при включенной WithCompression или зашифрованным при WithEncryption
+func (i Item) Value() interface{} {
+	return i.value
+}
+
+// CreatedAt возвращает время записи элемента
+func (i Item) CreatedAt() time.Time {
+	return i.createdAt
+}
+
+// Expiration возвращает время истечения, для бессрочного элемента - нулевое время
+func (i Item) Expiration() time.Time {
+	return unixTime(i.expiration)
+}
+
+// unixTime переводит время истечения в наносекундах в time.Time, 0 - в нулевое время
+func unixTime(nano int64) time.Time {
+	if nano == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, nano)
 }
 
 type InMemoryCache struct {
+	options
 	cache             map[string]Item
-	rmu               sync.RWMutex
-	defaultExpiration time.Duration
+	rmu               rwLocker
+	defaultExpiration atomic.Int64
 	cleanupInterval   time.Duration
+	keyBytes          int64
+	valueBytes        int64
+	epoch             time.Time
+	handlers          evictionHandlers
+	pending           []EvictedItem
+	keyLocks          keyMutex
+	userLocks         keyMutex
+	async             *asyncWriter
+	done              chan struct{}
+	closeOnce         sync.Once
+	closed            atomic.Bool
+	deps              depGraph
+	tags              tagIndex
+	prefixes          *prefixTrie
+	gc                gcStats
+	journal           *journal
+	coarseNow         atomic.Int64
+	flights           flightGroup
+	policy            *policyGuard
+	expiryWatches     map[string]*expiryWatch
+	events            eventHub
+	fetches           fetchStats
+	sources           map[string]string
+	bloom             atomic.Pointer[bloomFilter]
+	prefixCounters    prefixCounters
+	aead              cipher.AEAD
+	invalidator       *invalidator
+	evictions         uint64
+	wal               *writeAheadLog
+	lookups           hitCounter
+	expiredRemovals   uint64
+	refreshing        sync.Map
+	expiries          expiryQueue
 }
 
 func (c *InMemoryCache) Get(key string) (interface{}, bool) {
+	value, _, found := c.GetWithHits(key)
+	return value, found
+}
+
+// GetE возвращает значение как Get, а отсутствие живого элемента сообщает ошибкой ErrNotFound,
+// чтобы проверять результат через errors.Is вместе с ошибками других операций
+func (c *InMemoryCache) GetE(key string) (interface{}, error) {
+	value, found := c.Get(key)
+	if !found {
+		return nil, fmt.Errorf("key '%s': %w", key, ErrNotFound)
+	}
+
+	return value, nil
+}
+
+// GetWithExpiration возвращает значение как Get и время его истечения, нулевое для бессрочного
+// элемента, например чтобы выставить соответствующий Cache-Control. Для скользящего элемента
+// возвращается время истечения после продления этим чтением
+func (c *InMemoryCache) GetWithExpiration(key string) (interface{}, time.Time, bool) {
+	value, _, expiration, found := c.getTracked(key)
+	return value, unixTime(expiration), found
+}
+
+// Peek возвращает значение как Get, но не считается обращением к элементу: не меняет порядок
+// вытеснения, время последнего чтения и счетчики попаданий. Предназначен для мониторинга и отладки
+func (c *InMemoryCache) Peek(key string) (interface{}, bool) {
 	c.rmu.RLock()
 	defer c.rmu.RUnlock()
+
+	return c.get(key)
+}
+
+// get возвращает значение по ключу, вызывается под блокировкой
+func (c *InMemoryCache) get(key string) (interface{}, bool) {
 	item, found := c.cache[key]
 
 	if !found {
 		return nil, false
 	}
 
+	// Если в момент запроса кеш устарел возвращаем nil
+	if c.expired(item) {
+		return nil, false
+	}
+
+	return c.itemValue(item), true
+}
+
+// Has проверяет, есть ли в кеше живой элемент с ключом key
+func (c *InMemoryCache) Has(key string) bool {
+	_, found := c.Get(key)
+	return found
+}
+
+// Count возвращает количество элементов в хранилище, включая просроченные,
+// но еще не удаленные GC
+func (c *InMemoryCache) Count() int {
+	c.rmu.RLock()
+	defer c.rmu.RUnlock()
+
+	return len(c.cache)
+}
+
+// expired проверяет, истекло ли время жизни элемента
+func (c *InMemoryCache) expired(item Item) bool {
+	if c.expirationPredicate != nil {
+		return c.checkExpired(item)
+	}
+
 	// Проверка на установку времени истечения, в противном случае он бессрочный
-	if item.expiration > 0 {
+	return item.expiration > 0 && c.nowNano() > item.expiration+int64(c.expirationGrace)
+}
 
-		// Если в момент запроса кеш устарел возвращаем nil
-		if time.Now().UnixNano() > item.expiration {
-			return nil, false
-		}
+// nowNano возвращает текущее время в UnixNano, при WithCoarseClock - время последнего тика
+func (c *InMemoryCache) nowNano() int64 {
+	if c.coarseTick > 0 {
+		return c.coarseNow.Load()
+	}
+
+	return c.preciseNano()
+}
 
+// now возвращает текущее время, при WithClock - время подмененных часов
+func (c *InMemoryCache) now() time.Time {
+	if c.clock != nil {
+		return c.clock.Now()
 	}
 
-	return item.value, true
+	return time.Now()
 }
 
+// preciseNano возвращает текущее время в UnixNano. При WithMonotonicClock время отсчитывается
+// по монотонным часам от создания кеша и не зависит от перевода системных часов
+func (c *InMemoryCache) preciseNano() int64 {
+	if c.clock != nil {
+		return c.clock.Now().UnixNano()
+	}
+
+	if c.monotonicClock {
+		return c.epoch.UnixNano() + int64(time.Since(c.epoch))
+	}
+
+	return time.Now().UnixNano()
+}
+
+// Set записывает значение. Если ключ не может быть записан (например, длиннее WithMaxKeyLength),
+// значение отбрасывается, узнать причину позволяет SetE
 func (c *InMemoryCache) Set(key string, value interface{}, duration time.Duration) {
-	var expiration int64
+	if err := c.SetE(key, value, duration); err != nil {
+		c.logger.Warn("cache set rejected", "key", key, "err", err)
+	}
+}
+
+// SetE записывает значение как Set, но возвращает ошибку, если запись отклонена
+func (c *InMemoryCache) SetE(key string, value interface{}, duration time.Duration) error {
+	t := c.startOp("set")
+	defer t.done()
 
-	// Если продолжительность жизни равна 0 - используется значение по-умолчанию
-	if duration == 0 {
-		duration = c.defaultExpiration
+	// Ключ проверяем до захвата блокировок
+	if err := c.checkKey(key); err != nil {
+		return err
 	}
 
-	// Устанавливаем время истечения кеша
-	if duration > 0 {
-		expiration = time.Now().Add(duration).UnixNano()
+	if c.keyLocking {
+		defer c.keyLocks.lock(key)()
 	}
 
+	// Элемент готовим до захвата блокировки
+	item := c.newItem(key, value, duration)
+
+	// В режиме асинхронной записи элемент запишет отдельная горутина,
+	// буфер закрывается только в Close
+	if c.async != nil {
+		if !c.async.enqueue(key, item) {
+			return ErrClosed
+		}
+
+		return nil
+	}
+
+	return c.storeTimed(key, item, t)
+}
+
+// SetKeepTTL заменяет значение живого элемента, сохраняя его время истечения, как KEEPTTL в Redis.
+// Если элемента нет или он просрочен, значение записывается со временем жизни по-умолчанию.
+// Пишет синхронно и при WithAsyncWrites
+func (c *InMemoryCache) SetKeepTTL(key string, value interface{}) {
+	if err := c.checkKey(key); err != nil {
+		c.logger.Warn("cache set rejected", "key", key, "err", err)
+		return
+	}
+
+	if c.keyLocking {
+		defer c.keyLocks.lock(key)()
+	}
+
+	item := c.newItem(key, value, DefaultExpiration)
+
 	c.rmu.Lock()
-	defer func() {
-		fmt.Printf("Set key: %s value: %v expiration: %v\n", key, value, expiration)
-		c.rmu.Unlock()
-	}()
+	defer c.unlock()
 
-	c.cache[key] = Item{
-		value:      value,
-		createdAt:  time.Now(),
-		expiration: expiration,
+	if old, found := c.cache[key]; found && !c.expired(old) {
+		item.expiration = old.expiration
 	}
+
+	c.storeItem(key, item)
 }
 
-func (c *InMemoryCache) Delete(key string) error {
+// checkKey проверяет, можно ли записать ключ
+func (c *InMemoryCache) checkKey(key string) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+
+	if c.maxKeyLength > 0 && len(key) > c.maxKeyLength {
+		return ErrKeyTooLong
+	}
+
+	return nil
+}
+
+// store записывает элемент, захватывая блокировку хранилища
+func (c *InMemoryCache) store(key string, item Item) error {
+	return c.storeTimed(key, item, nil)
+}
+
+// storeTimed записывает элемент как store, отмечая в t момент захвата блокировки
+func (c *InMemoryCache) storeTimed(key string, item Item, t *opTimer) error {
 	c.rmu.Lock()
-	defer c.rmu.Unlock()
+	t.locked()
+	defer c.unlock()
+
+	if c.skipEqual(key, item) {
+		return nil
+	}
+
+	if err := c.storeItem(key, item); err != nil {
+		return err
+	}
+
+	c.logger.Debug("cache set", "key", key, "expiration", item.Expiration())
+
+	return nil
+}
+
+// SetDefaultExpiration меняет время жизни по-умолчанию для последующих вызовов Set
+// с продолжительностью DefaultExpiration (или 0 без WithStrictTTL), уже записанные элементы не затрагиваются.
+// Время жизни префиксов из WithPrefixDefault не меняется
+func (c *InMemoryCache) SetDefaultExpiration(d time.Duration) {
+	c.defaultExpiration.Store(int64(d))
+}
+
+// newItem создает элемент со временем истечения, рассчитанным от текущего момента
+func (c *InMemoryCache) newItem(key string, value interface{}, duration time.Duration) Item {
+	duration = c.itemTTL(key, duration)
+
+	item := Item{
+		createdAt:  c.now(),
+		expiration: expirationFor(c.nowNano(), c.jitter(duration)),
+	}
+
+	if c.slidingExpiration && item.expiration > 0 {
+		item.idle = duration
+	}
+
+	if (c.refreshLoader != nil || c.staleLoader != nil) && item.expiration > 0 {
+		item.ttl = duration
+	}
+
+	// Время истечения из значения не отменяет WithTTLOverride
+	if c.valueExpiry && c.ttlOverride <= 0 {
+		if expiration, ok := c.valueExpiration(value); ok {
+			item.expiration = expiration
+			item.idle = 0
+			item.ttl = 0
+		}
+	}
+
+	c.encodeValue(&item, value)
+	c.countHits(&item)
+
+	// Учитываем размер значения в том виде, в каком оно будет храниться
+	if c.sizeEstimator != nil {
+		item.size = c.estimateSize(item.value)
+	}
+
+	return item
+}
+
+// jitter случайно изменяет время жизни duration в пределах WithTTLJitter
+func (c *InMemoryCache) jitter(duration time.Duration) time.Duration {
+	if c.ttlJitter <= 0 || duration <= 0 {
+		return duration
+	}
 
-	if _, found := c.cache[key]; !found {
-		errorString := "key: '" + key + "' not found"
-		return errors.New(errorString)
+	delta := time.Duration((rand.Float64()*2 - 1) * c.ttlJitter * float64(duration))
+
+	return max(duration+delta, 1)
+}
+
+// itemTTL возвращает время жизни, которое получит элемент, записанный с продолжительностью duration
+func (c *InMemoryCache) itemTTL(key string, duration time.Duration) time.Duration {
+	// Если продолжительность жизни равна 0 - используется значение по-умолчанию для ключа,
+	// при WithStrictTTL значение по-умолчанию запрашивается только явно
+	switch {
+	case duration == DefaultExpiration, duration == 0 && !c.strictTTL:
+		duration = c.defaultTTL(key)
+	case duration == 0:
+		duration = NoExpiration
+	}
+
+	// Переопределение не касается элементов, явно записанных бессрочными
+	if c.ttlOverride > 0 && duration >= 0 {
+		duration = c.ttlOverride
+	}
+
+	return duration
+}
+
+// storeItem записывает элемент в хранилище, вызывается под блокировкой на запись.
+// Для нового ключа в заполненном кеше и для элемента сверх WithMaxBytes возвращает ErrCacheFull
+func (c *InMemoryCache) storeItem(key string, item Item) error {
+	// Кеш с недопустимыми опциями не принимает записи, см. WithEncryption
+	if c.configErr != nil {
+		return c.configErr
+	}
+
+	if err := c.admitBytes(key, item); err != nil {
+		return err
+	}
+
+	old, found := c.cache[key]
+	if !found {
+		if err := c.admit(key); err != nil {
+			return err
+		}
+	}
+
+	// В журнал пишем до изменения хранилища, чтобы не потерять элемент при воспроизведении
+	if err := c.logSet(key, item); err != nil {
+		return err
+	}
+
+	if found {
+		c.untrackSize(key, old)
+		item.pinned = old.pinned
+		c.policy.accessed(key)
+	} else {
+		if c.prefixes != nil {
+			c.prefixes.insert(key)
+		}
+		c.policy.added(key)
+		c.addToBloom(key)
+	}
+
+	c.cache[key] = item
+	c.trackSize(key, item)
+	c.maybeCompactWAL()
+	c.invalidate(key)
+	c.rescheduleExpiry(key, item)
+	if c.events.active() {
+		c.events.publish(Event{Type: EventSet, Key: key, Value: c.itemValue(item)})
+	}
+	c.journal.record(OpSet, key, 0)
+
+	// Новое значение не производно от прежних зависимостей
+	c.deps.unlink(key)
+	c.tags.unlink(key)
+	if c.sources != nil {
+		delete(c.sources, key)
+	}
+
+	return nil
+}
+
+// removeItem удаляет элемент из хранилища, вызывается под блокировкой на запись
+func (c *InMemoryCache) removeItem(key string) (Item, bool) {
+	item, found := c.cache[key]
+	if !found {
+		return Item{}, false
 	}
 
 	delete(c.cache, key)
+	c.untrackSize(key, item)
+	c.policy.removed(key)
+	c.tags.unlink(key)
+	c.fireExpiry(key)
+	if c.sources != nil {
+		delete(c.sources, key)
+	}
+
+	if c.prefixes != nil {
+		c.prefixes.remove(key)
+	}
+
+	return item, true
+}
+
+// expirationFor возвращает время истечения в UnixNano для продолжительности жизни duration,
+// отсчитанной от now, 0 означает бессрочный элемент
+func expirationFor(now int64, duration time.Duration) int64 {
+	if duration <= 0 {
+		return 0
+	}
+
+	// Слишком большая продолжительность переполняет int64 и дает время в прошлом,
+	// такой элемент считаем бессрочным
+	if int64(duration) > math.MaxInt64-now {
+		return 0
+	}
+
+	return now + int64(duration)
+}
+
+func (c *InMemoryCache) Delete(key string) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+
+	if c.keyLocking {
+		defer c.keyLocks.lock(key)()
+	}
+
+	c.rmu.Lock()
+	defer c.unlock()
+
+	return c.delete(key)
+}
+
+// DeleteMany удаляет ключи под одной блокировкой и возвращает количество удаленных,
+// отсутствующие ключи пропускаются
+func (c *InMemoryCache) DeleteMany(keys []string) int {
+	c.rmu.Lock()
+	defer c.unlock()
+
+	deleted := 0
+	for _, k := range keys {
+		if c.evict(k, ReasonDeleted) {
+			deleted++
+		}
+	}
+
+	return deleted
+}
+
+// delete удаляет ключ, вызывается под блокировкой на запись
+func (c *InMemoryCache) delete(key string) error {
+	if !c.evict(key, ReasonDeleted) {
+		return fmt.Errorf("key '%s': %w", key, ErrNotFound)
+	}
+
 	return nil
 }
 
@@ -93,20 +532,92 @@ func (c *InMemoryCache) StartGC() {
 }
 
 func (c *InMemoryCache) GC() {
+	interval := c.cleanupInterval
 
 	for {
 		// ожидаем время установленное в cleanupInterval
-		<-time.After(c.cleanupInterval)
-
-		if c.cache == nil {
+		select {
+		case <-c.done:
 			return
+		case <-c.timeSource().After(interval):
 		}
 
-		// Ищем элементы с истекшим временем жизни и удаляем из хранилища
-		if keys := c.expiredKeys(); len(keys) != 0 {
-			c.clearItems(keys)
-		}
+		interval = c.nextGCInterval(interval, c.cleanupInterval, c.sweep())
+	}
+}
+
+// nextGCInterval возвращает интервал до следующего прохода GC с учетом WithGCBackoff
+func (o options) nextGCInterval(interval, base time.Duration, collected int) time.Duration {
+	if o.gcBackoffMax <= base || collected > 0 {
+		return base
+	}
+
+	if interval *= 2; interval > o.gcBackoffMax {
+		interval = o.gcBackoffMax
 	}
+
+	return interval
+}
+
+// Close останавливает фоновые горутины кеша (GC, асинхронную запись).
+// В режиме WithAsyncWrites перед возвратом применяет все записи из буфера,
+// при WithPersistOnClose сохраняет кеш в файл и возвращает ошибку сохранения.
+//
+// После Close кеш доступен только на чтение: Get и другие чтения возвращают оставшиеся элементы,
+// просроченные элементы больше не удаляются, но и не возвращаются. Записи отклоняются:
+// SetE, Delete, GetOrCompute при промахе, SetWithDeps и SetWithTags возвращают ErrClosed, Set, Update и Flush ничего не делают
+func (c *InMemoryCache) Close() error {
+	var err error
+
+	c.closeOnce.Do(func() {
+		c.closed.Store(true)
+		close(c.done)
+
+		if c.async != nil {
+			c.async.close()
+		}
+
+		// Сегменты ShardedCache создаются без шины, их общий invalidator закрывает ShardedCache
+		if c.invalidator != nil && c.invalidationBus != nil {
+			c.invalidator.close()
+		}
+
+		if c.persistPath != "" {
+			err = saveFile(c.persistPath, c.persistCodec, c.snapshotItems())
+		}
+
+		c.rmu.Lock()
+		if walErr := c.wal.close(); err == nil {
+			err = walErr
+		}
+		c.rmu.Unlock()
+	})
+
+	return err
+}
+
+// sweep ищет элементы с истекшим временем жизни, удаляет их из хранилища
+// и запоминает результат прохода для Stats, возвращает количество удаленных элементов
+func (c *InMemoryCache) sweep() int {
+	start := time.Now()
+
+	collected := c.deleteReapable()
+
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+
+	c.gc.lastDuration = time.Since(start)
+	c.gc.lastCollected = collected
+	c.gc.runs++
+	c.rebuildBloom(false)
+
+	return collected
+}
+
+// ExpiredItems возвращает ключи, время жизни которых истекло, но которые еще не удалены GC.
+// Помогает понять, не слишком ли велик cleanupInterval
+func (c *InMemoryCache) ExpiredItems() []string {
+	return c.expiredKeys()
 }
 
 // expiredKeys возвращает список "просроченных" ключей
@@ -117,7 +628,7 @@ func (c *InMemoryCache) expiredKeys() (keys []string) {
 	defer c.rmu.RUnlock()
 
 	for k, i := range c.cache {
-		if time.Now().UnixNano() > i.expiration && i.expiration > 0 {
+		if c.expired(i) {
 			keys = append(keys, k)
 		}
 	}
@@ -125,35 +636,235 @@ func (c *InMemoryCache) expiredKeys() (keys []string) {
 	return
 }
 
-// clearItems удаляет ключи из переданного списка, в нашем случае "просроченные"
-func (c *InMemoryCache) clearItems(keys []string) {
-	fmt.Println("Clear items: ", keys)
+// reapableKeys возвращает просроченные ключи, которые пора удалить: с WithStaleWhileRevalidate
+// элементы остаются в хранилище до конца окна
+func (c *InMemoryCache) reapableKeys() (keys []string) {
+	c.rmu.RLock()
+	defer c.rmu.RUnlock()
+
+	for k, i := range c.cache {
+		if c.reapable(i) {
+			keys = append(keys, k)
+		}
+	}
+
+	return
+}
+
+// clearItems удаляет ключи из переданного списка, в нашем случае "просроченные".
+// При заданном WithGCBatchSize блокировка снимается после каждой порции ключей.
+// Возвращает количество удаленных элементов
+func (c *InMemoryCache) clearItems(keys []string) int {
+	c.logger.Debug("cache gc", "expired", len(keys))
+
+	batch := len(keys)
+	if c.gcBatchSize > 0 {
+		batch = c.gcBatchSize
+	}
+
+	cleared := 0
+	for len(keys) > 0 {
+		n := min(batch, len(keys))
+		cleared += c.clearBatch(keys[:n])
+		keys = keys[n:]
+	}
+
+	return cleared
+}
+
+// DeleteExpired удаляет все просроченные элементы, не дожидаясь GC, и возвращает их количество.
+// Как и GC, соблюдает WithGCBatchSize, но не учитывается в статистике проходов GC
+func (c *InMemoryCache) DeleteExpired() int {
+	if c.closed.Load() {
+		return 0
+	}
+
+	return c.deleteReapable()
+}
+
+// deleteReapable удаляет просроченные элементы по очереди истечения, а с WithExpirationPredicate,
+// когда истечение по времени не определить заранее, - обходом всего хранилища
+func (c *InMemoryCache) deleteReapable() int {
+	if c.expirationPredicate == nil {
+		return c.collectExpired()
+	}
+
+	keys := c.reapableKeys()
+	if len(keys) == 0 {
+		return 0
+	}
+
+	return c.clearItems(keys)
+}
+
+// clearBatch удаляет порцию "просроченных" ключей под одной блокировкой
+func (c *InMemoryCache) clearBatch(keys []string) int {
 	c.rmu.Lock()
 
-	defer c.rmu.Unlock()
+	defer c.unlock()
 
+	cleared := 0
 	for _, k := range keys {
-		delete(c.cache, k)
+		// Пока блокировка была снята, элемент могли перезаписать с новым временем жизни
+		if item, found := c.cache[k]; found && c.reapable(item) && c.evict(k, ReasonExpired) {
+			cleared++
+		}
 	}
+
+	return cleared
 }
 
+// Flush удаляет все элементы. Обработчики удаления получают их с причиной ReasonFlushed
+// после замены хранилища и снятия блокировки
 func (c *InMemoryCache) Flush() {
+	c.FlushWithCallback(nil)
+}
+
+// FlushWithCallback удаляет все элементы как Flush и затем вызывает fn для каждого из них,
+// включая просроченные, например чтобы освободить связанные со значениями ресурсы
+func (c *InMemoryCache) FlushWithCallback(fn func(key string, value interface{})) {
+	if c.closed.Load() {
+		return
+	}
+
 	c.rmu.Lock()
-	defer c.rmu.Unlock()
-	c.cache = make(map[string]Item)
+	flushed, handlers := c.cache, c.handlers
+	c.cache = c.newMap()
+	c.keyBytes, c.valueBytes = 0, 0
+	c.deps = depGraph{}
+	c.tags = tagIndex{}
+	c.expiries = nil
+	c.policy.reset()
+	if c.sources != nil {
+		c.sources = make(map[string]string)
+	}
+	c.rebuildBloom(true)
+
+	for key := range c.expiryWatches {
+		c.fireExpiry(key)
+	}
+	c.events.publishFlush(flushed, c.itemValue)
+	c.journal.record(OpFlush, "", 0)
+	c.logFlush()
+	c.invalidateAll()
+
+	if c.prefixes != nil {
+		c.prefixes = &prefixTrie{}
+	}
+	c.unlock()
+
+	if fn == nil && handlers.empty() {
+		return
+	}
+
+	// Прежнее хранилище больше никому не доступно, поэтому обходится без блокировки
+	items := make([]EvictedItem, 0, len(flushed))
+	for k, i := range flushed {
+		items = append(items, EvictedItem{Key: k, Value: c.itemValue(i), Reason: ReasonFlushed})
+	}
+
+	c.notifyEvicted(handlers, items)
+
+	if fn != nil {
+		for _, i := range items {
+			c.callSafe(func() { fn(i.Key, i.Value) })
+		}
+	}
 }
 
-func NewInMemoryCache(DefaultExpiration, CleanupInterval time.Duration) Cache {
-	cache := &InMemoryCache{
-		cache:             make(map[string]Item),
-		defaultExpiration: DefaultExpiration,
-		cleanupInterval:   CleanupInterval,
+// New создает кеш, все настройки которого, включая время жизни по-умолчанию (WithDefaultExpiration)
+// и интервал GC (WithCleanupInterval), задаются опциями opts
+func New(opts ...Option) Cache {
+	o := newOptions(opts)
+
+	if o.readMostly {
+		return newReadMostlyCache(o)
 	}
 
-	// Если интервал очистки больше 0, запускаем GC (удаление устаревших элементов)
-	if CleanupInterval > 0 {
-		cache.StartGC()
+	// При включенном разделении блокировок кеш делится на сегменты со своими мьютексами
+	if o.lockStripes > 1 {
+		return newShardedCache(o, o.defaultExpiration, o.cleanupInterval)
 	}
 
+	cache := newInMemoryCache(o, &sync.RWMutex{}, o.defaultExpiration, o.cleanupInterval)
+	cache.start()
+
 	return cache
 }
+
+// NewInMemoryCache создает кеш, поведение можно настроить опциями opts.
+// То же, что New с WithDefaultExpiration и WithCleanupInterval перед opts
+func NewInMemoryCache(defaultExpiration, cleanupInterval time.Duration, opts ...Option) Cache {
+	return New(append([]Option{WithDefaultExpiration(defaultExpiration), WithCleanupInterval(cleanupInterval)}, opts...)...)
+}
+
+// start запускает фоновые горутины несегментированного кеша
+func (c *InMemoryCache) start() {
+	// Если интервал очистки больше 0, запускаем GC (удаление устаревших элементов)
+	if c.cleanupInterval > 0 {
+		c.StartGC()
+	}
+
+	if c.memoryWatermark > 0 {
+		go watchMemory(c.done, c.options, c.self)
+	}
+
+	if c.coarseTick > 0 {
+		go tickClock(c.done, c.timeSource(), c.coarseTick, c.self)
+	}
+}
+
+func newInMemoryCache(o options, locker rwLocker, defaultExpiration, cleanupInterval time.Duration) *InMemoryCache {
+	c := &InMemoryCache{
+		options:         o,
+		rmu:             locker,
+		cleanupInterval: cleanupInterval,
+		epoch:           time.Now(),
+		done:            make(chan struct{}),
+	}
+	c.cache = c.newMap()
+	c.defaultExpiration.Store(int64(defaultExpiration))
+	c.coarseNow.Store(c.preciseNano())
+
+	if o.prefixIndex {
+		c.prefixes = &prefixTrie{}
+	}
+
+	if o.journalSize > 0 {
+		c.journal = newJournal(o.journalSize)
+	}
+
+	c.policy = newPolicyGuard(o)
+	c.events.max = o.maxWatchers
+
+	if o.sourceTracking {
+		c.sources = make(map[string]string)
+	}
+
+	if o.bloomKeys > 0 {
+		c.bloom.Store(newBloomFilter(o.bloomKeys))
+	}
+
+	// Длина ключа проверена в WithEncryption, другой ошибки aes.NewCipher не возвращает
+	c.aead, _ = newAEAD(o.encryptionKey)
+
+	if o.asyncWrites > 0 {
+		c.async = newAsyncWriter(c, o.asyncWrites)
+	}
+
+	if o.invalidationBus != nil {
+		c.invalidator = newInvalidator(o.invalidationBus, o.nodeID, o.logger, c.applyInvalidation)
+	}
+
+	return c
+}
+
+// self возвращает кеш как список из одного сегмента для фоновых задач, общих с ShardedCache
+func (c *InMemoryCache) self() []*InMemoryCache {
+	return []*InMemoryCache{c}
+}
+
+// newMap создает пустое хранилище с емкостью из WithInitialCapacity
+func (c *InMemoryCache) newMap() map[string]Item {
+	return make(map[string]Item, max(c.initialCapacity, 0))
+}
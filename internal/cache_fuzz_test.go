@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// FuzzCacheOps выполняет ops параллельно в нескольких горутинах при работающем GC.
+// Каждый байт задает операцию, ключ и время жизни. Запускать с -race
+func FuzzCacheOps(f *testing.F) {
+	f.Add([]byte{0, 1, 2, 3, 4, 5, 6})
+	f.Add([]byte{0, 7, 14, 21, 28, 35, 5, 12, 3, 10})
+	f.Add([]byte{6, 13, 20, 27, 34, 41, 48, 55, 62, 69})
+
+	f.Fuzz(func(t *testing.T, ops []byte) {
+		c := NewInMemoryCache(0, time.Millisecond, WithGCBatchSize(2)).(*InMemoryCache)
+		defer c.Close()
+		c.OnEvicted(func(string, interface{}, EvictionReason) {})
+
+		var wg sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+
+				for i, op := range ops {
+					key := strconv.Itoa(int(op) % 5)
+					ttl := time.Duration(op%3) * time.Millisecond
+					switch (int(op) + g) % 7 {
+					case 0:
+						c.Set(key, i, ttl)
+					case 1:
+						c.Get(key)
+					case 2:
+						c.Delete(key)
+					case 3:
+						c.Expire(key, ttl)
+					case 4:
+						c.Flush()
+					case 5:
+						c.DeleteExpired()
+					case 6:
+						c.GetOrCompute(key, func() (interface{}, time.Duration, error) {
+							return i, ttl, nil
+						})
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+
+		c.Set("persistent", "value", NoExpiration)
+		if value, found := c.Get("persistent"); !found || value != "value" {
+			t.Fatalf("Get after Set without expiration = %v, %v", value, found)
+		}
+
+		c.Set("expiring", "value", time.Millisecond)
+		deadline := time.Now().Add(time.Second)
+		for {
+			c.rmu.RLock()
+			_, stored := c.cache["expiring"]
+			c.rmu.RUnlock()
+			if !stored {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("expired item was not collected by GC")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+}
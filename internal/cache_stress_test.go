@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"math/rand"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestConcurrentStress нагружает кеш смесью операций из многих горутин при работающем GC
+// и проверяет, что все горутины завершаются, а статистика согласована с содержимым кеша
+func TestConcurrentStress(t *testing.T) {
+	duration := 500 * time.Millisecond
+	if testing.Short() {
+		duration = 100 * time.Millisecond
+	}
+
+	c := NewInMemoryCache(0, time.Millisecond,
+		WithSizeEstimator(SizeOf),
+		WithGCBatchSize(3),
+		WithCompression(4),
+		WithKeyLocking(),
+	).(*InMemoryCache)
+	c.OnEvictedBatch(func([]EvictedItem) {})
+
+	var gets atomic.Uint64
+	var wg sync.WaitGroup
+	stop := time.Now().Add(duration)
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+
+			r := rand.New(rand.NewSource(seed))
+			for time.Now().Before(stop) {
+				key := strconv.Itoa(r.Intn(50))
+				ttl := time.Duration(r.Intn(3)-1) * time.Millisecond
+				switch r.Intn(12) {
+				case 0:
+					c.Set(key, []byte("value-value-value"), ttl)
+				case 1:
+					c.Get(key)
+					gets.Add(1)
+				case 2:
+					c.Delete(key)
+				case 3:
+					if r.Intn(50) == 0 {
+						c.Flush()
+					}
+				case 4:
+					c.DeleteMany([]string{key, "1", "2"})
+				case 5:
+					c.Atomic(func(tx *Txn) {
+						tx.Set(key, 1, ttl)
+						tx.Get("3")
+						tx.Delete("4")
+					})
+				case 6:
+					c.GetOrCompute(key, func() (interface{}, time.Duration, error) {
+						return "computed", ttl, nil
+					})
+				case 7:
+					c.Update(key, func(interface{}, bool) interface{} { return 2 }, ttl)
+				case 8:
+					c.SetWithDeps(key, 1, ttl, strconv.Itoa(r.Intn(50)))
+				case 9:
+					c.Expire(key, ttl)
+				case 10:
+					c.Snapshot()
+					c.Lookup(key)
+				case 11:
+					c.DeleteExpired()
+					c.Stats()
+				}
+			}
+		}(int64(g))
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+	case <-time.After(duration + 10*time.Second):
+		t.Fatal("goroutines did not finish, possible deadlock")
+	}
+
+	c.Close()
+
+	c.rmu.RLock()
+	defer c.rmu.RUnlock()
+
+	stats := c.stats()
+	if stats.Items != len(c.cache) {
+		t.Errorf("Items = %d, want %d", stats.Items, len(c.cache))
+	}
+
+	var keyBytes, valueBytes int64
+	for key, item := range c.cache {
+		keyBytes += int64(len(key))
+		valueBytes += item.size
+	}
+	if stats.KeyBytes != keyBytes || stats.ValueBytes != valueBytes {
+		t.Errorf("KeyBytes, ValueBytes = %d, %d, want %d, %d", stats.KeyBytes, stats.ValueBytes, keyBytes, valueBytes)
+	}
+
+	if lookups := stats.Hits + stats.Misses; lookups < gets.Load() {
+		t.Errorf("Hits + Misses = %d, want at least %d", lookups, gets.Load())
+	}
+
+	for parent, children := range c.deps.dependents {
+		for child := range children {
+			linked := false
+			for _, p := range c.deps.parents[child] {
+				if p == parent {
+					linked = true
+				}
+			}
+			if !linked {
+				t.Errorf("dependency %s -> %s is not linked back", parent, child)
+			}
+		}
+	}
+}
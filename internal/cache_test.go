@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSetMaxDurationNeverExpires(t *testing.T) {
+	c := NewInMemoryCache(0, 0).(*InMemoryCache)
+	defer c.Close()
+
+	c.Set("key", "value", time.Duration(math.MaxInt64))
+
+	value, found := c.Get("key")
+	if !found || value != "value" {
+		t.Fatalf("Get = %v, %v, want value, true", value, found)
+	}
+
+	if expiration := c.cache["key"].Expiration(); !expiration.IsZero() {
+		t.Fatalf("Expiration = %v, want no expiration", expiration)
+	}
+}
+
+func TestExpirationForOverflow(t *testing.T) {
+	now := time.Now().UnixNano()
+
+	tests := []struct {
+		name     string
+		duration time.Duration
+		want     int64
+	}{
+		{"max duration", time.Duration(math.MaxInt64), 0},
+		{"just over the limit", time.Duration(math.MaxInt64 - now + 1), 0},
+		{"at the limit", time.Duration(math.MaxInt64 - now), math.MaxInt64},
+		{"hour", time.Hour, now + int64(time.Hour)},
+		{"no expiration", NoExpiration, 0},
+	}
+
+	for _, tt := range tests {
+		if got := expirationFor(now, tt.duration); got != tt.want {
+			t.Errorf("%s: expirationFor = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
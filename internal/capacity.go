@@ -0,0 +1,226 @@
+package internal
+
+import (
+	"fmt"
+	"sync"
+)
+
+// EvictionPolicy определяет, что происходит при записи нового ключа в заполненный кеш (см. WithMaxEntries)
+type EvictionPolicy int
+
+const (
+	// PolicyReject - новые ключи не записываются, SetE возвращает ErrCacheFull.
+	// Существующие ключи перезаписываются как обычно
+	PolicyReject EvictionPolicy = iota + 1
+	// PolicyARC - вытесняется элемент, выбранный адаптивным алгоритмом ARC, который
+	// балансирует между давностью и частотой обращений и устойчив к однократным проходам по ключам.
+	// Каждое чтение обновляет состояние политики под отдельным мьютексом. Требует WithMaxEntries
+	PolicyARC
+	// PolicyLRU - вытесняется элемент, к которому дольше всего не обращались, используется по-умолчанию.
+	// Каждое чтение обновляет состояние политики под отдельным мьютексом
+	PolicyLRU
+	// PolicyLFU - вытесняется элемент с наименьшим количеством обращений, среди равных - самый давний.
+	// Подходит для нагрузки, где небольшая доля ключей читается намного чаще остальных
+	PolicyLFU
+	// PolicyFIFO - вытесняется элемент, записанный раньше остальных. Чтения состояние политики не меняют
+	PolicyFIFO
+)
+
+// known сообщает, что политика входит в число поддерживаемых
+func (p EvictionPolicy) known() bool {
+	return p >= PolicyReject && p <= PolicyFIFO
+}
+
+func (p EvictionPolicy) String() string {
+	switch p {
+	case PolicyReject:
+		return "reject"
+	case PolicyARC:
+		return "arc"
+	case PolicyLRU:
+		return "lru"
+	case PolicyLFU:
+		return "lfu"
+	case PolicyFIFO:
+		return "fifo"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseEvictionPolicy возвращает политику по ее имени из EvictionPolicy.String
+func ParseEvictionPolicy(name string) (EvictionPolicy, error) {
+	for p := PolicyReject; p.known(); p++ {
+		if p.String() == name {
+			return p, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: unknown eviction policy %q", ErrInvalidConfig, name)
+}
+
+// evictionPolicy выбирает элементы для вытеснения из заполненного кеша.
+// Методы вызываются под policyGuard, поэтому реализации не синхронизируются сами
+type evictionPolicy interface {
+	// added - в кеш записан новый ключ
+	added(key string)
+	// accessed - ключ прочитан или перезаписан
+	accessed(key string)
+	// removed - ключ удален из кеша
+	removed(key string)
+	// victim выбирает ключ для вытеснения перед записью incoming, пропуская ключи, для которых skip истинна
+	victim(incoming string, skip func(key string) bool) (string, bool)
+	// reset забывает все ключи
+	reset()
+}
+
+// policyGuard сериализует обращения к политике вытеснения: чтения кеша выполняются
+// под блокировкой на чтение, но тоже меняют состояние политики
+type policyGuard struct {
+	mu     sync.Mutex
+	policy evictionPolicy
+	// recency - порядок обращений для вытеснения при нехватке памяти (WithMemoryWatermark):
+	// сама политика LRU или отдельный список, если политика другая или не задана
+	recency evictionPolicy
+}
+
+func newPolicyGuard(o options) *policyGuard {
+	policy := newEvictionPolicy(o)
+
+	var recency evictionPolicy
+	if o.memoryWatermark > 0 {
+		if lru, ok := policy.(*lruPolicy); ok {
+			recency = lru
+		} else {
+			recency = newLRUPolicy()
+		}
+	}
+
+	if policy == nil && recency == nil {
+		return nil
+	}
+
+	return &policyGuard{policy: policy, recency: recency}
+}
+
+// newEvictionPolicy создает политику вытеснения для ограничений WithMaxEntries и WithMaxBytes
+func newEvictionPolicy(o options) evictionPolicy {
+	if o.maxEntries <= 0 && o.maxBytes <= 0 {
+		return nil
+	}
+
+	switch o.policyKind() {
+	case PolicyARC:
+		// Размеры списков ARC задаются в элементах
+		if o.maxEntries <= 0 {
+			return nil
+		}
+		return newARCPolicy(o.maxEntries)
+	case PolicyLRU:
+		return newLRUPolicy()
+	case PolicyLFU:
+		return newLFUPolicy()
+	case PolicyFIFO:
+		return newFIFOPolicy()
+	default:
+		return nil
+	}
+}
+
+// policy возвращает политику вытеснения с учетом значения по-умолчанию
+func (o options) policyKind() EvictionPolicy {
+	if o.evictionPolicy == 0 {
+		return PolicyLRU
+	}
+
+	return o.evictionPolicy
+}
+
+// each вызывает fn для политики и отдельного порядка обращений, если он есть
+func (g *policyGuard) each(fn func(p evictionPolicy)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.policy != nil {
+		fn(g.policy)
+	}
+	if g.recency != nil && g.recency != g.policy {
+		fn(g.recency)
+	}
+}
+
+func (g *policyGuard) added(key string) {
+	if g == nil {
+		return
+	}
+
+	g.each(func(p evictionPolicy) { p.added(key) })
+}
+
+func (g *policyGuard) accessed(key string) {
+	if g == nil {
+		return
+	}
+
+	g.each(func(p evictionPolicy) { p.accessed(key) })
+}
+
+func (g *policyGuard) removed(key string) {
+	if g == nil {
+		return
+	}
+
+	g.each(func(p evictionPolicy) { p.removed(key) })
+}
+
+func (g *policyGuard) victim(incoming string, skip func(key string) bool) (string, bool) {
+	if g == nil || g.policy == nil {
+		return "", false
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.policy.victim(incoming, skip)
+}
+
+// leastRecent возвращает ключ, к которому дольше всего не обращались, пропуская ключи,
+// для которых skip истинна. Порядок ведется только с WithMemoryWatermark
+func (g *policyGuard) leastRecent(skip func(key string) bool) (string, bool) {
+	if g == nil || g.recency == nil {
+		return "", false
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.recency.victim("", skip)
+}
+
+func (g *policyGuard) reset() {
+	if g == nil {
+		return
+	}
+
+	g.each(func(p evictionPolicy) { p.reset() })
+}
+
+// admit освобождает место для нового ключа key, вытесняя элемент по политике вытеснения.
+// Возвращает ErrCacheFull, если место освободить нельзя. Вызывается под блокировкой на запись
+func (c *InMemoryCache) admit(key string) error {
+	if c.maxEntries <= 0 || len(c.cache) < c.maxEntries {
+		return nil
+	}
+
+	victim, found := c.policy.victim(key, func(k string) bool {
+		return c.cache[k].pinned
+	})
+	if !found {
+		return ErrCacheFull
+	}
+
+	c.evict(victim, ReasonCapacity)
+	c.evictions++
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package internal
+
+import (
+	"time"
+
+	"InMemoryCache/internal/clock"
+)
+
+// tickClock периодически обновляет грубое текущее время кешей, возвращаемых caches, пока не закрыт done
+func tickClock(done <-chan struct{}, source clock.Clock, tick time.Duration, caches func() []*InMemoryCache) {
+	ticker := source.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C():
+		}
+
+		for _, c := range caches() {
+			c.coarseNow.Store(c.preciseNano())
+		}
+	}
+}
+
+// timeSource возвращает часы из WithClock, по-умолчанию системные
+func (o options) timeSource() clock.Clock {
+	if o.clock != nil {
+		return o.clock
+	}
+
+	return clock.Real{}
+}
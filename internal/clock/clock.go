@@ -0,0 +1,216 @@
+// Package clock - источник времени кеша. Вместо системных часов в кеш можно передать Fake
+// и в тестах управлять временем через Advance, не дожидаясь истечения элементов и проходов GC
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock - часы, по которым кеш считает время жизни элементов и запускает GC
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	NewTicker(d time.Duration) Ticker
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Ticker - периодический таймер, см. time.Ticker
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Timer - отложенный вызов функции, см. time.AfterFunc
+type Timer interface {
+	Stop() bool
+}
+
+// Real - системные часы
+type Real struct{}
+
+func (Real) Now() time.Time                         { return time.Now() }
+func (Real) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (Real) NewTicker(d time.Duration) Ticker       { return realTicker{time.NewTicker(d)} }
+
+func (Real) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+type realTicker struct {
+	t *time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time { return t.t.C }
+func (t realTicker) Stop()               { t.t.Stop() }
+
+// Func - часы, текущее время которых возвращает функция, а таймеры работают по системному времени
+type Func func() time.Time
+
+func (f Func) Now() time.Time                          { return f() }
+func (Func) After(d time.Duration) <-chan time.Time    { return time.After(d) }
+func (Func) NewTicker(d time.Duration) Ticker          { return Real{}.NewTicker(d) }
+func (Func) AfterFunc(d time.Duration, f func()) Timer { return time.AfterFunc(d, f) }
+
+// Fake - часы, время которых идет только при вызове Advance. Таймеры и тикеры срабатывают
+// в Advance по порядку своих времен, функции AfterFunc вызываются в горутине Advance
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeTimer
+}
+
+// fakeTimer - ожидание Fake: канал After и тикера или функция AfterFunc
+type fakeTimer struct {
+	clock  *Fake
+	at     time.Time
+	period time.Duration
+	ch     chan time.Time
+	fn     func()
+}
+
+// NewFake создает часы, показывающие start
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.now
+}
+
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.add(d, 0, nil).ch
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+
+	return fakeTicker{f.add(d, d, nil)}
+}
+
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	return f.add(d, 0, fn)
+}
+
+// Waiters возвращает количество ожидающих таймеров и тикеров. Позволяет тесту дождаться,
+// пока фоновая горутина кеша заведет таймер, прежде чем двигать время
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.waiters)
+}
+
+// Advance переводит часы вперед на d, по пути срабатывают все истекшие таймеры и тикеры
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	target := f.now.Add(d)
+
+	for {
+		w := f.next(target)
+		if w == nil {
+			break
+		}
+
+		f.now = w.at
+		if w.period > 0 {
+			w.at = w.at.Add(w.period)
+		} else {
+			f.remove(w)
+		}
+		now := f.now
+
+		// Функция таймера может обращаться к часам, поэтому вызывается без блокировки
+		f.mu.Unlock()
+		w.fire(now)
+		f.mu.Lock()
+	}
+
+	if target.After(f.now) {
+		f.now = target
+	}
+	f.mu.Unlock()
+}
+
+func (f *Fake) add(d, period time.Duration, fn func()) *fakeTimer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w := &fakeTimer{clock: f, at: f.now.Add(d), period: period, fn: fn}
+	if fn == nil {
+		w.ch = make(chan time.Time, 1)
+	}
+
+	// Уже истекший таймер срабатывает сразу, как в пакете time
+	if d <= 0 && period == 0 {
+		if fn != nil {
+			go fn()
+		} else {
+			w.ch <- f.now
+		}
+		return w
+	}
+
+	f.waiters = append(f.waiters, w)
+
+	return w
+}
+
+// next возвращает самое раннее ожидание, истекающее не позже target, вызывается под блокировкой
+func (f *Fake) next(target time.Time) *fakeTimer {
+	sort.SliceStable(f.waiters, func(i, j int) bool {
+		return f.waiters[i].at.Before(f.waiters[j].at)
+	})
+
+	if len(f.waiters) == 0 || f.waiters[0].at.After(target) {
+		return nil
+	}
+
+	return f.waiters[0]
+}
+
+// remove убирает ожидание w, вызывается под блокировкой
+func (f *Fake) remove(w *fakeTimer) bool {
+	for i, x := range f.waiters {
+		if x == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w *fakeTimer) fire(now time.Time) {
+	if w.fn != nil {
+		w.fn()
+		return
+	}
+
+	// Как и time.Ticker, тикер пропускает срабатывание, если прошлое еще не прочитано
+	select {
+	case w.ch <- now:
+	default:
+	}
+}
+
+func (w *fakeTimer) Stop() bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+
+	return w.clock.remove(w)
+}
+
+// fakeTicker - тикер Fake
+type fakeTicker struct {
+	w *fakeTimer
+}
+
+func (t fakeTicker) C() <-chan time.Time { return t.w.ch }
+func (t fakeTicker) Stop()               { t.w.Stop() }
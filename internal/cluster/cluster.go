@@ -0,0 +1,277 @@
+// Package cluster объединяет несколько серверов кеша в один логический кеш:
+// ключи распределяются по узлам консистентным хешированием (hashring),
+// недоступные узлы временно исключаются из кольца по результатам проверок
+package cluster
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"InMemoryCache/internal/hashring"
+)
+
+// ErrNoNodes возвращается, когда в кластере нет ни одного доступного узла
+var ErrNoNodes = errors.New("cluster: no healthy nodes")
+
+// healthKey - ключ, который читает проверка доступности по-умолчанию
+const healthKey = "__cluster_health__"
+
+// Node - соединение с одним сервером кеша, например *grpcapi.Client
+type Node interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) (bool, error)
+}
+
+// NodeStatus - состояние узла, возвращаемое Nodes
+type NodeStatus struct {
+	Name    string
+	Healthy bool
+	// LastError - ошибка последней проверки, nil для доступного узла
+	LastError error
+}
+
+// Cluster распределяет ключи по узлам. Методы безопасны для конкурентного использования.
+// Данные при изменении состава кольца не переносятся: ключи, сменившие владельца,
+// читаются как промах, пока их не запишут заново
+type Cluster struct {
+	mu      sync.RWMutex
+	ring    *hashring.Ring
+	members map[string]*member
+	options
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type member struct {
+	node    Node
+	healthy bool
+	lastErr error
+}
+
+type options struct {
+	replicas       int
+	healthInterval time.Duration
+	healthTimeout  time.Duration
+	check          func(ctx context.Context, node Node) error
+}
+
+// Option настраивает Cluster
+type Option func(*options)
+
+// WithReplicas задает количество виртуальных узлов на узел, по-умолчанию hashring.DefaultReplicas
+func WithReplicas(replicas int) Option {
+	return func(o *options) {
+		o.replicas = replicas
+	}
+}
+
+// WithHealthCheck раз в interval проверяет узлы функцией check с таймаутом timeout
+// (при timeout <= 0 - interval). Узел, не прошедший проверку, исключается из кольца,
+// его ключи переходят к соседним узлам, а после успешной проверки он возвращается.
+// При check == nil узел проверяется чтением служебного ключа. По-умолчанию проверки выключены
+func WithHealthCheck(interval, timeout time.Duration, check func(ctx context.Context, node Node) error) Option {
+	return func(o *options) {
+		o.healthInterval = interval
+		o.healthTimeout = timeout
+		o.check = check
+	}
+}
+
+// New создает пустой кластер, узлы добавляются через Add.
+// С WithHealthCheck кластер нужно закрыть через Close
+func New(opts ...Option) *Cluster {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.check == nil {
+		o.check = func(ctx context.Context, node Node) error {
+			_, _, err := node.Get(ctx, healthKey)
+			return err
+		}
+	}
+
+	if o.healthTimeout <= 0 {
+		o.healthTimeout = o.healthInterval
+	}
+
+	c := &Cluster{
+		ring:    hashring.New(o.replicas),
+		members: make(map[string]*member),
+		options: o,
+		done:    make(chan struct{}),
+	}
+
+	if o.healthInterval > 0 {
+		go c.watchHealth()
+	}
+
+	return c
+}
+
+// Add добавляет узел name, он сразу считается доступным. Повторное добавление заменяет соединение
+func (c *Cluster) Add(name string, node Node) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.members[name] = &member{node: node, healthy: true}
+	c.ring.Add(name)
+}
+
+// Remove удаляет узел name, его ключи переходят к соседним по кольцу узлам
+func (c *Cluster) Remove(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.members, name)
+	c.ring.Remove(name)
+}
+
+// Nodes возвращает состояние всех узлов, отсортированных по имени
+func (c *Cluster) Nodes() []NodeStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	nodes := make([]NodeStatus, 0, len(c.members))
+	for name, m := range c.members {
+		nodes = append(nodes, NodeStatus{Name: name, Healthy: m.healthy, LastError: m.lastErr})
+	}
+	sort.Slice(nodes, func(a, b int) bool { return nodes[a].Name < nodes[b].Name })
+
+	return nodes
+}
+
+// Owner возвращает имя узла, которому принадлежит ключ, ok = false без доступных узлов
+func (c *Cluster) Owner(key string) (name string, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.ring.Get(key)
+}
+
+// Get читает значение ключа с узла-владельца
+func (c *Cluster) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	node, err := c.node(key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return node.Get(ctx, key)
+}
+
+// Set записывает значение ключа на узел-владелец, см. grpcapi.Client.Set
+func (c *Cluster) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	node, err := c.node(key)
+	if err != nil {
+		return err
+	}
+
+	return node.Set(ctx, key, value, ttl)
+}
+
+// Delete удаляет ключ на узле-владельце и возвращает false, если его не было
+func (c *Cluster) Delete(ctx context.Context, key string) (bool, error) {
+	node, err := c.node(key)
+	if err != nil {
+		return false, err
+	}
+
+	return node.Delete(ctx, key)
+}
+
+// Close останавливает проверки доступности, соединения с узлами не закрываются
+func (c *Cluster) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+
+	return nil
+}
+
+func (c *Cluster) node(key string) (Node, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	name, ok := c.ring.Get(key)
+	if !ok {
+		return nil, ErrNoNodes
+	}
+
+	return c.members[name].node, nil
+}
+
+func (c *Cluster) watchHealth() {
+	ticker := time.NewTicker(c.healthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+
+		c.checkHealth()
+	}
+}
+
+// checkHealth проверяет все узлы параллельно и перестраивает кольцо по результатам
+func (c *Cluster) checkHealth() {
+	c.mu.RLock()
+	members := make(map[string]*member, len(c.members))
+	for name, m := range c.members {
+		members[name] = m
+	}
+	c.mu.RUnlock()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		results = make(map[string]error, len(members))
+	)
+
+	for name, m := range members {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), c.healthTimeout)
+			defer cancel()
+
+			err := c.check(ctx, m.node)
+
+			mu.Lock()
+			results[name] = err
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for name, err := range results {
+		m := c.members[name]
+		// Узел удалили или заменили, пока шла проверка
+		if m != members[name] {
+			continue
+		}
+
+		healthy := err == nil
+		if healthy != m.healthy {
+			if healthy {
+				c.ring.Add(name)
+			} else {
+				c.ring.Remove(name)
+			}
+		}
+
+		m.healthy, m.lastErr = healthy, err
+	}
+}
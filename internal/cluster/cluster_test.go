@@ -0,0 +1,258 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errDown = errors.New("node is down")
+
+// memNode - узел кластера в памяти, down имитирует недоступный сервер
+type memNode struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	down atomic.Bool
+}
+
+func newMemNode() *memNode {
+	return &memNode{data: make(map[string][]byte)}
+}
+
+func (n *memNode) Get(_ context.Context, key string) ([]byte, bool, error) {
+	if n.down.Load() {
+		return nil, false, errDown
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	value, found := n.data[key]
+	return value, found, nil
+}
+
+func (n *memNode) Set(_ context.Context, key string, value []byte, _ time.Duration) error {
+	if n.down.Load() {
+		return errDown
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.data[key] = value
+	return nil
+}
+
+func (n *memNode) Delete(_ context.Context, key string) (bool, error) {
+	if n.down.Load() {
+		return false, errDown
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	_, found := n.data[key]
+	delete(n.data, key)
+	return found, nil
+}
+
+func (n *memNode) len() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return len(n.data)
+}
+
+// owners возвращает узлы-владельцы ключей 0..count-1
+func owners(t *testing.T, c *Cluster, count int) map[string]string {
+	t.Helper()
+
+	owners := make(map[string]string, count)
+	for i := range count {
+		key := strconv.Itoa(i)
+		name, ok := c.Owner(key)
+		if !ok {
+			t.Fatalf("Owner(%s): no nodes", key)
+		}
+		owners[key] = name
+	}
+
+	return owners
+}
+
+func TestEmptyCluster(t *testing.T) {
+	c := New()
+	defer c.Close()
+
+	ctx := context.Background()
+	if _, _, err := c.Get(ctx, "a"); !errors.Is(err, ErrNoNodes) {
+		t.Errorf("Get err = %v, want %v", err, ErrNoNodes)
+	}
+	if err := c.Set(ctx, "a", nil, 0); !errors.Is(err, ErrNoNodes) {
+		t.Errorf("Set err = %v, want %v", err, ErrNoNodes)
+	}
+	if _, err := c.Delete(ctx, "a"); !errors.Is(err, ErrNoNodes) {
+		t.Errorf("Delete err = %v, want %v", err, ErrNoNodes)
+	}
+	if _, ok := c.Owner("a"); ok {
+		t.Error("Owner found a node in an empty cluster")
+	}
+}
+
+func TestRoutesKeysToOwners(t *testing.T) {
+	c := New()
+	defer c.Close()
+
+	nodes := map[string]*memNode{"a": newMemNode(), "b": newMemNode(), "c": newMemNode()}
+	for name, n := range nodes {
+		c.Add(name, n)
+	}
+
+	ctx := context.Background()
+	for key, owner := range owners(t, c, 300) {
+		if err := c.Set(ctx, key, []byte(key), 0); err != nil {
+			t.Fatalf("Set(%s): %v", key, err)
+		}
+		if _, found, _ := nodes[owner].Get(ctx, key); !found {
+			t.Errorf("key %s is not stored on its owner %s", key, owner)
+		}
+
+		value, found, err := c.Get(ctx, key)
+		if err != nil || !found || string(value) != key {
+			t.Errorf("Get(%s) = %q, %v, %v", key, value, found, err)
+		}
+	}
+
+	for name, n := range nodes {
+		if n.len() == 0 {
+			t.Errorf("node %s got no keys", name)
+		}
+	}
+
+	if deleted, err := c.Delete(ctx, "0"); err != nil || !deleted {
+		t.Errorf("Delete = %v, %v, want true", deleted, err)
+	}
+}
+
+func TestAddRemoveMovesOnlyAffectedKeys(t *testing.T) {
+	c := New()
+	defer c.Close()
+
+	c.Add("a", newMemNode())
+	c.Add("b", newMemNode())
+	before := owners(t, c, 500)
+
+	c.Add("c", newMemNode())
+	added := owners(t, c, 500)
+	for key, owner := range added {
+		if owner != before[key] && owner != "c" {
+			t.Errorf("key %s moved from %s to %s, not to the new node", key, before[key], owner)
+		}
+	}
+
+	c.Remove("c")
+	for key, owner := range owners(t, c, 500) {
+		if owner != before[key] {
+			t.Errorf("key %s owned by %s after Remove, want %s", key, owner, before[key])
+		}
+	}
+
+	if got := c.Nodes(); len(got) != 2 || got[0].Name != "a" || got[1].Name != "b" {
+		t.Errorf("Nodes = %v, want a and b", got)
+	}
+}
+
+func TestHealthCheckRemovesAndRestoresNode(t *testing.T) {
+	c := New()
+	defer c.Close()
+
+	down := newMemNode()
+	c.Add("a", newMemNode())
+	c.Add("b", down)
+	before := owners(t, c, 300)
+
+	down.down.Store(true)
+	c.checkHealth()
+
+	status := c.Nodes()
+	if status[1].Healthy || !errors.Is(status[1].LastError, errDown) {
+		t.Errorf("status of the failed node = %+v, want unhealthy with %v", status[1], errDown)
+	}
+	if !status[0].Healthy {
+		t.Errorf("status of the working node = %+v, want healthy", status[0])
+	}
+	for key, owner := range owners(t, c, 300) {
+		if owner != "a" {
+			t.Errorf("key %s owned by %s while it is down", key, owner)
+		}
+	}
+
+	down.down.Store(false)
+	c.checkHealth()
+
+	if status := c.Nodes(); !status[1].Healthy || status[1].LastError != nil {
+		t.Errorf("status after recovery = %+v, want healthy", status[1])
+	}
+	for key, owner := range owners(t, c, 300) {
+		if owner != before[key] {
+			t.Errorf("key %s owned by %s after recovery, want %s", key, owner, before[key])
+		}
+	}
+}
+
+func TestHealthCheckIgnoresReplacedNode(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	c := New(WithHealthCheck(time.Hour, 0, func(context.Context, Node) error {
+		close(started)
+		<-release
+		return errDown
+	}))
+	defer c.Close()
+
+	c.Add("a", newMemNode())
+
+	done := make(chan struct{})
+	go func() {
+		c.checkHealth()
+		close(done)
+	}()
+
+	<-started
+	// Результат проверки прежнего соединения не должен исключить новое
+	c.Add("a", newMemNode())
+	close(release)
+	<-done
+
+	if status := c.Nodes(); !status[0].Healthy {
+		t.Errorf("replaced node status = %+v, want healthy", status[0])
+	}
+	if _, ok := c.Owner("x"); !ok {
+		t.Error("replaced node is removed from the ring")
+	}
+}
+
+func TestHealthCheckRunsPeriodically(t *testing.T) {
+	c := New(WithHealthCheck(time.Millisecond, 0, nil))
+	defer c.Close()
+
+	down := newMemNode()
+	down.down.Store(true)
+	c.Add("a", down)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for c.Nodes()[0].Healthy {
+		if time.Now().After(deadline) {
+			t.Fatal("health check did not mark the node down")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx := context.Background()
+	if _, _, err := c.Get(ctx, "a"); !errors.Is(err, ErrNoNodes) {
+		t.Errorf("Get err = %v, want %v", err, ErrNoNodes)
+	}
+}
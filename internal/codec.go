@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Codec задает формат, в котором содержимое кеша сохраняется SaveFile и читается LoadFile
+type Codec interface {
+	Encode(w io.Writer, items map[string]Item) error
+	Decode(r io.Reader) (map[string]Item, error)
+}
+
+// GobCodec сохраняет кеш в формате gob, используется по-умолчанию.
+// Типы значений, кроме встроенных, нужно зарегистрировать через gob.Register
+type GobCodec struct{}
+
+func (GobCodec) Encode(w io.Writer, items map[string]Item) error {
+	return gob.NewEncoder(w).Encode(items)
+}
+
+func (GobCodec) Decode(r io.Reader) (map[string]Item, error) {
+	var items map[string]Item
+	if err := gob.NewDecoder(r).Decode(&items); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// JSONCodec сохраняет кеш в JSON. После загрузки значения имеют типы,
+// которые дает encoding/json: float64 для чисел, map[string]interface{} для объектов и т.д.
+type JSONCodec struct{}
+
+func (JSONCodec) Encode(w io.Writer, items map[string]Item) error {
+	return json.NewEncoder(w).Encode(items)
+}
+
+func (JSONCodec) Decode(r io.Reader) (map[string]Item, error) {
+	var items map[string]Item
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// NewItem создает элемент для загрузки в кеш, нужен при реализации своего Codec.
+// Нулевое expiration означает бессрочный элемент
+func NewItem(value interface{}, createdAt, expiration time.Time) Item {
+	item := Item{
+		value:     value,
+		createdAt: createdAt,
+	}
+
+	if !expiration.IsZero() {
+		item.expiration = expiration.UnixNano()
+	}
+
+	return item
+}
+
+// itemData - сериализуемое представление Item
+type itemData struct {
+	Value      interface{} `json:"value"`
+	CreatedAt  time.Time   `json:"created_at"`
+	Expiration time.Time   `json:"expiration,omitempty"`
+}
+
+func (i Item) data() itemData {
+	return itemData{
+		Value:      i.value,
+		CreatedAt:  i.createdAt,
+		Expiration: i.Expiration(),
+	}
+}
+
+func (i Item) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(i.data()); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (i *Item) GobDecode(data []byte) error {
+	var d itemData
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&d); err != nil {
+		return err
+	}
+
+	*i = NewItem(d.Value, d.CreatedAt, d.Expiration)
+
+	return nil
+}
+
+func (i Item) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.data())
+}
+
+func (i *Item) UnmarshalJSON(data []byte) error {
+	var d itemData
+	if err := json.Unmarshal(data, &d); err != nil {
+		return err
+	}
+
+	*i = NewItem(d.Value, d.CreatedAt, d.Expiration)
+
+	return nil
+}
@@ -0,0 +1,270 @@
+package internal
+
+import (
+	"sort"
+	"time"
+)
+
+// Списки хранятся как []interface{}, множества - как map[string]struct{}.
+// Операции не изменяют сохраненное значение, а записывают новую копию,
+// поэтому значение, полученное через Get, можно читать без блокировок
+
+// LPush атомарно добавляет values в начало списка key и возвращает его длину.
+// values добавляются по одному, как в Redis: LPush(k, ttl, a, b) дает список [b a].
+// Отсутствующий или просроченный список создается с временем жизни ttl,
+// у существующего время истечения сохраняется. Для значения другого типа возвращается ErrTypeMismatch
+func (c *InMemoryCache) LPush(key string, ttl time.Duration, values ...interface{}) (int, error) {
+	return c.push(key, ttl, values, true)
+}
+
+// RPush атомарно добавляет values в конец списка key и возвращает его длину, см. LPush
+func (c *InMemoryCache) RPush(key string, ttl time.Duration, values ...interface{}) (int, error) {
+	return c.push(key, ttl, values, false)
+}
+
+// LPop атомарно извлекает первый элемент списка key, found = false для отсутствующего или пустого списка.
+// Опустевший список удаляется из кеша
+func (c *InMemoryCache) LPop(key string) (value interface{}, found bool, err error) {
+	return c.pop(key, true)
+}
+
+// RPop атомарно извлекает последний элемент списка key, см. LPop
+func (c *InMemoryCache) RPop(key string) (value interface{}, found bool, err error) {
+	return c.pop(key, false)
+}
+
+// LLen возвращает длину списка key, 0 для отсутствующего
+func (c *InMemoryCache) LLen(key string) (int, error) {
+	var n int
+	err := c.readCollection(key, func(value interface{}) error {
+		list, ok := value.([]interface{})
+		if !ok {
+			return ErrTypeMismatch
+		}
+		n = len(list)
+
+		return nil
+	})
+
+	return n, err
+}
+
+// SAdd атомарно добавляет members в множество key и возвращает количество новых элементов.
+// Время жизни применяется как в LPush
+func (c *InMemoryCache) SAdd(key string, ttl time.Duration, members ...string) (int, error) {
+	added := 0
+	err := c.mutateCollection(key, ttl, func(value interface{}, found bool) (interface{}, error) {
+		set, ok := value.(map[string]struct{})
+		if found && !ok {
+			return nil, ErrTypeMismatch
+		}
+
+		next := make(map[string]struct{}, len(set)+len(members))
+		for m := range set {
+			next[m] = struct{}{}
+		}
+		for _, m := range members {
+			if _, exists := next[m]; !exists {
+				next[m] = struct{}{}
+				added++
+			}
+		}
+
+		return next, nil
+	})
+
+	return added, err
+}
+
+// SRem атомарно удаляет members из множества key и возвращает количество удаленных.
+// Опустевшее множество удаляется из кеша
+func (c *InMemoryCache) SRem(key string, members ...string) (int, error) {
+	removed := 0
+	err := c.mutateCollection(key, DefaultExpiration, func(value interface{}, found bool) (interface{}, error) {
+		if !found {
+			return nil, nil
+		}
+
+		set, ok := value.(map[string]struct{})
+		if !ok {
+			return nil, ErrTypeMismatch
+		}
+
+		next := make(map[string]struct{}, len(set))
+		for m := range set {
+			next[m] = struct{}{}
+		}
+		for _, m := range members {
+			if _, exists := next[m]; exists {
+				delete(next, m)
+				removed++
+			}
+		}
+
+		if len(next) == 0 {
+			return nil, nil
+		}
+
+		return next, nil
+	})
+
+	return removed, err
+}
+
+// SIsMember проверяет наличие member в множестве key
+func (c *InMemoryCache) SIsMember(key string, member string) (bool, error) {
+	var exists bool
+	err := c.readCollection(key, func(value interface{}) error {
+		set, ok := value.(map[string]struct{})
+		if !ok {
+			return ErrTypeMismatch
+		}
+		_, exists = set[member]
+
+		return nil
+	})
+
+	return exists, err
+}
+
+// SMembers возвращает элементы множества key в отсортированном порядке, nil для отсутствующего
+func (c *InMemoryCache) SMembers(key string) ([]string, error) {
+	var members []string
+	err := c.readCollection(key, func(value interface{}) error {
+		set, ok := value.(map[string]struct{})
+		if !ok {
+			return ErrTypeMismatch
+		}
+
+		members = make([]string, 0, len(set))
+		for m := range set {
+			members = append(members, m)
+		}
+		sort.Strings(members)
+
+		return nil
+	})
+
+	return members, err
+}
+
+func (c *InMemoryCache) push(key string, ttl time.Duration, values []interface{}, front bool) (int, error) {
+	n := 0
+	err := c.mutateCollection(key, ttl, func(value interface{}, found bool) (interface{}, error) {
+		list, ok := value.([]interface{})
+		if found && !ok {
+			return nil, ErrTypeMismatch
+		}
+
+		next := make([]interface{}, 0, len(list)+len(values))
+		if front {
+			for i := len(values) - 1; i >= 0; i-- {
+				next = append(next, values[i])
+			}
+			next = append(next, list...)
+		} else {
+			next = append(append(next, list...), values...)
+		}
+		n = len(next)
+
+		return next, nil
+	})
+
+	return n, err
+}
+
+func (c *InMemoryCache) pop(key string, front bool) (value interface{}, found bool, err error) {
+	err = c.mutateCollection(key, DefaultExpiration, func(current interface{}, exists bool) (interface{}, error) {
+		if !exists {
+			return nil, nil
+		}
+
+		list, ok := current.([]interface{})
+		if !ok {
+			return nil, ErrTypeMismatch
+		}
+		if len(list) == 0 {
+			return nil, nil
+		}
+
+		found = true
+		var rest []interface{}
+		if front {
+			value, rest = list[0], list[1:]
+		} else {
+			value, rest = list[len(list)-1], list[:len(list)-1]
+		}
+
+		if len(rest) == 0 {
+			return nil, nil
+		}
+
+		return append([]interface{}(nil), rest...), nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return value, found, nil
+}
+
+// mutateCollection заменяет значение key результатом fn под блокировкой кеша.
+// Новое значение получает время жизни ttl, замена существующего сохраняет время его истечения,
+// nil удаляет ключ. При ошибке fn кеш не изменяется
+func (c *InMemoryCache) mutateCollection(key string, ttl time.Duration, fn func(value interface{}, found bool) (interface{}, error)) error {
+	if err := c.checkKey(key); err != nil {
+		return err
+	}
+
+	if c.closed.Load() {
+		return ErrClosed
+	}
+
+	if c.keyLocking {
+		defer c.keyLocks.lock(key)()
+	}
+
+	c.rmu.Lock()
+	defer c.unlock()
+
+	var value interface{}
+	old, found := c.cache[key]
+	if found && c.expired(old) {
+		found = false
+	}
+	if found {
+		value = c.itemValue(old)
+	}
+
+	next, err := fn(value, found)
+	if err != nil {
+		return err
+	}
+
+	if next == nil {
+		if found {
+			c.evict(key, ReasonDeleted)
+		}
+
+		return nil
+	}
+
+	item := c.newItem(key, next, ttl)
+	if found {
+		item.expiration = old.expiration
+	}
+
+	return c.storeItem(key, item)
+}
+
+func (c *InMemoryCache) readCollection(key string, fn func(value interface{}) error) error {
+	c.rmu.RLock()
+	defer c.rmu.RUnlock()
+
+	item, found := c.cache[key]
+	if !found || c.expired(item) {
+		return nil
+	}
+
+	return fn(c.itemValue(item))
+}
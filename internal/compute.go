@@ -0,0 +1,50 @@
+package internal
+
+import "time"
+
+// GetOrCompute возвращает значение по ключу, а при его отсутствии вычисляет его функцией compute
+// и записывает в кеш на возвращенный compute срок. Вычисления одного ключа выполняются по очереди,
+// поэтому конкурентные вызовы не вычисляют значение повторно. Ошибка compute возвращается как есть,
+// значение при этом не сохраняется
+func (c *InMemoryCache) GetOrCompute(key string, compute func() (interface{}, time.Duration, error)) (interface{}, error) {
+	if value, found := c.Get(key); found {
+		return value, nil
+	}
+
+	if err := c.checkKey(key); err != nil {
+		return nil, err
+	}
+
+	defer c.keyLocks.lock(key)()
+
+	// Пока ждали блокировку ключа, значение мог вычислить другой вызов
+	if value, found := c.Get(key); found {
+		return value, nil
+	}
+
+	value, duration, err := c.callCompute(compute)
+	if err != nil {
+		return nil, err
+	}
+
+	c.store(key, c.newItem(key, value, duration))
+
+	return value, nil
+}
+
+// Update заменяет значение по ключу результатом fn, которой передается текущее значение
+// и признак его наличия. Обновления одного ключа выполняются по очереди.
+// Недопустимый ключ (см. SetE) не записывается, fn для него не вызывается
+func (c *InMemoryCache) Update(key string, fn func(value interface{}, found bool) interface{}, duration time.Duration) interface{} {
+	if err := c.checkKey(key); err != nil {
+		return nil
+	}
+
+	defer c.keyLocks.lock(key)()
+
+	value, found := c.Get(key)
+	value = fn(value, found)
+	c.store(key, c.newItem(key, value, duration))
+
+	return value
+}
@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"errors"
+	"time"
+)
+
+// errNotSwapped прерывает запись CompareAndSwap, если текущее значение отличается от ожидаемого
+var errNotSwapped = errors.New("current value differs")
+
+// Add записывает значение, только если живого элемента с ключом нет, иначе возвращает ErrExists.
+// Проверка и запись выполняются атомарно
+func (c *InMemoryCache) Add(key string, value interface{}, duration time.Duration) error {
+	return c.setIf(key, value, duration, func(found bool) error {
+		if found {
+			return ErrExists
+		}
+
+		return nil
+	})
+}
+
+// Replace заменяет значение живого элемента, а если его нет, возвращает ErrNotFound.
+// Время жизни задается заново, как в SetE. Проверка и запись выполняются атомарно
+func (c *InMemoryCache) Replace(key string, value interface{}, duration time.Duration) error {
+	return c.setIf(key, value, duration, func(found bool) error {
+		if !found {
+			return ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+// CompareAndSwap записывает value на duration, только если текущее живое значение равно expected, и возвращает,
+// была ли запись. Значения сравниваются функцией из WithEqualitySkip, без нее - reflect.DeepEqual.
+// Для отсутствующего ключа возвращает false. Сравнение и запись выполняются атомарно
+func (c *InMemoryCache) CompareAndSwap(key string, expected, value interface{}, duration time.Duration) (bool, error) {
+	err := c.setIfItem(key, value, duration, func(current Item, found bool) error {
+		if !found || !c.valuesEqual(c.itemValue(current), expected) {
+			return errNotSwapped
+		}
+
+		return nil
+	})
+	if err == errNotSwapped {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// setIf записывает значение, если check для наличия живого элемента не вернула ошибку
+func (c *InMemoryCache) setIf(key string, value interface{}, duration time.Duration, check func(found bool) error) error {
+	return c.setIfItem(key, value, duration, func(_ Item, found bool) error {
+		return check(found)
+	})
+}
+
+// setIfItem записывает значение, если check для текущего живого элемента не вернула ошибку.
+// Элемент готовится до захвата блокировки, check и запись выполняются под ней
+func (c *InMemoryCache) setIfItem(key string, value interface{}, duration time.Duration, check func(current Item, found bool) error) error {
+	if err := c.checkKey(key); err != nil {
+		return err
+	}
+
+	if c.keyLocking {
+		defer c.keyLocks.lock(key)()
+	}
+
+	item := c.newItem(key, value, duration)
+
+	c.rmu.Lock()
+	defer c.unlock()
+
+	current, found := c.cache[key]
+	if found && c.expired(current) {
+		found = false
+	}
+
+	if err := check(current, found); err != nil {
+		return err
+	}
+
+	return c.storeItem(key, item)
+}
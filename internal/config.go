@@ -0,0 +1,56 @@
+package internal
+
+import "time"
+
+// CacheConfig - текущие настройки кеша
+type CacheConfig struct {
+	DefaultExpiration time.Duration `json:"default_expiration"`
+	CleanupInterval   time.Duration `json:"cleanup_interval"`
+	// Количество сегментов, 1 для несегментированного кеша
+	Shards       int           `json:"shards"`
+	StrictTTL    bool          `json:"strict_ttl"`
+	TTLOverride  time.Duration `json:"ttl_override"`
+	MaxKeyLength int           `json:"max_key_length"`
+	// Ограничения количества элементов и их размера (0 - без ограничения) и поведение при их достижении
+	MaxEntries int            `json:"max_entries"`
+	MaxBytes   int64          `json:"max_bytes"`
+	Policy     EvictionPolicy `json:"policy"`
+}
+
+// Configurable реализуют кеши, сообщающие свои настройки. Cache, возвращенный NewInMemoryCache,
+// всегда его реализует:
+//
+//	if cfg, ok := cache.(Configurable); ok {
+//		ttl := cfg.Config().DefaultExpiration
+//	}
+type Configurable interface {
+	Config() CacheConfig
+}
+
+// Config возвращает текущие настройки кеша
+func (c *InMemoryCache) Config() CacheConfig {
+	return CacheConfig{
+		DefaultExpiration: time.Duration(c.defaultExpiration.Load()),
+		CleanupInterval:   c.cleanupInterval,
+		Shards:            1,
+		StrictTTL:         c.strictTTL,
+		TTLOverride:       c.ttlOverride,
+		MaxKeyLength:      c.maxKeyLength,
+		MaxEntries:        c.maxEntries,
+		MaxBytes:          c.maxBytes,
+		Policy:            c.policyKind(),
+	}
+}
+
+// Config возвращает текущие настройки кеша, у всех сегментов они одинаковы
+func (c *ShardedCache) Config() CacheConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	cfg := c.shards[0].Config()
+	cfg.Shards = len(c.shards)
+	cfg.MaxEntries *= len(c.shards)
+	cfg.MaxBytes *= int64(len(c.shards))
+
+	return cfg
+}
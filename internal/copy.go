@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/gob"
+	"reflect"
+)
+
+// CopyMode - когда кеш копирует значения, см. WithValueCopy. Режимы можно объединять: CopyOnSet|CopyOnGet
+type CopyMode int
+
+const (
+	// CopyOnSet - записывается копия значения, последующие изменения переданного объекта кеш не видит
+	CopyOnSet CopyMode = 1 << iota
+	// CopyOnGet - чтение возвращает копию, изменения полученного объекта не затрагивают кеш
+	CopyOnGet
+)
+
+// WithValueCopy копирует значения функцией clone при записи и (или) чтении в зависимости от mode,
+// чтобы указатели, срезы и map в кеше нельзя было изменить снаружи. clone должна возвращать
+// глубокую копию, готовая копия через gob - GobClone. При панике clone используется исходное значение
+func WithValueCopy(clone func(value interface{}) interface{}, mode CopyMode) Option {
+	return func(o *options) {
+		o.clone = clone
+		o.copyMode = mode
+	}
+}
+
+// GobClone возвращает глубокую копию value, закодировав и раскодировав его через gob.
+// Неэкспортируемые поля не копируются, типы, которые gob не кодирует, вызывают панику
+func GobClone(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+		panic(err)
+	}
+
+	copied := reflect.New(reflect.TypeOf(value))
+	if err := gob.NewDecoder(&buf).DecodeValue(copied); err != nil {
+		panic(err)
+	}
+
+	return copied.Elem().Interface()
+}
+
+// copyValue копирует значение, если mode входит в WithValueCopy
+func (c *InMemoryCache) copyValue(value interface{}, mode CopyMode) interface{} {
+	if c.clone == nil || c.copyMode&mode == 0 {
+		return value
+	}
+
+	return c.transform(c.clone, value)
+}
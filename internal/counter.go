@@ -0,0 +1,65 @@
+package internal
+
+import "time"
+
+// Increment атомарно увеличивает счетчик key на delta и возвращает новое значение.
+// Отсутствующий или просроченный счетчик создается со значением из WithCounterDefault (по-умолчанию 0),
+// увеличенным на delta, и временем жизни по-умолчанию. См. IncrementWithTTL
+func (c *InMemoryCache) Increment(key string, delta int64) (int64, error) {
+	return c.IncrementWithTTL(key, delta, DefaultExpiration)
+}
+
+// Decrement атомарно уменьшает счетчик key на delta, как Increment с -delta
+func (c *InMemoryCache) Decrement(key string, delta int64) (int64, error) {
+	return c.IncrementWithTTL(key, -delta, DefaultExpiration)
+}
+
+// IncrementWithTTL атомарно увеличивает счетчик key на delta и возвращает новое значение.
+// Отсутствующий или просроченный счетчик создается со значением из WithCounterDefault,
+// увеличенным на delta, и временем жизни ttl,
+// у существующего время истечения сохраняется - так строится ограничитель частоты с фиксированным окном.
+// Поддерживаются значения int и int64, для значения другого типа возвращается ErrTypeMismatch
+func (c *InMemoryCache) IncrementWithTTL(key string, delta int64, ttl time.Duration) (int64, error) {
+	if err := c.checkKey(key); err != nil {
+		return 0, err
+	}
+
+	if c.keyLocking {
+		defer c.keyLocks.lock(key)()
+	}
+
+	c.rmu.Lock()
+	defer c.unlock()
+
+	old, found := c.cache[key]
+	if !found || c.expired(old) {
+		total := c.counterDefault + delta
+		if err := c.storeItem(key, c.newItem(key, total, ttl)); err != nil {
+			return 0, err
+		}
+
+		return total, nil
+	}
+
+	var (
+		next  interface{}
+		total int64
+	)
+
+	switch v := c.itemValue(old).(type) {
+	case int64:
+		total = v + delta
+		next = total
+	case int:
+		total = int64(v) + delta
+		next = int(total)
+	default:
+		return 0, ErrTypeMismatch
+	}
+
+	item := c.newItem(key, next, ttl)
+	item.expiration = old.expiration
+	c.storeItem(key, item)
+
+	return total, nil
+}
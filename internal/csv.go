@@ -0,0 +1,46 @@
+package internal
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// ExportCSV записывает живые элементы в w в формате CSV с колонками key, value, created_at, expiration,
+// отсортированными по ключу. Значение выводится как %v, время - в RFC 3339, у бессрочных
+// элементов колонка expiration пустая. Предназначен для просмотра человеком, загрузить
+// результат обратно нельзя - для этого есть SaveFile и LoadFile
+func (c *InMemoryCache) ExportCSV(w io.Writer) error {
+	items := c.snapshotItems()
+
+	keys := make([]string, 0, len(items))
+	for k := range items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"key", "value", "created_at", "expiration"}); err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		i := items[k]
+
+		expiration := ""
+		if i.expiration != 0 {
+			expiration = i.Expiration().Format(time.RFC3339Nano)
+		}
+
+		record := []string{k, fmt.Sprintf("%v", i.value), i.createdAt.Format(time.RFC3339Nano), expiration}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"context"
+	"time"
+)
+
+// ContextCache - вариант Cache, методы которого принимают context.Context.
+// Кеш в памяти не блокируется надолго, поэтому ctx проверяется перед операцией,
+// а в FetchCtx еще и передается загрузчику
+type ContextCache interface {
+	GetCtx(ctx context.Context, key string) (interface{}, bool, error)
+	SetCtx(ctx context.Context, key string, value interface{}, duration time.Duration) error
+	DeleteCtx(ctx context.Context, key string) error
+	FetchCtx(ctx context.Context, key string, ttl time.Duration, loader func(ctx context.Context) (interface{}, error)) (interface{}, error)
+}
+
+// GetCtx - Get, который возвращает ctx.Err(), если ctx уже завершен
+func (c *InMemoryCache) GetCtx(ctx context.Context, key string) (interface{}, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	value, found := c.Get(key)
+
+	return value, found, nil
+}
+
+// SetCtx - SetE, который не записывает значение и возвращает ctx.Err(), если ctx уже завершен
+func (c *InMemoryCache) SetCtx(ctx context.Context, key string, value interface{}, duration time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.SetE(key, value, duration)
+}
+
+// DeleteCtx - Delete, который не удаляет элемент и возвращает ctx.Err(), если ctx уже завершен
+func (c *InMemoryCache) DeleteCtx(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.Delete(key)
+}
+
+// FetchCtx - Fetch, передающий ctx загрузчику. Отмена ctx прерывает ожидание загрузки,
+// начатой другим вызовом, а загрузка, начатая этим вызовом, получает его ctx
+// и вместе с ним может отмениться у всех, кто ее ждет
+func (c *InMemoryCache) FetchCtx(ctx context.Context, key string, ttl time.Duration, loader func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return c.fetch(ctx, key, ttl, loader)
+}
+
+// GetCtx - см. InMemoryCache.GetCtx
+func (c *ShardedCache) GetCtx(ctx context.Context, key string) (interface{}, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	value, found := c.Get(key)
+
+	return value, found, nil
+}
+
+// SetCtx - см. InMemoryCache.SetCtx
+func (c *ShardedCache) SetCtx(ctx context.Context, key string, value interface{}, duration time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.SetE(key, value, duration)
+}
+
+// DeleteCtx - см. InMemoryCache.DeleteCtx
+func (c *ShardedCache) DeleteCtx(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.Delete(key)
+}
+
+// FetchCtx - см. InMemoryCache.FetchCtx
+func (c *ShardedCache) FetchCtx(ctx context.Context, key string, ttl time.Duration, loader func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	c.mu.RLock()
+	s := c.shard(key)
+	c.mu.RUnlock()
+
+	return s.FetchCtx(ctx, key, ttl, loader)
+}
@@ -0,0 +1,135 @@
+// Package debugapi - отладочный HTTP-обработчик и переменная expvar для кеша:
+//
+//	GET  /debug/cache                - количество элементов, доля попаданий и самые читаемые ключи (?top=N)
+//	POST /debug/cache/delete-expired - удаление просроченных элементов
+//	POST /debug/cache/flush          - очистка кеша
+//
+// Изменяющие запросы требуют заголовка Authorization: Bearer <token>. Обработчик монтируется
+// в существующий mux как есть: mux.Handle("/debug/cache/", debugapi.New(cache, token))
+package debugapi
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"strconv"
+
+	"InMemoryCache/internal"
+)
+
+// DefaultTopKeys - количество самых читаемых ключей в отчете по-умолчанию
+const DefaultTopKeys = 10
+
+// Admin - кеш, который обслуживает обработчик: *internal.InMemoryCache или *internal.ShardedCache.
+// Счетчики обращений к ключам ведутся только с internal.WithHitCounting
+type Admin interface {
+	Stats() internal.CacheStats
+	TopKeys(n int) []internal.KeyStat
+	DeleteExpired() int
+	Flush()
+}
+
+// Report - ответ GET /debug/cache и значение переменной expvar
+type Report struct {
+	Items    int                `json:"items"`
+	Hits     uint64             `json:"hits"`
+	Misses   uint64             `json:"misses"`
+	HitRatio float64            `json:"hit_ratio"`
+	TopKeys  []internal.KeyStat `json:"top_keys"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type handler struct {
+	admin Admin
+	token string
+}
+
+// New возвращает отладочный обработчик для admin. С пустым token изменяющие запросы
+// всегда отклоняются, а отчет остается доступен
+func New(admin Admin, token string) http.Handler {
+	h := handler{admin: admin, token: token}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /debug/cache", h.report)
+	mux.HandleFunc("POST /debug/cache/delete-expired", h.authorized(h.deleteExpired))
+	mux.HandleFunc("POST /debug/cache/flush", h.authorized(h.flush))
+
+	return mux
+}
+
+// Publish публикует отчет по admin в expvar под именем name, он будет виден в /debug/vars.
+// Как и expvar.Publish, вызывает панику при повторном имени
+func Publish(name string, admin Admin) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return NewReport(admin, DefaultTopKeys)
+	}))
+}
+
+// NewReport собирает отчет по admin с top самыми читаемыми ключами
+func NewReport(admin Admin, top int) Report {
+	stats := admin.Stats()
+
+	report := Report{
+		Items:   stats.Items,
+		Hits:    stats.Hits,
+		Misses:  stats.Misses,
+		TopKeys: admin.TopKeys(top),
+	}
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		report.HitRatio = float64(stats.Hits) / float64(lookups)
+	}
+
+	return report
+}
+
+func (h handler) report(w http.ResponseWriter, r *http.Request) {
+	top := DefaultTopKeys
+	if s := r.URL.Query().Get("top"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "top must be a non-negative integer")
+			return
+		}
+		top = n
+	}
+
+	writeJSON(w, http.StatusOK, NewReport(h.admin, top))
+}
+
+func (h handler) deleteExpired(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]int{"deleted": h.admin.DeleteExpired()})
+}
+
+func (h handler) flush(w http.ResponseWriter, _ *http.Request) {
+	h.admin.Flush()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorized пропускает к next только запросы с токеном h.token
+func (h handler) authorized(next http.HandlerFunc) http.HandlerFunc {
+	want := []byte("Bearer " + h.token)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if h.token == "" || subtle.ConstantTimeCompare(got, want) != 1 {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorResponse{Error: msg})
+}
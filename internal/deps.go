@@ -0,0 +1,116 @@
+package internal
+
+import "time"
+
+// depGraph - граф зависимостей между ключами, изменяется под блокировкой на запись
+type depGraph struct {
+	// dependents - ключи, зависящие от ключа
+	dependents map[string]map[string]struct{}
+	// parents - ключи, от которых зависит ключ
+	parents map[string][]string
+}
+
+// SetWithDeps записывает значение, производное от ключей dependsOn: при удалении
+// или истечении любого из них элемент удаляется вместе с ним (причина ReasonDependency).
+// Повторная запись ключа через Set убирает его зависимости.
+// Возвращает ErrDependencyCycle, если ключ прямо или косвенно зависел бы от самого себя
+func (c *InMemoryCache) SetWithDeps(key string, value interface{}, d time.Duration, dependsOn ...string) error {
+	if err := c.checkKey(key); err != nil {
+		return err
+	}
+
+	item := c.newItem(key, value, d)
+
+	c.rmu.Lock()
+	defer c.unlock()
+
+	for _, parent := range dependsOn {
+		if c.deps.reaches(parent, key) {
+			return ErrDependencyCycle
+		}
+	}
+
+	if err := c.storeItem(key, item); err != nil {
+		return err
+	}
+
+	c.deps.link(key, dependsOn)
+
+	return nil
+}
+
+// link добавляет зависимости ключа key от parents
+func (g *depGraph) link(key string, parents []string) {
+	if len(parents) == 0 {
+		return
+	}
+
+	if g.dependents == nil {
+		g.dependents = make(map[string]map[string]struct{})
+		g.parents = make(map[string][]string)
+	}
+
+	for _, p := range parents {
+		if g.dependents[p] == nil {
+			g.dependents[p] = make(map[string]struct{})
+		}
+		g.dependents[p][key] = struct{}{}
+	}
+
+	g.parents[key] = append(g.parents[key], parents...)
+}
+
+// unlink убирает зависимости ключа key от его родителей
+func (g *depGraph) unlink(key string) {
+	for _, p := range g.parents[key] {
+		delete(g.dependents[p], key)
+		if len(g.dependents[p]) == 0 {
+			delete(g.dependents, p)
+		}
+	}
+
+	delete(g.parents, key)
+}
+
+// detach убирает удаленный ключ из графа и возвращает зависевшие от него ключи
+func (g *depGraph) detach(key string) []string {
+	if g.dependents == nil {
+		return nil
+	}
+
+	g.unlink(key)
+
+	children := g.dependents[key]
+	delete(g.dependents, key)
+
+	keys := make([]string, 0, len(children))
+	for k := range children {
+		keys = append(keys, k)
+	}
+
+	return keys
+}
+
+// reaches проверяет, зависит ли from прямо или косвенно от to (или совпадает с ним)
+func (g *depGraph) reaches(from, to string) bool {
+	visited := make(map[string]bool)
+	stack := []string{from}
+
+	for len(stack) > 0 {
+		k := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if k == to {
+			return true
+		}
+
+		if visited[k] {
+			continue
+		}
+		visited[k] = true
+
+		stack = append(stack, g.parents[k]...)
+	}
+
+	return false
+}
@@ -0,0 +1,25 @@
+// Package internal реализует потокобезопасный кеш в памяти со временем жизни элементов.
+//
+// Кеш создается New с опциями, например New(WithDefaultExpiration(time.Minute), WithMaxEntries(1000)).
+// NewInMemoryCache с позиционными аргументами оставлен для совместимости.
+//
+// Для чтения через кеш с загрузкой отсутствующих значений рекомендуется Fetch: он объединяет
+// одновременные промахи, перехватывает панику загрузчика и ведет статистику попаданий.
+//
+// Гарантии согласованности InMemoryCache:
+//   - каждая операция атомарна: Get, Set, Delete и их варианты выполняются под одной блокировкой
+//     и не наблюдают промежуточных состояний других операций;
+//   - операции над несколькими ключами (DeleteMany, Rotate, Snapshot, Flush и т.д.)
+//     выполняются под одной блокировкой и видны другим пользователям целиком;
+//   - между отдельными вызовами атомарности нет: Get и следующий за ним Set могут быть разделены
+//     чужой записью. Для нескольких операций как единого целого используйте Atomic,
+//     для чтения-изменения-записи одного ключа - Update или GetOrCompute;
+//   - GC удаляет элемент, только если он просрочен в момент удаления, поэтому элемент,
+//     перезаписанный после сбора просроченных ключей, не теряется;
+//   - обработчики удаления вызываются после снятия блокировки и могут видеть кеш
+//     уже после последующих изменений;
+//   - в режиме WithAsyncWrites запись становится видна только после ее применения из буфера.
+//
+// ShardedCache дает те же гарантии в пределах одного ключа, но операции над несколькими ключами
+// (Flush, Count, Stats) выполняются по сегментам поочередно и атомарными не являются
+package internal
@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+)
+
+// newAEAD создает шифр AES-GCM для WithEncryption, без ключа возвращает nil
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if key == nil {
+		return nil, nil
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func validAESKey(size int) bool {
+	return size == 16 || size == 24 || size == 32
+}
+
+// encrypt шифрует данные, случайный nonce хранится перед шифротекстом
+func (c *InMemoryCache) encrypt(data []byte) []byte {
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(data)+c.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		panic(fmt.Sprintf("cache: reading encryption nonce: %v", err))
+	}
+
+	return c.aead.Seal(nonce, nonce, data, nil)
+}
+
+// decrypt расшифровывает данные из encrypt, для измененного шифротекста возвращает false
+func (c *InMemoryCache) decrypt(data []byte) ([]byte, bool) {
+	if len(data) < c.aead.NonceSize() {
+		return nil, false
+	}
+
+	nonce, sealed := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, false
+	}
+
+	return plain, true
+}
@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newEncryptedCache(opts ...Option) *InMemoryCache {
+	key := bytes.Repeat([]byte{7}, 32)
+	return NewInMemoryCache(0, 0, append([]Option{WithEncryption(key)}, opts...)...).(*InMemoryCache)
+}
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"plain":      nil,
+		"compressed": {WithCompression(16)},
+	} {
+		c := newEncryptedCache(opts...)
+		secret := bytes.Repeat([]byte("secret-"), 100)
+		c.Set("secret", secret, NoExpiration)
+		c.Set("number", 42, NoExpiration)
+
+		item := c.cache["secret"]
+		if !item.encrypted || bytes.Contains(item.value.([]byte), []byte("secret")) {
+			t.Errorf("%s: value is stored in plain text", name)
+		}
+
+		if value, _ := c.Get("secret"); !bytes.Equal(value.([]byte), secret) {
+			t.Errorf("%s: Get returned %q, want the original value", name, value)
+		}
+
+		// Значения других типов не шифруются
+		if value, _ := c.Get("number"); value != 42 || c.cache["number"].encrypted {
+			t.Errorf("%s: Get(number) = %v, encrypted = %v", name, value, c.cache["number"].encrypted)
+		}
+
+		c.Close()
+	}
+}
+
+func TestEncryptionDetectsTampering(t *testing.T) {
+	c := newEncryptedCache()
+	defer c.Close()
+
+	c.Set("secret", []byte("value"), NoExpiration)
+
+	sealed := c.cache["secret"].value.([]byte)
+	sealed[len(sealed)-1] ^= 1
+
+	if value, found := c.Get("secret"); !found || value != nil {
+		t.Errorf("Get of tampered value = %v, %v, want nil, true", value, found)
+	}
+
+	c.Set("short", []byte("value"), NoExpiration)
+	item := c.cache["short"]
+	item.value = item.value.([]byte)[:4]
+	c.cache["short"] = item
+
+	if value, _ := c.Get("short"); value != nil {
+		t.Errorf("Get of truncated value = %v, want nil", value)
+	}
+}
+
+func TestInvalidEncryptionKey(t *testing.T) {
+	key := []byte("short")
+
+	if _, err := NewBuilder().With(WithEncryption(key)).Build(); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Build err = %v, want %v", err, ErrInvalidConfig)
+	}
+
+	path := filepath.Join(t.TempDir(), "cache.wal")
+	if _, err := NewInMemoryCacheFromWAL(path, 0, 0, WithEncryption(key)); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("NewInMemoryCacheFromWAL err = %v, want %v", err, ErrInvalidConfig)
+	}
+
+	caches := map[string]Cache{
+		"New":              New(WithEncryption(key)),
+		"NewInMemoryCache": NewInMemoryCache(0, 0, WithEncryption(key)),
+		"NewShardedCache":  NewShardedCache(4, 0, 0, WithEncryption(key)),
+	}
+	for name, c := range caches {
+		setter := c.(interface {
+			SetE(key string, value interface{}, duration time.Duration) error
+		})
+		if err := setter.SetE("secret", []byte("value"), NoExpiration); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("%s: SetE err = %v, want %v", name, err, ErrInvalidConfig)
+		}
+		if _, found := c.Get("secret"); found {
+			t.Errorf("%s: rejected value was stored", name)
+		}
+		c.Close()
+	}
+}
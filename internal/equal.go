@@ -0,0 +1,50 @@
+package internal
+
+import "reflect"
+
+// EqualSetMode определяет, что делает Set со значением, равным текущему (см. WithEqualitySkip)
+type EqualSetMode int
+
+const (
+	// EqualSkip - запись пропускается целиком, время жизни не меняется
+	EqualSkip EqualSetMode = iota + 1
+	// EqualRefreshTTL - обновляется только время истечения, значение и время записи остаются прежними
+	EqualRefreshTTL
+)
+
+// skipEqual проверяет, равно ли новое значение текущему живому, и в этом случае
+// применяет WithEqualitySkip вместо записи. Вызывается под блокировкой на запись
+func (c *InMemoryCache) skipEqual(key string, item Item) bool {
+	if c.equalMode == 0 {
+		return false
+	}
+
+	old, found := c.cache[key]
+	if !found || c.expired(old) || !c.valuesEqual(c.itemValue(old), c.itemValue(item)) {
+		return false
+	}
+
+	if c.equalMode == EqualRefreshTTL {
+		old.expiration = item.expiration
+		// Не удалось записать журнал - записываем элемент обычным путем, он вернет ошибку
+		if c.logSet(key, old) != nil {
+			return false
+		}
+		c.cache[key] = old
+		c.maybeCompactWAL()
+		c.rescheduleExpiry(key, old)
+	}
+
+	return true
+}
+
+// valuesEqual сравнивает значения функцией из WithEqualitySkip, при панике значения считаются разными
+func (c *InMemoryCache) valuesEqual(a, b interface{}) (equal bool) {
+	defer c.recoverPanic()
+
+	if c.equal == nil {
+		return reflect.DeepEqual(a, b)
+	}
+
+	return c.equal(a, b)
+}
@@ -0,0 +1,33 @@
+package internal
+
+import "errors"
+
+var (
+	// ErrPanic возвращается из GetOrCompute, если функция вычисления запаниковала
+	ErrPanic = errors.New("callback panicked")
+	// ErrKeyTooLong возвращается при записи ключа длиннее WithMaxKeyLength
+	ErrKeyTooLong = errors.New("key is too long")
+	// ErrDependencyCycle возвращается из SetWithDeps, если зависимость образует цикл
+	ErrDependencyCycle = errors.New("dependency cycle")
+	// ErrInvalidConfig возвращается из CacheBuilder.Build для недопустимых настроек
+	ErrInvalidConfig = errors.New("invalid cache config")
+	// ErrClosed возвращается из операций записи после Close
+	ErrClosed = errors.New("cache is closed")
+	// ErrTypeMismatch возвращается, если значение элемента не подходит для операции, например IncrementWithTTL
+	ErrTypeMismatch = errors.New("value type mismatch")
+	// ErrCacheFull возвращается при записи в заполненный кеш (см. WithMaxEntries и WithMaxBytes)
+	ErrCacheFull = errors.New("cache is full")
+	// ErrTooManyWatchers возвращается из Watch при превышении WithMaxWatchers
+	ErrTooManyWatchers = errors.New("too many watchers")
+	// ErrNotFound возвращается, если живого элемента с ключом нет, например из TTL, GetE и Delete
+	ErrNotFound = errors.New("key not found")
+	// ErrExists возвращается из Add, если живой элемент с ключом уже есть
+	ErrExists = errors.New("key already exists")
+
+	// ErrKeyNotFound - другое имя ErrNotFound, errors.Is подходит для обоих
+	ErrKeyNotFound = ErrNotFound
+	// ErrCacheClosed - другое имя ErrClosed
+	ErrCacheClosed = ErrClosed
+	// ErrKeyExists - другое имя ErrExists
+	ErrKeyExists = ErrExists
+)
@@ -0,0 +1,145 @@
+package internal
+
+// EvictionReason - причина удаления элемента из кеша
+type EvictionReason int
+
+const (
+	// ReasonExpired - истекло время жизни, элемент удален GC
+	ReasonExpired EvictionReason = iota + 1
+	// ReasonDeleted - элемент удален явно
+	ReasonDeleted
+	// ReasonRotated - элемент заменен новым содержимым кеша в Rotate
+	ReasonRotated
+	// ReasonDependency - удален элемент, от которого зависел данный (см. SetWithDeps)
+	ReasonDependency
+	// ReasonMemoryPressure - элемент вытеснен при превышении порога памяти (см. WithMemoryWatermark)
+	ReasonMemoryPressure
+	// ReasonFlushed - элемент удален вместе со всем содержимым кеша в Flush
+	ReasonFlushed
+	// ReasonCapacity - элемент вытеснен политикой вытеснения, чтобы освободить место (см. WithMaxEntries)
+	ReasonCapacity
+	// ReasonInvalidated - элемент изменен на другом узле (см. WithInvalidationBus)
+	ReasonInvalidated
+)
+
+func (r EvictionReason) String() string {
+	switch r {
+	case ReasonExpired:
+		return "expired"
+	case ReasonDeleted:
+		return "deleted"
+	case ReasonRotated:
+		return "rotated"
+	case ReasonDependency:
+		return "dependency"
+	case ReasonMemoryPressure:
+		return "memory_pressure"
+	case ReasonFlushed:
+		return "flushed"
+	case ReasonCapacity:
+		return "capacity"
+	case ReasonInvalidated:
+		return "invalidated"
+	default:
+		return "unknown"
+	}
+}
+
+// EvictedItem - удаленный из кеша элемент
+type EvictedItem struct {
+	Key    string
+	Value  interface{}
+	Reason EvictionReason
+}
+
+type evictionHandlers struct {
+	onEvicted      func(key string, value interface{}, reason EvictionReason)
+	onEvictedBatch func(items []EvictedItem)
+}
+
+func (h evictionHandlers) empty() bool {
+	return h.onEvicted == nil && h.onEvictedBatch == nil
+}
+
+// OnEvicted задает обработчик, который вызывается для каждого удаленного элемента.
+// Обработчик вызывается после снятия блокировки, nil отключает оповещение
+func (c *InMemoryCache) OnEvicted(fn func(key string, value interface{}, reason EvictionReason)) {
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+
+	c.handlers.onEvicted = fn
+}
+
+// OnEvictedBatch задает обработчик, который получает все элементы, удаленные одной операцией
+// (очисткой GC, DeleteMany и т.д.), одним вызовом. Может использоваться вместе с OnEvicted
+// или вместо него, nil отключает оповещение
+func (c *InMemoryCache) OnEvictedBatch(fn func(items []EvictedItem)) {
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+
+	c.handlers.onEvictedBatch = fn
+}
+
+// evict удаляет ключ и запоминает его для оповещения обработчиков,
+// вызывается под блокировкой на запись
+func (c *InMemoryCache) evict(key string, reason EvictionReason) bool {
+	item, found := c.removeItem(key)
+	if !found {
+		return false
+	}
+
+	c.journal.record(OpEvict, key, reason)
+	c.logger.Debug("cache evict", "key", key, "reason", reason.String())
+	// Истечение определяется временем, записанным в журнал, и воспроизводится без отдельной записи
+	if reason == ReasonExpired {
+		c.expiredRemovals++
+	} else {
+		c.logDelete(key)
+	}
+	// Другим узлам сообщаем только о явных удалениях, вытеснение касается лишь этого узла
+	if reason == ReasonDeleted || reason == ReasonRotated || reason == ReasonDependency {
+		c.invalidate(key)
+	}
+	if c.events.active() {
+		c.events.publish(Event{Type: EventEvicted, Key: key, Value: c.itemValue(item), Reason: reason})
+	}
+
+	if !c.handlers.empty() {
+		c.pending = append(c.pending, EvictedItem{Key: key, Value: c.itemValue(item), Reason: reason})
+	}
+
+	// Вместе с элементом удаляются зависящие от него
+	for _, dependent := range c.deps.detach(key) {
+		c.evict(dependent, ReasonDependency)
+	}
+
+	return true
+}
+
+// unlock снимает блокировку на запись и оповещает обработчики об элементах,
+// удаленных под этой блокировкой
+func (c *InMemoryCache) unlock() {
+	items, handlers := c.pending, c.handlers
+	c.pending = nil
+	c.rmu.Unlock()
+
+	c.notifyEvicted(handlers, items)
+}
+
+// notifyEvicted передает удаленные элементы обработчикам, вызывается без блокировки кеша.
+// Паника одного обработчика не мешает вызову остальных
+func (c *InMemoryCache) notifyEvicted(h evictionHandlers, items []EvictedItem) {
+	if len(items) == 0 {
+		return
+	}
+
+	if h.onEvictedBatch != nil {
+		c.callSafe(func() { h.onEvictedBatch(items) })
+	}
+
+	if h.onEvicted != nil {
+		for _, i := range items {
+			c.callSafe(func() { h.onEvicted(i.Key, i.Value, i.Reason) })
+		}
+	}
+}
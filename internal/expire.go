@@ -0,0 +1,65 @@
+package internal
+
+import "time"
+
+// Expire задает живому элементу новое время жизни ttl, отсчитанное от текущего момента,
+// как EXPIRE в Redis. NoExpiration делает элемент бессрочным, а ttl <= 0 удаляет его.
+// У элемента со скользящим временем жизни ttl становится новым периодом продления.
+// Возвращает false, если живого элемента нет, кеш закрыт или изменение не удалось записать в журнал
+func (c *InMemoryCache) Expire(key string, ttl time.Duration) bool {
+	if c.closed.Load() {
+		return false
+	}
+
+	c.rmu.Lock()
+	defer c.unlock()
+
+	item, found := c.cache[key]
+	if !found || c.expired(item) {
+		return false
+	}
+
+	if ttl <= 0 && ttl != NoExpiration {
+		return c.evict(key, ReasonDeleted)
+	}
+
+	item.expiration = expirationFor(c.nowNano(), ttl)
+	if item.idle > 0 {
+		item.idle = max(ttl, 0)
+	}
+
+	if err := c.logSet(key, item); err != nil {
+		c.logger.Warn("cache expire rejected", "key", key, "err", err)
+		return false
+	}
+
+	c.cache[key] = item
+	c.maybeCompactWAL()
+	c.rescheduleExpiry(key, item)
+
+	return true
+}
+
+// Persist делает живой элемент бессрочным, как PERSIST в Redis. То же, что Expire с NoExpiration
+func (c *InMemoryCache) Persist(key string) bool {
+	return c.Expire(key, NoExpiration)
+}
+
+// TTL возвращает оставшееся время жизни живого элемента, для бессрочного - NoExpiration.
+// Если живого элемента нет, возвращает ErrNotFound
+func (c *InMemoryCache) TTL(key string) (time.Duration, error) {
+	c.rmu.RLock()
+	defer c.rmu.RUnlock()
+
+	item, found := c.cache[key]
+	if !found || c.expired(item) {
+		return 0, ErrNotFound
+	}
+
+	if item.expiration == 0 {
+		return NoExpiration, nil
+	}
+
+	// С WithExpirationGrace элемент живет и после своего времени истечения
+	return max(c.remaining(item), 0), nil
+}
@@ -0,0 +1,28 @@
+package internal
+
+import "time"
+
+// Expirer реализуют значения, которые сами знают время своего истечения.
+// При WithValueExpiration время истечения такого значения берется из ExpireAt,
+// а продолжительность жизни, переданная в Set, игнорируется. Нулевое время означает бессрочный элемент
+type Expirer interface {
+	ExpireAt() time.Time
+}
+
+// valueExpiration возвращает время истечения из значения, реализующего Expirer, в UnixNano.
+// ok = false, если значение не реализует Expirer или ExpireAt запаниковал
+func (c *InMemoryCache) valueExpiration(value interface{}) (expiration int64, ok bool) {
+	e, isExpirer := value.(Expirer)
+	if !isExpirer {
+		return 0, false
+	}
+
+	defer c.recoverPanic()
+
+	at := e.ExpireAt()
+	if at.IsZero() {
+		return 0, true
+	}
+
+	return at.UnixNano(), true
+}
@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"time"
+
+	"InMemoryCache/internal/clock"
+)
+
+// expiryWatch - ожидающие истечения или удаления ключа и таймер проверки его истечения
+type expiryWatch struct {
+	chans []chan struct{}
+	timer clock.Timer
+}
+
+// ExpiryChan возвращает канал, который закрывается, когда элемент key истекает или удаляется
+// любым способом. Истечение отслеживается таймером, поэтому GC для этого не нужен.
+// Если живого элемента нет, возвращается уже закрытый канал
+func (c *InMemoryCache) ExpiryChan(key string) <-chan struct{} {
+	ch := make(chan struct{})
+
+	c.rmu.Lock()
+	defer c.unlock()
+
+	item, found := c.cache[key]
+	if !found || c.expired(item) {
+		close(ch)
+		return ch
+	}
+
+	if c.expiryWatches == nil {
+		c.expiryWatches = make(map[string]*expiryWatch)
+	}
+
+	w, watched := c.expiryWatches[key]
+	if !watched {
+		w = &expiryWatch{}
+		c.expiryWatches[key] = w
+		c.scheduleExpiry(key, w, item)
+	}
+	w.chans = append(w.chans, ch)
+
+	return ch
+}
+
+// scheduleExpiry заводит таймер проверки истечения элемента, вызывается под блокировкой на запись
+func (c *InMemoryCache) scheduleExpiry(key string, w *expiryWatch, item Item) {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+
+	if item.expiration == 0 {
+		return
+	}
+
+	// Таймер срабатывает сразу после истечения с учетом допуска, чтобы проверка его уже застала
+	w.timer = c.timeSource().AfterFunc(c.remaining(item)+c.expirationGrace+time.Millisecond, func() {
+		c.checkExpiry(key)
+	})
+}
+
+// checkExpiry удаляет элемент, если он истек, иначе переносит проверку на его новое время истечения
+func (c *InMemoryCache) checkExpiry(key string) {
+	c.rmu.Lock()
+	defer c.unlock()
+
+	w, watched := c.expiryWatches[key]
+	if !watched {
+		return
+	}
+
+	item, found := c.cache[key]
+	if found && c.reapable(item) {
+		c.evict(key, ReasonExpired)
+		return
+	}
+
+	// Устаревший элемент еще доступен GetStale, но для ожидающих он уже истек
+	if found && c.expired(item) {
+		c.fireExpiry(key)
+		return
+	}
+
+	if found {
+		c.scheduleExpiry(key, w, item)
+	}
+}
+
+// rescheduleExpiry добавляет новое время истечения элемента в очередь GC и переносит
+// проверку его истечения для ожидающих, вызывается под блокировкой на запись
+func (c *InMemoryCache) rescheduleExpiry(key string, item Item) {
+	c.expiries.push(key, item.expiration)
+	if w, watched := c.expiryWatches[key]; watched {
+		c.scheduleExpiry(key, w, item)
+	}
+}
+
+// fireExpiry закрывает каналы ожидающих удаления ключа, вызывается под блокировкой на запись
+func (c *InMemoryCache) fireExpiry(key string) {
+	w, watched := c.expiryWatches[key]
+	if !watched {
+		return
+	}
+
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+
+	for _, ch := range w.chans {
+		close(ch)
+	}
+
+	delete(c.expiryWatches, key)
+}
@@ -0,0 +1,118 @@
+package internal
+
+import "container/heap"
+
+// expiryEntry - время истечения ключа, записанное в очередь GC
+type expiryEntry struct {
+	expiration int64
+	key        string
+}
+
+// expiryQueue - min-куча времен истечения, по которой GC находит просроченные элементы без обхода
+// всего хранилища. Записи не удаляются при перезаписи или удалении ключа: устаревшая запись
+// отбрасывается, когда доходит до вершины. Изменяется под блокировкой на запись
+type expiryQueue []expiryEntry
+
+func (q expiryQueue) Len() int           { return len(q) }
+func (q expiryQueue) Less(i, j int) bool { return q[i].expiration < q[j].expiration }
+func (q expiryQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+
+func (q *expiryQueue) Push(x interface{}) {
+	*q = append(*q, x.(expiryEntry))
+}
+
+func (q *expiryQueue) Pop() interface{} {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return e
+}
+
+// push добавляет время истечения элемента, бессрочные элементы в очередь не попадают
+func (q *expiryQueue) push(key string, expiration int64) {
+	if expiration > 0 {
+		heap.Push(q, expiryEntry{expiration: expiration, key: key})
+	}
+}
+
+// popDue снимает с вершины запись, истекшую к deadline
+func (q *expiryQueue) popDue(deadline int64) (expiryEntry, bool) {
+	if len(*q) == 0 || (*q)[0].expiration > deadline {
+		return expiryEntry{}, false
+	}
+
+	return heap.Pop(q).(expiryEntry), true
+}
+
+// collectExpired удаляет просроченные элементы по очереди истечения и возвращает их количество.
+// При заданном WithGCBatchSize блокировка снимается после каждой порции ключей
+func (c *InMemoryCache) collectExpired() int {
+	batch := c.gcBatchSize
+	if batch <= 0 {
+		batch = -1
+	}
+
+	collected := 0
+	for {
+		n, more := c.collectBatch(batch)
+		collected += n
+		if !more {
+			break
+		}
+	}
+
+	c.logger.Debug("cache gc", "expired", collected)
+
+	return collected
+}
+
+// collectBatch удаляет не больше batch просроченных элементов (-1 - без ограничения)
+// под одной блокировкой, more сообщает, что в очереди остались истекшие записи
+func (c *InMemoryCache) collectBatch(batch int) (collected int, more bool) {
+	c.rmu.Lock()
+	defer c.unlock()
+
+	// Элементы живут еще grace после истечения и окно WithStaleWhileRevalidate после него
+	deadline := c.nowNano() - int64(c.expirationGrace+c.staleWindow)
+
+	for batch != 0 {
+		e, due := c.expiries.popDue(deadline)
+		if !due {
+			c.compactExpiries()
+			return collected, false
+		}
+
+		// Запись устарела, если ключ удален или его время истечения с тех пор изменилось
+		item, found := c.cache[e.key]
+		if !found || item.expiration != e.expiration || !c.reapable(item) {
+			continue
+		}
+
+		if c.evict(e.key, ReasonExpired) {
+			collected++
+			if batch > 0 {
+				batch--
+			}
+		}
+	}
+
+	return collected, true
+}
+
+// compactExpiries пересобирает очередь из хранилища, когда устаревших записей в ней
+// накопилось больше, чем элементов, вызывается под блокировкой на запись
+func (c *InMemoryCache) compactExpiries() {
+	if len(c.expiries) <= 2*len(c.cache)+1024 {
+		return
+	}
+
+	q := make(expiryQueue, 0, len(c.cache))
+	for k, i := range c.cache {
+		if i.expiration > 0 {
+			q = append(q, expiryEntry{expiration: i.expiration, key: k})
+		}
+	}
+	heap.Init(&q)
+
+	c.expiries = q
+}
@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// fetchStats - счетчики Fetch, изменяются без блокировки кеша
+type fetchStats struct {
+	hits     atomic.Uint64
+	misses   atomic.Uint64
+	loads    atomic.Uint64
+	loadTime atomic.Int64
+}
+
+// Fetch - рекомендуемый способ чтения через кеш: возвращает живое значение по ключу, а при его
+// отсутствии загружает его loader и записывает на срок ttl (DefaultExpiration и NoExpiration
+// работают как в Set). Одновременные промахи по одному ключу ждут одной загрузки.
+// Паника loader перехватывается и возвращается как ErrPanic, ошибка loader возвращается как есть,
+// в обоих случаях значение не сохраняется. Попадания, промахи и время загрузок видны в Stats
+func (c *InMemoryCache) Fetch(key string, ttl time.Duration, loader func() (interface{}, error)) (interface{}, error) {
+	return c.fetch(context.Background(), key, ttl, func(context.Context) (interface{}, error) {
+		return loader()
+	})
+}
+
+// fetch - общая часть Fetch и FetchCtx, отмена ctx прерывает ожидание чужой загрузки
+func (c *InMemoryCache) fetch(ctx context.Context, key string, ttl time.Duration, loader func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	if value, found := c.Get(key); found {
+		c.fetches.hits.Add(1)
+		return value, nil
+	}
+
+	if err := c.checkKey(key); err != nil {
+		return nil, err
+	}
+
+	c.fetches.misses.Add(1)
+
+	value, _, err := c.flights.do(ctx, key, func() (interface{}, time.Duration, error) {
+		// Загрузка, начавшаяся перед нами, могла успеть записать значение
+		if value, found := c.Peek(key); found {
+			return value, ttl, nil
+		}
+
+		start := time.Now()
+		value, _, err := c.callCompute(func() (interface{}, time.Duration, error) {
+			value, err := loader(ctx)
+			return value, ttl, err
+		})
+
+		c.fetches.loads.Add(1)
+		c.fetches.loadTime.Add(int64(time.Since(start)))
+
+		if err != nil {
+			return nil, 0, err
+		}
+
+		c.store(key, c.newItem(key, value, ttl))
+
+		return value, ttl, nil
+	})
+
+	return value, err
+}
+
+// GetOrSet возвращает значение по ключу, а при промахе вычисляет его fn и записывает на ttl.
+// Как и Fetch, одновременные промахи по одному ключу вызывают fn один раз
+func (c *InMemoryCache) GetOrSet(key string, fn func() (interface{}, error), ttl time.Duration) (interface{}, error) {
+	return c.Fetch(key, ttl, fn)
+}
+
+// addTo добавляет счетчики Fetch в stats
+func (s *fetchStats) addTo(stats *CacheStats) {
+	stats.FetchHits = s.hits.Load()
+	stats.FetchMisses = s.misses.Load()
+	stats.FetchLoads = s.loads.Load()
+	stats.FetchLoadTime = time.Duration(s.loadTime.Load())
+}
+
+// fetcher реализуют кеши с Fetch: *InMemoryCache и *ShardedCache
+type fetcher interface {
+	Fetch(key string, ttl time.Duration, loader func() (interface{}, error)) (interface{}, error)
+}
+
+// Fetch - типизированная обертка над методом Fetch кеша: возвращает значение типа V по ключу,
+// а при промахе загружает его loader и записывает на ttl. Если под ключом лежит значение
+// другого типа, оно удаляется и загружается заново, а не вызывает панику; ErrTypeMismatch
+// возвращается, только если и после перезагрузки под ключом оказалось чужое значение.
+// Для кешей без метода Fetch одновременные промахи не объединяются и паника loader не перехватывается
+func Fetch[V any](c Cache, key string, ttl time.Duration, loader func() (V, error)) (V, error) {
+	load := func() (interface{}, error) {
+		return loader()
+	}
+
+	value, err := fetchValue(c, key, ttl, load)
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+
+	if typed, ok := value.(V); ok {
+		return typed, nil
+	}
+
+	c.Delete(key)
+
+	if value, err = fetchValue(c, key, ttl, load); err != nil {
+		var zero V
+		return zero, err
+	}
+
+	typed, ok := value.(V)
+	if !ok {
+		var zero V
+		return zero, ErrTypeMismatch
+	}
+
+	return typed, nil
+}
+
+func fetchValue(c Cache, key string, ttl time.Duration, load func() (interface{}, error)) (interface{}, error) {
+	if f, ok := c.(fetcher); ok {
+		return f.Fetch(key, ttl, load)
+	}
+
+	if value, found := c.Get(key); found {
+		return value, nil
+	}
+
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+	c.Set(key, value, ttl)
+
+	return value, nil
+}
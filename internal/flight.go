@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// flightGroup объединяет одновременные загрузки одного ключа в одну
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+// flight - выполняющаяся загрузка, поля результата доступны после закрытия done
+type flight struct {
+	done     chan struct{}
+	value    interface{}
+	duration time.Duration
+	err      error
+}
+
+// do выполняет load, если загрузка ключа еще не идет, иначе ждет результата идущей.
+// Ожидание прерывается отменой ctx, сама загрузка при этом продолжается
+func (g *flightGroup) do(ctx context.Context, key string, load func() (interface{}, time.Duration, error)) (interface{}, time.Duration, error) {
+	g.mu.Lock()
+	if f, found := g.calls[key]; found {
+		g.mu.Unlock()
+
+		select {
+		case <-f.done:
+			return f.value, f.duration, f.err
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		}
+	}
+
+	if g.calls == nil {
+		g.calls = make(map[string]*flight)
+	}
+
+	f := &flight{done: make(chan struct{})}
+	g.calls[key] = f
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+
+		close(f.done)
+	}()
+
+	f.value, f.duration, f.err = load()
+
+	return f.value, f.duration, f.err
+}
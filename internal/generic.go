@@ -0,0 +1,62 @@
+package internal
+
+import "time"
+
+// GenericCache - типизированный вариант интерфейса Cache: ключи типа K и значения типа V
+// возвращаются без приведения типов. Реализуют TypedCache и представление TypedView
+type GenericCache[K comparable, V any] interface {
+	Get(key K) (V, bool)
+	Has(key K) bool
+	Count() int
+	Set(key K, value V, duration time.Duration)
+	Delete(key K) error
+	Flush()
+}
+
+var (
+	_ GenericCache[string, int] = (*TypedCache[string, int])(nil)
+	_ GenericCache[string, int] = typedView[int]{}
+)
+
+type typedView[V any] struct {
+	cache Cache
+}
+
+// TypedView возвращает типизированное представление кеша c со строковыми ключами.
+// В отличие от TypedCache, у представления работают все опции c. Значение другого типа,
+// записанное в c в обход представления, Get считает промахом
+func TypedView[V any](c Cache) GenericCache[string, V] {
+	return typedView[V]{cache: c}
+}
+
+func (v typedView[V]) Get(key string) (V, bool) {
+	value, found := v.cache.Get(key)
+	if !found {
+		var zero V
+		return zero, false
+	}
+
+	typed, ok := value.(V)
+	return typed, ok
+}
+
+func (v typedView[V]) Has(key string) bool {
+	_, found := v.Get(key)
+	return found
+}
+
+func (v typedView[V]) Count() int {
+	return v.cache.Count()
+}
+
+func (v typedView[V]) Set(key string, value V, duration time.Duration) {
+	v.cache.Set(key, value, duration)
+}
+
+func (v typedView[V]) Delete(key string) error {
+	return v.cache.Delete(key)
+}
+
+func (v typedView[V]) Flush() {
+	v.cache.Flush()
+}
@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"context"
+	"sync"
+)
+
+// Getter загружает значение ключа при промахе Group
+type Getter interface {
+	Get(ctx context.Context, key string) (interface{}, error)
+}
+
+// GetterFunc - функция, реализующая Getter
+type GetterFunc func(ctx context.Context, key string) (interface{}, error)
+
+func (f GetterFunc) Get(ctx context.Context, key string) (interface{}, error) {
+	return f(ctx, key)
+}
+
+// Group - именованное пространство кеша в духе groupcache, но в пределах процесса: промах Get
+// вызывает getter, результат кешируется бессрочно до вытеснения, одновременные загрузки
+// одного ключа выполняются один раз. Ошибки getter не кешируются
+type Group struct {
+	name   string
+	getter Getter
+	cache  *InMemoryCache
+}
+
+var (
+	groupsMu sync.RWMutex
+	groups   = make(map[string]*Group)
+)
+
+// NewGroup создает и регистрирует группу name, которая хранит не больше cacheBytes байт значений
+// (по оценке SizeOf) и вытесняет давно не читавшиеся. cacheBytes <= 0 - без ограничения.
+// opts настраивают кеш группы, кроме времени жизни - значения группы бессрочны.
+// Как и в groupcache, повторное имя вызывает панику
+func NewGroup(name string, cacheBytes int64, getter Getter, opts ...Option) *Group {
+	if getter == nil {
+		panic("cache: nil Getter for group " + name)
+	}
+
+	groupsMu.Lock()
+	defer groupsMu.Unlock()
+
+	if _, dup := groups[name]; dup {
+		panic("cache: duplicate group " + name)
+	}
+
+	opts = append([]Option{WithSizeEstimator(SizeOf)}, opts...)
+	if cacheBytes > 0 {
+		opts = append(opts, WithMaxBytes(cacheBytes), WithEvictionPolicy(PolicyLRU))
+	}
+
+	g := &Group{
+		name:   name,
+		getter: getter,
+		cache:  New(append(opts, WithDefaultExpiration(NoExpiration))...).(*InMemoryCache),
+	}
+	groups[name] = g
+
+	return g
+}
+
+// GetGroup возвращает группу, созданную NewGroup, или nil
+func GetGroup(name string) *Group {
+	groupsMu.RLock()
+	defer groupsMu.RUnlock()
+
+	return groups[name]
+}
+
+// Name возвращает имя группы
+func (g *Group) Name() string {
+	return g.name
+}
+
+// Get возвращает закешированное значение key, а при промахе загружает его getter
+func (g *Group) Get(ctx context.Context, key string) (interface{}, error) {
+	return g.cache.FetchCtx(ctx, key, NoExpiration, func(ctx context.Context) (interface{}, error) {
+		return g.getter.Get(ctx, key)
+	})
+}
+
+// Forget удаляет key из группы, следующий Get загрузит его заново
+func (g *Group) Forget(key string) {
+	g.cache.Delete(key)
+}
+
+// Stats возвращает статистику кеша группы: загрузки getter видны в FetchLoads
+func (g *Group) Stats() CacheStats {
+	return g.cache.Stats()
+}
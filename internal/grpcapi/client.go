@@ -0,0 +1,127 @@
+package grpcapi
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"InMemoryCache/internal/grpcapi/cachepb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Client обращается к сервису кеша через соединение gRPC
+type Client struct {
+	rpc cachepb.CacheClient
+}
+
+// NewClient создает клиента поверх соединения conn, например из grpc.NewClient
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{rpc: cachepb.NewCacheClient(conn)}
+}
+
+// Get возвращает значение по ключу и признак его наличия
+func (c *Client) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	resp, err := c.rpc.Get(ctx, &cachepb.GetRequest{Key: key})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return resp.GetValue(), resp.GetFound(), nil
+}
+
+// Set записывает значение на ttl, при ttl <= 0 - со временем жизни кеша по-умолчанию
+func (c *Client) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := c.rpc.Set(ctx, setRequest(key, value, ttl))
+	return err
+}
+
+// Delete удаляет ключ и возвращает false, если его не было
+func (c *Client) Delete(ctx context.Context, key string) (bool, error) {
+	resp, err := c.rpc.Delete(ctx, &cachepb.DeleteRequest{Key: key})
+	if err != nil {
+		return false, err
+	}
+
+	return resp.GetDeleted(), nil
+}
+
+// Flush удаляет все элементы кеша
+func (c *Client) Flush(ctx context.Context) error {
+	_, err := c.rpc.Flush(ctx, &cachepb.FlushRequest{})
+	return err
+}
+
+// GetMany возвращает найденные значения ключей keys одним потоком
+func (c *Client) GetMany(ctx context.Context, keys []string) (map[string][]byte, error) {
+	stream, err := c.rpc.GetMany(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Запросы отправляются параллельно с чтением ответов, чтобы не переполнить буферы потока
+	sendErr := make(chan error, 1)
+	go func() {
+		for _, key := range keys {
+			if err := stream.Send(&cachepb.GetRequest{Key: key}); err != nil {
+				sendErr <- err
+				return
+			}
+		}
+		sendErr <- stream.CloseSend()
+	}()
+
+	found := make(map[string][]byte, len(keys))
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.GetFound() {
+			found[resp.GetKey()] = resp.GetValue()
+		}
+	}
+
+	// Ошибка отправки без ошибки чтения означает лишь, что сервер закрыл поток раньше
+	if err := <-sendErr; err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+// SetMany записывает значения items на ttl одним потоком
+func (c *Client) SetMany(ctx context.Context, items map[string][]byte, ttl time.Duration) error {
+	stream, err := c.rpc.SetMany(ctx)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range items {
+		if err := stream.Send(setRequest(key, value, ttl)); err != nil {
+			// Причину ошибки сервера возвращает CloseAndRecv
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+	}
+
+	_, err = stream.CloseAndRecv()
+
+	return err
+}
+
+func setRequest(key string, value []byte, ttl time.Duration) *cachepb.SetRequest {
+	req := &cachepb.SetRequest{Key: key, Value: value}
+	if ttl > 0 {
+		req.Ttl = durationpb.New(ttl)
+	}
+
+	return req
+}
@@ -0,0 +1,179 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"InMemoryCache/internal"
+	"InMemoryCache/internal/grpcapi/cachepb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// dial поднимает сервис над кешем в памяти процесса и возвращает подключенного клиента
+func dial(t *testing.T, opts ...internal.Option) (*Client, *internal.InMemoryCache, *grpc.ClientConn) {
+	t.Helper()
+
+	c := internal.New(opts...).(*internal.InMemoryCache)
+	t.Cleanup(func() { c.Close() })
+
+	l := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	Register(s, c)
+	go s.Serve(l)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return l.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return NewClient(conn), c, conn
+}
+
+func TestGetSetDelete(t *testing.T) {
+	cl, _, _ := dial(t)
+	ctx := context.Background()
+
+	if _, found, err := cl.Get(ctx, "a"); err != nil || found {
+		t.Fatalf("Get of a missing key = %v, %v, want not found", found, err)
+	}
+
+	if err := cl.Set(ctx, "a", []byte("value"), time.Minute); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	value, found, err := cl.Get(ctx, "a")
+	if err != nil || !found || string(value) != "value" {
+		t.Fatalf("Get = %q, %v, %v, want value", value, found, err)
+	}
+
+	if deleted, err := cl.Delete(ctx, "a"); err != nil || !deleted {
+		t.Errorf("Delete = %v, %v, want true", deleted, err)
+	}
+	if deleted, err := cl.Delete(ctx, "a"); err != nil || deleted {
+		t.Errorf("second Delete = %v, %v, want false", deleted, err)
+	}
+	if _, found, _ := cl.Get(ctx, "a"); found {
+		t.Error("key is found after Delete")
+	}
+}
+
+func TestGetReturnsTTL(t *testing.T) {
+	_, c, conn := dial(t, internal.WithDefaultExpiration(internal.NoExpiration))
+	rpc := cachepb.NewCacheClient(conn)
+	ctx := context.Background()
+
+	if _, err := rpc.Set(ctx, &cachepb.SetRequest{Key: "a", Value: []byte("1"), Ttl: durationpb.New(time.Minute)}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	c.Set("forever", int64(7), internal.NoExpiration)
+
+	resp, err := rpc.Get(ctx, &cachepb.GetRequest{Key: "a"})
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if ttl := resp.GetTtl().AsDuration(); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("ttl = %v, want at most a minute", ttl)
+	}
+
+	// Значения, записанные не через сервис, отдаются строкой
+	resp, err = rpc.Get(ctx, &cachepb.GetRequest{Key: "forever"})
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if resp.Ttl != nil || string(resp.GetValue()) != "7" {
+		t.Errorf("Get = %q, ttl %v, want \"7\" without ttl", resp.GetValue(), resp.Ttl)
+	}
+}
+
+func TestManyAndFlush(t *testing.T) {
+	cl, c, _ := dial(t)
+	ctx := context.Background()
+
+	items := make(map[string][]byte)
+	keys := []string{"missing"}
+	for i := range 1000 {
+		key := strconv.Itoa(i)
+		items[key] = []byte(key)
+		keys = append(keys, key)
+	}
+
+	if err := cl.SetMany(ctx, items, 0); err != nil {
+		t.Fatalf("SetMany: %v", err)
+	}
+
+	found, err := cl.GetMany(ctx, keys)
+	if err != nil {
+		t.Fatalf("GetMany: %v", err)
+	}
+	if len(found) != len(items) || string(found["42"]) != "42" {
+		t.Errorf("GetMany found %d keys, want %d", len(found), len(items))
+	}
+
+	if err := cl.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if n := c.Len(); n != 0 {
+		t.Errorf("Len after Flush = %d, want 0", n)
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	cl, c, conn := dial(t,
+		internal.WithMaxKeyLength(3),
+		internal.WithMaxEntries(1),
+		internal.WithEvictionPolicy(internal.PolicyReject),
+	)
+	rpc := cachepb.NewCacheClient(conn)
+	ctx := context.Background()
+
+	if err := cl.Set(ctx, "a", nil, 0); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		call func() error
+		want codes.Code
+	}{
+		{"long key", func() error { return cl.Set(ctx, "abcd", nil, 0) }, codes.InvalidArgument},
+		{"cache full", func() error { return cl.Set(ctx, "b", nil, 0) }, codes.ResourceExhausted},
+		{"cache full in stream", func() error { return cl.SetMany(ctx, map[string][]byte{"c": nil}, 0) }, codes.ResourceExhausted},
+		{"negative ttl", func() error {
+			_, err := rpc.Set(ctx, &cachepb.SetRequest{Key: "a", Ttl: durationpb.New(-time.Second)})
+			return err
+		}, codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); status.Code(err) != tt.want {
+				t.Errorf("err = %v, want code %v", err, tt.want)
+			}
+		})
+	}
+
+	c.Close()
+
+	if err := cl.Set(ctx, "a", nil, 0); status.Code(err) != codes.Unavailable {
+		t.Errorf("Set after Close = %v, want code %v", err, codes.Unavailable)
+	}
+	if _, err := cl.Delete(ctx, "a"); status.Code(err) != codes.Unavailable {
+		t.Errorf("Delete after Close = %v, want code %v", err, codes.Unavailable)
+	}
+}
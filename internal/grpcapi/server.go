@@ -0,0 +1,161 @@
+// Package grpcapi отдает кеш по gRPC: сервис описан в cachepb/cache.proto,
+// Register подключает его к grpc.Server, а Client - удобная обертка над сгенерированным клиентом.
+// Значения передаются байтами, их сериализацию выбирает вызывающий
+package grpcapi
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative -I cachepb cachepb/cache.proto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"InMemoryCache/internal"
+	"InMemoryCache/internal/grpcapi/cachepb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Store - кеш, который обслуживает сервис: *internal.InMemoryCache или *internal.ShardedCache
+type Store interface {
+	internal.Cache
+
+	SetE(key string, value interface{}, duration time.Duration) error
+	Inspect(key string) (internal.CacheEntry, bool)
+}
+
+type service struct {
+	cachepb.UnimplementedCacheServer
+
+	store Store
+}
+
+// Register регистрирует сервис кеша store на сервере s
+func Register(s grpc.ServiceRegistrar, store Store) {
+	cachepb.RegisterCacheServer(s, &service{store: store})
+}
+
+func (s *service) Get(_ context.Context, req *cachepb.GetRequest) (*cachepb.GetResponse, error) {
+	return s.get(req.GetKey()), nil
+}
+
+func (s *service) Set(_ context.Context, req *cachepb.SetRequest) (*cachepb.SetResponse, error) {
+	if err := s.set(req); err != nil {
+		return nil, err
+	}
+
+	return &cachepb.SetResponse{}, nil
+}
+
+func (s *service) Delete(_ context.Context, req *cachepb.DeleteRequest) (*cachepb.DeleteResponse, error) {
+	err := s.store.Delete(req.GetKey())
+	switch {
+	case errors.Is(err, internal.ErrClosed):
+		return nil, status.Error(codes.Unavailable, err.Error())
+	case err != nil && !errors.Is(err, internal.ErrNotFound):
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &cachepb.DeleteResponse{Deleted: err == nil}, nil
+}
+
+func (s *service) Flush(context.Context, *cachepb.FlushRequest) (*cachepb.FlushResponse, error) {
+	s.store.Flush()
+	return &cachepb.FlushResponse{}, nil
+}
+
+func (s *service) GetMany(stream grpc.BidiStreamingServer[cachepb.GetRequest, cachepb.GetResponse]) error {
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := stream.Send(s.get(req.GetKey())); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *service) SetMany(stream grpc.ClientStreamingServer[cachepb.SetRequest, cachepb.SetManyResponse]) error {
+	var stored int64
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&cachepb.SetManyResponse{Stored: stored})
+		}
+		if err != nil {
+			return err
+		}
+
+		// Отклоненная запись прерывает поток, Stored считает только записанные
+		if err := s.set(req); err != nil {
+			return err
+		}
+		stored++
+	}
+}
+
+func (s *service) get(key string) *cachepb.GetResponse {
+	resp := &cachepb.GetResponse{Key: key}
+
+	value, found := s.store.Get(key)
+	if !found {
+		return resp
+	}
+
+	resp.Found, resp.Value = true, toBytes(value)
+	if e, ok := s.store.Inspect(key); ok && e.RemainingTTL >= 0 {
+		resp.Ttl = durationpb.New(e.RemainingTTL)
+	}
+
+	return resp
+}
+
+func (s *service) set(req *cachepb.SetRequest) error {
+	ttl := internal.DefaultExpiration
+	if req.Ttl != nil {
+		if err := req.Ttl.CheckValid(); err != nil || req.Ttl.AsDuration() <= 0 {
+			return status.Error(codes.InvalidArgument, "ttl must be positive")
+		}
+		ttl = req.Ttl.AsDuration()
+	}
+
+	err := s.store.SetE(req.GetKey(), req.GetValue(), ttl)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, internal.ErrClosed):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, internal.ErrKeyTooLong):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, internal.ErrCacheFull):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// toBytes возвращает значение байтами. Значения, записанные в кеш не через сервис,
+// передаются строкой, как их печатает fmt
+func toBytes(value interface{}) []byte {
+	switch v := value.(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	case int64:
+		return strconv.AppendInt(nil, v, 10)
+	default:
+		return fmt.Append(nil, v)
+	}
+}
@@ -0,0 +1,118 @@
+// Package hashring реализует консистентное хеширование с виртуальными узлами.
+// При добавлении или удалении узла меняет владельца лишь доля ключей порядка 1/n,
+// а не почти все ключи, как при взятии хеша по модулю
+package hashring
+
+import (
+	"sort"
+	"strconv"
+)
+
+// DefaultReplicas - количество виртуальных узлов на узел по-умолчанию
+const DefaultReplicas = 128
+
+// Ring - кольцо хешей. Не синхронизировано: изменения кольца нельзя выполнять
+// одновременно с другими вызовами
+type Ring struct {
+	replicas int
+	hashes   []uint32
+	owners   map[uint32]string
+	nodes    map[string]struct{}
+}
+
+// New создает пустое кольцо с replicas виртуальными узлами на узел, при replicas <= 0 - DefaultReplicas
+func New(replicas int) *Ring {
+	if replicas <= 0 {
+		replicas = DefaultReplicas
+	}
+
+	return &Ring{
+		replicas: replicas,
+		owners:   make(map[uint32]string),
+		nodes:    make(map[string]struct{}),
+	}
+}
+
+// Add добавляет узел, повторное добавление ничего не меняет
+func (r *Ring) Add(node string) {
+	if _, found := r.nodes[node]; found {
+		return
+	}
+	r.nodes[node] = struct{}{}
+
+	for i := 0; i < r.replicas; i++ {
+		h := Hash(node + "#" + strconv.Itoa(i))
+		// При редком совпадении хешей виртуальная точка остается за прежним узлом
+		if _, taken := r.owners[h]; taken {
+			continue
+		}
+
+		r.owners[h] = node
+		r.hashes = append(r.hashes, h)
+	}
+
+	sort.Slice(r.hashes, func(a, b int) bool { return r.hashes[a] < r.hashes[b] })
+}
+
+// Remove удаляет узел, его ключи переходят к соседним по кольцу узлам
+func (r *Ring) Remove(node string) {
+	if _, found := r.nodes[node]; !found {
+		return
+	}
+	delete(r.nodes, node)
+
+	hashes := r.hashes[:0]
+	for _, h := range r.hashes {
+		if r.owners[h] == node {
+			delete(r.owners, h)
+			continue
+		}
+
+		hashes = append(hashes, h)
+	}
+	r.hashes = hashes
+}
+
+// Get возвращает узел, владеющий ключом, ok = false для пустого кольца
+func (r *Ring) Get(key string) (node string, ok bool) {
+	if len(r.hashes) == 0 {
+		return "", false
+	}
+
+	h := Hash(key)
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0
+	}
+
+	return r.owners[r.hashes[i]], true
+}
+
+// Len возвращает количество узлов
+func (r *Ring) Len() int {
+	return len(r.nodes)
+}
+
+// Hash считает хеш FNV-1a строки с перемешиванием битов, чтобы близкие строки
+// (номера виртуальных узлов) расходились по кольцу
+func Hash(key string) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+
+	h := uint32(offset32)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= prime32
+	}
+
+	// Финализатор murmur3
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+
+	return h
+}
@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// KeyStat - ключ и количество обращений к нему
+type KeyStat struct {
+	Key  string `json:"key"`
+	Hits uint64 `json:"hits"`
+}
+
+// GetWithHits возвращает значение как Get и количество обращений к элементу с момента его записи,
+// включая текущее. Без WithHitCounting количество обращений всегда 0
+func (c *InMemoryCache) GetWithHits(key string) (value interface{}, hits uint64, ok bool) {
+	value, hits, _, ok = c.getTracked(key)
+	return value, hits, ok
+}
+
+// getTracked читает значение как Get и возвращает вместе с ним количество обращений
+// и время истечения с учетом продления скользящего элемента
+func (c *InMemoryCache) getTracked(key string) (value interface{}, hits uint64, expiration int64, ok bool) {
+	t := c.startOp("get")
+	defer t.done()
+
+	if c.definitelyAbsent(key) {
+		c.journal.record(OpGetMiss, key, 0)
+		c.countLookup(key, false)
+		return nil, 0, 0, false
+	}
+
+	c.rmu.RLock()
+	t.locked()
+
+	item, found := c.cache[key]
+	if !found || c.expired(item) {
+		c.journal.record(OpGetMiss, key, 0)
+		c.countLookup(key, false)
+		c.rmu.RUnlock()
+
+		// Просроченный элемент удаляем сразу, не дожидаясь GC. Блокировку на чтение
+		// нельзя повысить до записи, поэтому clearBatch захватывает ее заново и перепроверяет элемент
+		if found && !c.closed.Load() {
+			c.clearBatch([]string{key})
+		}
+
+		return nil, 0, 0, false
+	}
+
+	c.policy.accessed(key)
+	c.countLookup(key, true)
+	value, hits, expiration = c.itemValue(item), item.hit(c.now), item.expiration
+	c.rmu.RUnlock()
+
+	// Продлевать элемент можно только под блокировкой на запись
+	if item.idle > 0 {
+		expiration = c.slide(key, item)
+	}
+
+	if c.refreshLoader != nil {
+		c.refreshIfDue(key, item)
+	}
+
+	return value, hits, expiration, true
+}
+
+// TopKeys возвращает не более n живых ключей с наибольшим количеством обращений по убыванию,
+// ключи с равным количеством упорядочены лексикографически. Ключи без обращений не включаются,
+// без WithHitCounting возвращает nil
+func (c *InMemoryCache) TopKeys(n int) []KeyStat {
+	if n <= 0 || !c.hitCounting {
+		return nil
+	}
+
+	c.rmu.RLock()
+	stats := make([]KeyStat, 0, len(c.cache))
+	for k, i := range c.cache {
+		if i.Hits() > 0 && !c.expired(i) {
+			stats = append(stats, KeyStat{Key: k, Hits: i.Hits()})
+		}
+	}
+	c.rmu.RUnlock()
+
+	// Сортируем уже после снятия блокировки
+	return topKeyStats(stats, n)
+}
+
+// topKeyStats упорядочивает stats как TopKeys и оставляет первые n
+func topKeyStats(stats []KeyStat, n int) []KeyStat {
+	sort.Slice(stats, func(a, b int) bool {
+		if stats[a].Hits != stats[b].Hits {
+			return stats[a].Hits > stats[b].Hits
+		}
+
+		return stats[a].Key < stats[b].Key
+	})
+
+	return stats[:min(n, len(stats))]
+}
+
+// itemAccess - счетчик обращений к элементу и время последнего из них в UnixNano
+type itemAccess struct {
+	hits atomic.Uint64
+	last atomic.Int64
+}
+
+// hit учитывает обращение к элементу в момент now и возвращает количество обращений.
+// Счетчик общий для всех копий элемента, поэтому его можно менять под блокировкой на чтение
+func (i Item) hit(now func() time.Time) uint64 {
+	if i.access == nil {
+		return 0
+	}
+
+	i.access.last.Store(now().UnixNano())
+
+	return i.access.hits.Add(1)
+}
+
+// Hits возвращает количество обращений к элементу, при выключенном WithHitCounting - 0
+func (i Item) Hits() uint64 {
+	if i.access == nil {
+		return 0
+	}
+
+	return i.access.hits.Load()
+}
+
+// LastAccess возвращает время последнего обращения к элементу, при выключенном WithHitCounting
+// или без обращений - нулевое время
+func (i Item) LastAccess() time.Time {
+	if i.access == nil {
+		return time.Time{}
+	}
+
+	if last := i.access.last.Load(); last != 0 {
+		return time.Unix(0, last)
+	}
+
+	return time.Time{}
+}
+
+// countHits заводит элементу счетчик обращений, если он включен опцией WithHitCounting
+func (c *InMemoryCache) countHits(item *Item) {
+	if c.hitCounting {
+		item.access = new(itemAccess)
+	}
+}
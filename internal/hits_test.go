@@ -0,0 +1,41 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTopKeysWithoutHitCounting(t *testing.T) {
+	for name, c := range map[string]interface {
+		Cache
+		TopKeys(n int) []KeyStat
+	}{
+		"InMemoryCache": NewInMemoryCache(0, 0).(*InMemoryCache),
+		"ShardedCache":  NewShardedCache(4, 0, 0),
+	} {
+		c.Set("key", 1, NoExpiration)
+		c.Get("key")
+
+		if top := c.TopKeys(10); top != nil {
+			t.Errorf("%s: TopKeys = %v, want nil", name, top)
+		}
+		c.Close()
+	}
+}
+
+func TestTopKeysSkipsUnreadKeys(t *testing.T) {
+	c := NewShardedCache(4, 0, 0, WithHitCounting())
+	defer c.Close()
+
+	for _, key := range []string{"a", "b", "c", "unread"} {
+		c.Set(key, key, NoExpiration)
+	}
+	for _, key := range []string{"a", "b", "b", "c", "c"} {
+		c.Get(key)
+	}
+
+	want := []KeyStat{{Key: "b", Hits: 2}, {Key: "c", Hits: 2}, {Key: "a", Hits: 1}}
+	if top := c.TopKeys(10); !reflect.DeepEqual(top, want) {
+		t.Errorf("TopKeys = %v, want %v", top, want)
+	}
+}
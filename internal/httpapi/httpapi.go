@@ -0,0 +1,168 @@
+// Package httpapi отдает кеш по HTTP с телами в JSON, чтобы с отдельно запущенным кешем
+// могли работать сервисы на других языках и curl:
+//
+//	GET    /cache/{key}        - значение и оставшееся время жизни, 404 для отсутствующего ключа
+//	PUT    /cache/{key}?ttl=30s - запись значения из тела запроса: 503 для закрытого кеша,
+//	                             400 для слишком длинного ключа, 507 для заполненного кеша
+//	DELETE /cache/{key}        - удаление, 404 для отсутствующего ключа
+//	GET    /stats              - статистика кеша
+//
+// Параметр ttl задается длительностью Go (30s, 5m) или числом секунд, без него
+// используется время жизни кеша по-умолчанию
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"InMemoryCache/internal"
+)
+
+// maxBodySize ограничивает размер записываемого значения
+const maxBodySize = 10 << 20
+
+// Store - кеш, который обслуживает API: *internal.InMemoryCache или *internal.ShardedCache
+type Store interface {
+	internal.Cache
+
+	SetE(key string, value interface{}, duration time.Duration) error
+	Inspect(key string) (internal.CacheEntry, bool)
+	Stats() internal.CacheStats
+}
+
+// Entry - ответ GET /cache/{key}. TTL - оставшееся время жизни в секундах,
+// для бессрочного элемента не передается
+type Entry struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+	TTL   *float64    `json:"ttl,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type handler struct {
+	store Store
+}
+
+// New возвращает обработчик API для кеша store. Пути начинаются от корня,
+// для другого префикса обработчик можно обернуть в http.StripPrefix
+func New(store Store) http.Handler {
+	h := handler{store: store}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /cache/{key}", h.get)
+	mux.HandleFunc("PUT /cache/{key}", h.put)
+	mux.HandleFunc("DELETE /cache/{key}", h.delete)
+	mux.HandleFunc("GET /stats", h.stats)
+
+	return mux
+}
+
+func (h handler) get(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	value, found := h.store.Get(key)
+	if !found {
+		writeError(w, http.StatusNotFound, "key not found")
+		return
+	}
+
+	entry := Entry{Key: key, Value: value}
+	if e, ok := h.store.Inspect(key); ok && e.RemainingTTL >= 0 {
+		ttl := e.RemainingTTL.Seconds()
+		entry.TTL = &ttl
+	}
+
+	writeJSON(w, http.StatusOK, entry)
+}
+
+func (h handler) put(w http.ResponseWriter, r *http.Request) {
+	ttl, err := parseTTL(r.URL.Query().Get("ttl"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var value interface{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&value); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+
+	err = h.store.SetE(r.PathValue("key"), value, ttl)
+	switch {
+	case errors.Is(err, internal.ErrClosed):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	case errors.Is(err, internal.ErrKeyTooLong):
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, internal.ErrCacheFull):
+		writeError(w, http.StatusInsufficientStorage, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h handler) delete(w http.ResponseWriter, r *http.Request) {
+	err := h.store.Delete(r.PathValue("key"))
+	switch {
+	case errors.Is(err, internal.ErrClosed):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	case errors.Is(err, internal.ErrNotFound):
+		writeError(w, http.StatusNotFound, "key not found")
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h handler) stats(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.store.Stats())
+}
+
+// parseTTL разбирает параметр ttl: длительность Go или целое число секунд.
+// Число секунд, которое не помещается в time.Duration, отклоняется
+func parseTTL(s string) (time.Duration, error) {
+	if s == "" {
+		return internal.DefaultExpiration, nil
+	}
+
+	ttl, err := time.ParseDuration(s)
+	if seconds, convErr := strconv.ParseInt(s, 10, 64); convErr == nil {
+		if seconds > math.MaxInt64/int64(time.Second) {
+			return 0, errors.New("ttl is too large")
+		}
+		ttl, err = time.Duration(seconds)*time.Second, nil
+	}
+
+	if err != nil || ttl <= 0 {
+		return 0, errors.New("ttl must be a positive duration or number of seconds")
+	}
+
+	return ttl, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorResponse{Error: msg})
+}
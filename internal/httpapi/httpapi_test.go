@@ -0,0 +1,148 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"InMemoryCache/internal"
+)
+
+// do выполняет запрос к API кеша и возвращает ответ
+func do(t *testing.T, h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestRoundTrip(t *testing.T) {
+	cache := internal.NewInMemoryCache(0, 0).(*internal.InMemoryCache)
+	defer cache.Close()
+	h := New(cache)
+
+	if w := do(t, h, http.MethodPut, "/cache/user?ttl=60", `{"name":"ann"}`); w.Code != http.StatusNoContent {
+		t.Fatalf("PUT status = %d, body %s", w.Code, w.Body)
+	}
+
+	w := do(t, h, http.MethodGet, "/cache/user", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET status = %d, body %s", w.Code, w.Body)
+	}
+
+	var entry Entry
+	if err := json.NewDecoder(w.Body).Decode(&entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Key != "user" || entry.Value.(map[string]interface{})["name"] != "ann" {
+		t.Errorf("GET entry = %+v", entry)
+	}
+	if entry.TTL == nil || *entry.TTL <= 0 || *entry.TTL > 60 {
+		t.Errorf("GET ttl = %v, want (0, 60]", entry.TTL)
+	}
+
+	if w := do(t, h, http.MethodDelete, "/cache/user", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE status = %d, body %s", w.Code, w.Body)
+	}
+	if w := do(t, h, http.MethodGet, "/cache/user", ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET after DELETE status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w := do(t, h, http.MethodDelete, "/cache/user", ""); w.Code != http.StatusNotFound {
+		t.Errorf("second DELETE status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestGetWithoutExpirationOmitsTTL(t *testing.T) {
+	cache := internal.NewInMemoryCache(0, 0).(*internal.InMemoryCache)
+	defer cache.Close()
+	cache.Set("key", 1, internal.NoExpiration)
+
+	w := do(t, New(cache), http.MethodGet, "/cache/key", "")
+	if strings.Contains(w.Body.String(), "ttl") {
+		t.Errorf("GET body = %s, want no ttl", w.Body)
+	}
+}
+
+func TestParseTTL(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", internal.DefaultExpiration, false},
+		{"30s", 30 * time.Second, false},
+		{"5m", 5 * time.Minute, false},
+		{"90", 90 * time.Second, false},
+		{"9223372036", 9223372036 * time.Second, false},
+		{"9223372037", 0, true},
+		{"20000000000", 0, true},
+		{"0", 0, true},
+		{"-5", 0, true},
+		{"-1s", 0, true},
+		{"soon", 0, true},
+		{"10000000h", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTTL(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseTTL(%q) = %v, %v, want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestPutErrors(t *testing.T) {
+	full := internal.NewInMemoryCache(0, 0, internal.WithMaxEntries(1), internal.WithEvictionPolicy(internal.PolicyReject)).(*internal.InMemoryCache)
+	defer full.Close()
+	full.Set("taken", 1, internal.NoExpiration)
+
+	short := internal.NewInMemoryCache(0, 0, internal.WithMaxKeyLength(4)).(*internal.InMemoryCache)
+	defer short.Close()
+
+	closed := internal.NewInMemoryCache(0, 0).(*internal.InMemoryCache)
+	closed.Close()
+
+	tests := []struct {
+		name   string
+		store  Store
+		target string
+		body   string
+		want   int
+	}{
+		{"overflowing ttl", short, "/cache/k?ttl=20000000000", "1", http.StatusBadRequest},
+		{"invalid ttl", short, "/cache/k?ttl=soon", "1", http.StatusBadRequest},
+		{"invalid body", short, "/cache/k", "{", http.StatusBadRequest},
+		{"key too long", short, "/cache/long-key", "1", http.StatusBadRequest},
+		{"cache full", full, "/cache/new", "1", http.StatusInsufficientStorage},
+		{"closed", closed, "/cache/k", "1", http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		if w := do(t, New(tt.store), http.MethodPut, tt.target, tt.body); w.Code != tt.want {
+			t.Errorf("%s: PUT status = %d, want %d, body %s", tt.name, w.Code, tt.want, w.Body)
+		}
+	}
+
+	if w := do(t, New(closed), http.MethodDelete, "/cache/k", ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("closed: DELETE status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestStats(t *testing.T) {
+	cache := internal.NewInMemoryCache(0, 0).(*internal.InMemoryCache)
+	defer cache.Close()
+	cache.Set("key", 1, internal.NoExpiration)
+
+	w := do(t, New(cache), http.MethodGet, "/stats", "")
+
+	var stats internal.CacheStats
+	if err := json.NewDecoder(w.Body).Decode(&stats); err != nil || stats.Items != 1 {
+		t.Errorf("GET /stats = %+v, %v, want 1 item", stats, err)
+	}
+}
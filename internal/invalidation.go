@@ -0,0 +1,259 @@
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+const (
+	// invalidationBuffer - число ключей, ожидающих отправки в шину
+	invalidationBuffer = 1024
+	// invalidationBatch - наибольшее число ключей в одном сообщении
+	invalidationBatch = 256
+	// invalidationTimeout ограничивает отправку одного сообщения
+	invalidationTimeout = 5 * time.Second
+)
+
+// Invalidation - сообщение шины инвалидации: узел Node изменил ключи Keys
+// или, при Flush, очистил кеш целиком
+type Invalidation struct {
+	Node  string   `json:"node"`
+	Keys  []string `json:"keys,omitempty"`
+	Flush bool     `json:"flush,omitempty"`
+}
+
+// InvalidationBus доставляет сообщения инвалидации всем экземплярам кеша, включая отправителя.
+// Subscribe вызывает handler для каждого сообщения и возвращает функцию отписки
+type InvalidationBus interface {
+	Publish(ctx context.Context, msg Invalidation) error
+	Subscribe(handler func(msg Invalidation)) (unsubscribe func(), err error)
+}
+
+// LocalBus - шина инвалидации внутри одного процесса, например для нескольких кешей
+// над общими данными или для тестов. Publish вызывает обработчики синхронно
+type LocalBus struct {
+	mu       sync.RWMutex
+	handlers map[*func(Invalidation)]struct{}
+}
+
+// NewLocalBus создает шину инвалидации внутри процесса
+func NewLocalBus() *LocalBus {
+	return &LocalBus{handlers: make(map[*func(Invalidation)]struct{})}
+}
+
+func (b *LocalBus) Publish(_ context.Context, msg Invalidation) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for h := range b.handlers {
+		(*h)(msg)
+	}
+
+	return nil
+}
+
+func (b *LocalBus) Subscribe(handler func(msg Invalidation)) (func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	h := &handler
+	b.handlers[h] = struct{}{}
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.handlers, h)
+	}, nil
+}
+
+// pendingInvalidation - ключ или очистка, ожидающие отправки
+type pendingInvalidation struct {
+	key   string
+	flush bool
+}
+
+// invalidator отправляет изменения кеша в шину и применяет изменения других узлов.
+// У ShardedCache один invalidator на все сегменты
+type invalidator struct {
+	bus         InvalidationBus
+	node        string
+	logger      Logger
+	mu          sync.RWMutex
+	closed      bool
+	queue       chan pendingInvalidation
+	done        chan struct{}
+	unsubscribe func()
+}
+
+// newInvalidator подписывается на bus и передает apply сообщения других узлов.
+// Пустой node заменяется случайным, поэтому каждый кеш получает свой идентификатор
+func newInvalidator(bus InvalidationBus, node string, logger Logger, apply func(msg Invalidation)) *invalidator {
+	if node == "" {
+		node = newNodeID()
+	}
+
+	inv := &invalidator{
+		bus:    bus,
+		node:   node,
+		logger: logger,
+		queue:  make(chan pendingInvalidation, invalidationBuffer),
+		done:   make(chan struct{}),
+	}
+
+	unsubscribe, err := bus.Subscribe(func(msg Invalidation) {
+		if msg.Node != node {
+			apply(msg)
+		}
+	})
+	if err != nil {
+		// Без подписки кеш продолжает работать и сообщает свои изменения другим узлам
+		logger.Error("cache invalidation subscribe failed", "err", err)
+		unsubscribe = func() {}
+	}
+	inv.unsubscribe = unsubscribe
+
+	go inv.run()
+
+	return inv
+}
+
+// enqueue ставит изменение в очередь отправки, вызывается под блокировкой кеша на запись.
+// Запись в кеш не ждет шину: при заполненной очереди изменение отбрасывается
+func (inv *invalidator) enqueue(p pendingInvalidation) {
+	inv.mu.RLock()
+	defer inv.mu.RUnlock()
+
+	if inv.closed {
+		return
+	}
+
+	select {
+	case inv.queue <- p:
+	default:
+		inv.logger.Warn("cache invalidation dropped", "key", p.key, "flush", p.flush)
+	}
+}
+
+// run отправляет накопившиеся изменения пачками до invalidationBatch ключей
+func (inv *invalidator) run() {
+	defer close(inv.done)
+
+	for p := range inv.queue {
+		msg := Invalidation{Node: inv.node}
+		msg.add(p)
+
+	batch:
+		for len(msg.Keys) < invalidationBatch {
+			select {
+			case next, ok := <-inv.queue:
+				if !ok {
+					break batch
+				}
+				msg.add(next)
+			default:
+				break batch
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), invalidationTimeout)
+		if err := inv.bus.Publish(ctx, msg); err != nil {
+			inv.logger.Warn("cache invalidation publish failed", "keys", len(msg.Keys), "flush", msg.Flush, "err", err)
+		}
+		cancel()
+	}
+}
+
+// add добавляет изменение в сообщение, после очистки отдельные ключи не нужны
+func (m *Invalidation) add(p pendingInvalidation) {
+	if p.flush {
+		m.Flush, m.Keys = true, nil
+		return
+	}
+
+	if !m.Flush {
+		m.Keys = append(m.Keys, p.key)
+	}
+}
+
+// close отписывается от шины и дожидается отправки изменений из очереди
+func (inv *invalidator) close() {
+	inv.unsubscribe()
+
+	inv.mu.Lock()
+	inv.closed = true
+	close(inv.queue)
+	inv.mu.Unlock()
+
+	<-inv.done
+}
+
+// invalidate сообщает другим узлам об изменении ключа, вызывается под блокировкой на запись
+func (c *InMemoryCache) invalidate(key string) {
+	if c.invalidator != nil {
+		c.invalidator.enqueue(pendingInvalidation{key: key})
+	}
+}
+
+// invalidateAll сообщает другим узлам об очистке кеша, вызывается под блокировкой на запись
+func (c *InMemoryCache) invalidateAll() {
+	if c.invalidator != nil {
+		c.invalidator.enqueue(pendingInvalidation{flush: true})
+	}
+}
+
+// applyInvalidation удаляет ключи, измененные другим узлом, с причиной ReasonInvalidated.
+// Такие удаления в шину не отправляются
+func (c *InMemoryCache) applyInvalidation(msg Invalidation) {
+	if c.closed.Load() {
+		return
+	}
+
+	c.rmu.Lock()
+	defer c.unlock()
+
+	if msg.Flush {
+		for key := range c.cache {
+			c.evict(key, ReasonInvalidated)
+		}
+		return
+	}
+
+	for _, key := range msg.Keys {
+		c.evict(key, ReasonInvalidated)
+	}
+}
+
+// applyInvalidation передает изменения другого узла сегментам, которым принадлежат ключи
+func (c *ShardedCache) applyInvalidation(msg Invalidation) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if msg.Flush {
+		for _, s := range c.shards {
+			s.applyInvalidation(msg)
+		}
+		return
+	}
+
+	keys := make(map[*InMemoryCache][]string)
+	for _, key := range msg.Keys {
+		s := c.shard(key)
+		keys[s] = append(keys[s], key)
+	}
+
+	for s, shardKeys := range keys {
+		s.applyInvalidation(Invalidation{Node: msg.Node, Keys: shardKeys})
+	}
+}
+
+// newNodeID возвращает случайный идентификатор узла для WithInvalidationBus
+func newNodeID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
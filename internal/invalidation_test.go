@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+// eventually ждет, пока cond не станет истинным, сообщения шины отправляются в фоне
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestInvalidationBusOptionReusedAcrossCaches(t *testing.T) {
+	bus := NewLocalBus()
+	opt := WithInvalidationBus(bus, "")
+
+	a := New(opt).(*InMemoryCache)
+	defer a.Close()
+	b := New(opt).(*InMemoryCache)
+	defer b.Close()
+
+	if a.invalidator.node == b.invalidator.node {
+		t.Fatalf("caches share node ID %q", a.invalidator.node)
+	}
+
+	b.Set("key", 1, NoExpiration)
+	a.Set("key", 2, NoExpiration)
+	eventually(t, func() bool { return !b.Has("key") })
+}
+
+func TestShardedCacheSubscribesOnce(t *testing.T) {
+	bus := NewLocalBus()
+
+	c := NewShardedCache(4, 0, 0, WithInvalidationBus(bus, "sharded"))
+	c.AddShard()
+	if n := len(bus.handlers); n != 1 {
+		t.Fatalf("bus subscriptions = %d, want 1", n)
+	}
+
+	peer := New(WithInvalidationBus(bus, "peer")).(*InMemoryCache)
+	defer peer.Close()
+
+	keys := []string{"a", "b", "c", "d"}
+	for _, key := range keys {
+		c.Set(key, 1, NoExpiration)
+	}
+	for _, key := range keys {
+		peer.Set(key, 1, NoExpiration)
+	}
+	eventually(t, func() bool { return c.Count() == 0 })
+
+	for _, key := range keys {
+		c.Set(key, 2, NoExpiration)
+	}
+	eventually(t, func() bool { return peer.Count() == 0 })
+
+	peer.Set("a", 3, NoExpiration)
+	eventually(t, func() bool { return !c.Has("a") })
+	if !c.Has("b") {
+		t.Error("invalidation of a removed b")
+	}
+
+	peer.Flush()
+	eventually(t, func() bool { return c.Count() == 0 })
+
+	c.Close()
+	if n := len(bus.handlers); n != 1 {
+		t.Errorf("bus subscriptions after Close = %d, want 1", n)
+	}
+}
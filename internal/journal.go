@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"sync"
+	"time"
+)
+
+// OpKind - вид операции в журнале
+type OpKind int
+
+const (
+	// OpSet - запись элемента
+	OpSet OpKind = iota + 1
+	// OpGetMiss - чтение отсутствующего или просроченного элемента
+	OpGetMiss
+	// OpEvict - удаление элемента, причина в OpRecord.Reason
+	OpEvict
+	// OpFlush - очистка всего кеша, ключ пустой
+	OpFlush
+)
+
+func (k OpKind) String() string {
+	switch k {
+	case OpSet:
+		return "set"
+	case OpGetMiss:
+		return "get_miss"
+	case OpEvict:
+		return "evict"
+	case OpFlush:
+		return "flush"
+	default:
+		return "unknown"
+	}
+}
+
+// OpRecord - запись журнала операций
+type OpRecord struct {
+	Time   time.Time
+	Op     OpKind
+	Key    string
+	Reason EvictionReason
+}
+
+// journal - кольцевой буфер последних операций. Нулевой указатель означает выключенный журнал
+type journal struct {
+	mu      sync.Mutex
+	records []OpRecord
+	next    int
+	full    bool
+}
+
+func newJournal(size int) *journal {
+	return &journal{records: make([]OpRecord, size)}
+}
+
+// record добавляет операцию, вытесняя самую старую при заполненном буфере
+func (j *journal) record(op OpKind, key string, reason EvictionReason) {
+	if j == nil {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.records[j.next] = OpRecord{Time: time.Now(), Op: op, Key: key, Reason: reason}
+	j.next = (j.next + 1) % len(j.records)
+	if j.next == 0 {
+		j.full = true
+	}
+}
+
+// recent возвращает не более n последних операций в порядке выполнения
+func (j *journal) recent(n int) []OpRecord {
+	if j == nil || n <= 0 {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	count := j.next
+	if j.full {
+		count = len(j.records)
+	}
+	n = min(n, count)
+
+	ops := make([]OpRecord, n)
+	for i := range ops {
+		ops[i] = j.records[(j.next-n+i+len(j.records))%len(j.records)]
+	}
+
+	return ops
+}
+
+// RecentOps возвращает не более n последних операций (записи, промахи чтения, удаления, очистки)
+// в порядке выполнения. Журнал ведется только при WithJournal, иначе возвращается nil
+func (c *InMemoryCache) RecentOps(n int) []OpRecord {
+	return c.journal.recent(n)
+}
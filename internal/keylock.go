@@ -0,0 +1,61 @@
+package internal
+
+import "sync"
+
+// keyMutex - набор мьютексов по ключам: операции над разными ключами
+// выполняются параллельно, над одним - по очереди
+type keyMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock захватывает мьютекс ключа и возвращает функцию для его освобождения
+func (m *keyMutex) lock(key string) func() {
+	m.mu.Lock()
+	if m.locks == nil {
+		m.locks = make(map[string]*keyLock)
+	}
+
+	l, found := m.locks[key]
+	if !found {
+		l = &keyLock{}
+		m.locks[key] = l
+	}
+	l.refs++
+	m.mu.Unlock()
+
+	l.Lock()
+
+	return func() {
+		l.Unlock()
+
+		m.mu.Lock()
+		// Мьютекс больше никто не ждет - удаляем, чтобы набор не рос бесконечно
+		if l.refs--; l.refs == 0 {
+			delete(m.locks, key)
+		}
+		m.mu.Unlock()
+	}
+}
+
+// WithLock выполняет fn, удерживая блокировку ключа key: вызовы WithLock и LockKey для одного ключа
+// выполняются по очереди, для разных - параллельно, и остальной кеш не блокируется.
+// Блокировка отдельна от блокировок Update, GetOrCompute и WithKeyLocking, поэтому внутри fn
+// можно вызывать любые методы кеша для key. Повторный WithLock того же ключа внутри fn
+// приводит к взаимоблокировке. При панике fn блокировка освобождается
+func (c *InMemoryCache) WithLock(key string, fn func()) {
+	defer c.userLocks.lock(key)()
+
+	fn()
+}
+
+// LockKey захватывает блокировку ключа key как WithLock и возвращает функцию для ее освобождения,
+// которую нужно вызвать ровно один раз
+func (c *InMemoryCache) LockKey(key string) (unlock func()) {
+	return c.userLocks.lock(key)
+}
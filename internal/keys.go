@@ -0,0 +1,55 @@
+package internal
+
+import "sort"
+
+// Keys возвращает живые ключи в произвольном порядке
+func (c *InMemoryCache) Keys() []string {
+	return c.KeysWithPrefix("")
+}
+
+// Len возвращает количество живых элементов. В отличие от Count не учитывает
+// просроченные элементы, которые еще не удалил GC, и поэтому перебирает весь кеш - O(n)
+func (c *InMemoryCache) Len() int {
+	return c.liveCount()
+}
+
+// Range вызывает fn для каждого живого элемента, пока fn возвращает true.
+// Элементы копируются под блокировкой на чтение, а fn вызывается уже без нее,
+// поэтому может обращаться к кешу, но видит снимок на момент вызова Range
+func (c *InMemoryCache) Range(fn func(key string, value interface{}) bool) {
+	for _, e := range c.liveItems() {
+		if !fn(e.key, c.itemValue(e.item)) {
+			return
+		}
+	}
+}
+
+// keyedItem - элемент вместе с ключом
+type keyedItem struct {
+	key  string
+	item Item
+}
+
+// liveItems копирует живые элементы под блокировкой на чтение
+func (c *InMemoryCache) liveItems() []keyedItem {
+	c.rmu.RLock()
+	defer c.rmu.RUnlock()
+
+	items := make([]keyedItem, 0, len(c.cache))
+	for k, i := range c.cache {
+		if !c.expired(i) {
+			items = append(items, keyedItem{key: k, item: i})
+		}
+	}
+
+	return items
+}
+
+// SortedKeys возвращает живые ключи в лексикографическом порядке. В отличие от обхода map
+// порядок воспроизводим, что удобно для дампов и сравнения снимков в тестах
+func (c *InMemoryCache) SortedKeys() []string {
+	keys := c.KeysWithPrefix("")
+	sort.Strings(keys)
+
+	return keys
+}
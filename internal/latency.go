@@ -0,0 +1,44 @@
+package internal
+
+import "time"
+
+// opTimer измеряет время ожидания блокировки и полное время операции для WithLatencyObserver.
+// Нулевой указатель ничего не измеряет, поэтому без наблюдателя замеров нет
+type opTimer struct {
+	c        *InMemoryCache
+	op       string
+	start    time.Time
+	lockedAt time.Time
+}
+
+// startOp начинает замер операции op, без наблюдателя возвращает nil
+func (c *InMemoryCache) startOp(op string) *opTimer {
+	if c.latencyObserver == nil {
+		return nil
+	}
+
+	return &opTimer{c: c, op: op, start: time.Now()}
+}
+
+// locked отмечает момент захвата блокировки
+func (t *opTimer) locked() {
+	if t != nil {
+		t.lockedAt = time.Now()
+	}
+}
+
+// done завершает замер и передает его наблюдателю, вызывается после снятия блокировки
+func (t *opTimer) done() {
+	if t == nil {
+		return
+	}
+
+	total := time.Since(t.start)
+
+	var wait time.Duration
+	if !t.lockedAt.IsZero() {
+		wait = t.lockedAt.Sub(t.start)
+	}
+
+	t.c.callSafe(func() { t.c.latencyObserver(t.op, wait, total) })
+}
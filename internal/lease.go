@@ -0,0 +1,39 @@
+package internal
+
+import "time"
+
+// Lock захватывает ключ key как аренду на ttl: если живого элемента с таким ключом нет,
+// записывает его и возвращает acquired = true и срок аренды. Иначе возвращает оставшееся время аренды
+// текущего держателя (отрицательное, если аренда бессрочная), а если кеш заполнен - 0.
+// Освобождается аренда через Delete.
+// Проверка и запись выполняются атомарно
+func (c *InMemoryCache) Lock(key string, ttl time.Duration) (acquired bool, remaining time.Duration) {
+	// Недопустимый ключ (см. SetE) захватить нельзя
+	if c.checkKey(key) != nil {
+		return false, 0
+	}
+
+	item := c.newItem(key, true, ttl)
+
+	c.rmu.Lock()
+	defer c.unlock()
+
+	if holder, found := c.cache[key]; found && !c.expired(holder) {
+		return false, c.remaining(holder)
+	}
+
+	if c.storeItem(key, item) != nil {
+		return false, 0
+	}
+
+	return true, c.remaining(item)
+}
+
+// remaining возвращает оставшееся время жизни элемента, для бессрочного - отрицательное
+func (c *InMemoryCache) remaining(item Item) time.Duration {
+	if item.expiration == 0 {
+		return -1
+	}
+
+	return time.Duration(item.expiration - c.nowNano())
+}
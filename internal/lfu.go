@@ -0,0 +1,92 @@
+package internal
+
+import "container/list"
+
+// lfuPolicy вытесняет ключ с наименьшим количеством обращений, среди равных - самый давний.
+// Корзины ключей с одинаковой частотой упорядочены по возрастанию частоты, поэтому
+// каждая операция выполняется за O(1)
+type lfuPolicy struct {
+	buckets *list.List
+	entries map[string]*lfuEntry
+}
+
+type lfuBucket struct {
+	freq  int
+	items *list.List
+}
+
+type lfuEntry struct {
+	bucket *list.Element
+	elem   *list.Element
+}
+
+func newLFUPolicy() *lfuPolicy {
+	p := &lfuPolicy{}
+	p.reset()
+
+	return p
+}
+
+func (p *lfuPolicy) reset() {
+	p.buckets = list.New()
+	p.entries = make(map[string]*lfuEntry)
+}
+
+func (p *lfuPolicy) added(key string) {
+	if _, found := p.entries[key]; found {
+		p.accessed(key)
+		return
+	}
+
+	first := p.buckets.Front()
+	if first == nil || first.Value.(*lfuBucket).freq != 1 {
+		first = p.buckets.PushFront(&lfuBucket{freq: 1, items: list.New()})
+	}
+
+	p.entries[key] = &lfuEntry{bucket: first, elem: first.Value.(*lfuBucket).items.PushFront(key)}
+}
+
+func (p *lfuPolicy) accessed(key string) {
+	entry, found := p.entries[key]
+	if !found {
+		return
+	}
+
+	current := entry.bucket.Value.(*lfuBucket)
+	next := entry.bucket.Next()
+	if next == nil || next.Value.(*lfuBucket).freq != current.freq+1 {
+		next = p.buckets.InsertAfter(&lfuBucket{freq: current.freq + 1, items: list.New()}, entry.bucket)
+	}
+
+	p.unlink(entry)
+	entry.bucket, entry.elem = next, next.Value.(*lfuBucket).items.PushFront(key)
+}
+
+func (p *lfuPolicy) removed(key string) {
+	if entry, found := p.entries[key]; found {
+		p.unlink(entry)
+		delete(p.entries, key)
+	}
+}
+
+func (p *lfuPolicy) victim(_ string, skip func(key string) bool) (string, bool) {
+	for b := p.buckets.Front(); b != nil; b = b.Next() {
+		for e := b.Value.(*lfuBucket).items.Back(); e != nil; e = e.Prev() {
+			if key := e.Value.(string); !skip(key) {
+				return key, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// unlink убирает ключ из его корзины, опустевшая корзина удаляется
+func (p *lfuPolicy) unlink(entry *lfuEntry) {
+	bucket := entry.bucket.Value.(*lfuBucket)
+	bucket.items.Remove(entry.elem)
+
+	if bucket.items.Len() == 0 {
+		p.buckets.Remove(entry.bucket)
+	}
+}
@@ -0,0 +1,25 @@
+package internal
+
+import "sync"
+
+// rwLocker - блокировка хранилища, позволяет подменить sync.RWMutex обычным мьютексом
+type rwLocker interface {
+	Lock()
+	Unlock()
+	RLock()
+	RUnlock()
+}
+
+// exclusiveLock - мьютекс без разделения на чтение и запись,
+// чтение захватывает ту же блокировку, что и запись
+type exclusiveLock struct {
+	sync.Mutex
+}
+
+func (l *exclusiveLock) RLock() {
+	l.Lock()
+}
+
+func (l *exclusiveLock) RUnlock() {
+	l.Unlock()
+}
@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// benchmarkWrites нагружает кеш 15 записями на одно чтение, как агрегатор метрик
+func benchmarkWrites(b *testing.B, opts ...Option) {
+	c := NewInMemoryCache(time.Minute, 0, opts...)
+	defer c.Close()
+
+	keys := make([]string, 4096)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("metric:%d", i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := keys[i%len(keys)]
+			if i%16 == 0 {
+				c.Get(key)
+			} else {
+				c.Set(key, i, DefaultExpiration)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkWriteHeavyRWMutex(b *testing.B) {
+	benchmarkWrites(b)
+}
+
+func BenchmarkWriteHeavyLockStriping(b *testing.B) {
+	for _, stripes := range []int{4, 16, 64} {
+		b.Run(fmt.Sprintf("stripes=%d", stripes), func(b *testing.B) {
+			benchmarkWrites(b, WithLockStriping(stripes))
+		})
+	}
+}
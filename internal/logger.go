@@ -0,0 +1,16 @@
+package internal
+
+// Logger принимает события кеша в виде сообщения и пар ключ-значение, как log/slog.
+// *slog.Logger подходит без обертки
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// nopLogger - журнал по-умолчанию, отбрасывает все события
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Warn(string, ...interface{})  {}
+func (nopLogger) Error(string, ...interface{}) {}
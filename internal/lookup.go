@@ -0,0 +1,114 @@
+package internal
+
+import "time"
+
+// LookupState - результат поиска ключа в Lookup
+type LookupState int
+
+const (
+	// LookupAbsent - ключ не записывался или уже удален
+	LookupAbsent LookupState = iota
+	// LookupPresent - элемент есть и его время жизни не истекло
+	LookupPresent
+	// LookupExpired - элемент есть, но его время жизни истекло и GC его еще не удалил
+	LookupExpired
+)
+
+func (s LookupState) String() string {
+	switch s {
+	case LookupPresent:
+		return "present"
+	case LookupExpired:
+		return "expired"
+	default:
+		return "absent"
+	}
+}
+
+// Lookup в отличие от Get различает отсутствующий и просроченный ключ.
+// Для просроченного элемента возвращается его последнее значение
+func (c *InMemoryCache) Lookup(key string) (interface{}, LookupState) {
+	c.rmu.RLock()
+	defer c.rmu.RUnlock()
+
+	item, found := c.cache[key]
+	if !found {
+		return nil, LookupAbsent
+	}
+
+	if c.expired(item) {
+		return c.itemValue(item), LookupExpired
+	}
+
+	return c.itemValue(item), LookupPresent
+}
+
+// CacheEntry - состояние элемента, прочитанное Inspect за одно обращение к хранилищу
+type CacheEntry struct {
+	Value interface{}
+	// Age - время с момента записи элемента
+	Age time.Duration
+	// RemainingTTL - оставшееся время жизни, отрицательное для бессрочного и просроченного элемента
+	RemainingTTL time.Duration
+	Expired      bool
+	// Source - метка из SetWithSource, пустая без WithSourceTracking
+	Source string
+}
+
+// ItemInfo - метаданные элемента, возвращаемые GetItem
+type ItemInfo struct {
+	CreatedAt time.Time
+	// LastAccessed - время последнего Get, нулевое без обращений и без WithHitCounting
+	LastAccessed time.Time
+	// Hits - количество обращений, без WithHitCounting - 0
+	Hits uint64
+	// RemainingTTL - оставшееся время жизни, отрицательное для бессрочного элемента
+	RemainingTTL time.Duration
+}
+
+// GetItem возвращает метаданные живого элемента: время записи, последнего обращения,
+// количество обращений и оставшееся время жизни. Сам вызов обращением не считается
+func (c *InMemoryCache) GetItem(key string) (ItemInfo, bool) {
+	c.rmu.RLock()
+	defer c.rmu.RUnlock()
+
+	item, found := c.cache[key]
+	if !found || c.expired(item) {
+		return ItemInfo{}, false
+	}
+
+	return ItemInfo{
+		CreatedAt:    item.createdAt,
+		LastAccessed: item.LastAccess(),
+		Hits:         item.Hits(),
+		RemainingTTL: c.remaining(item),
+	}, true
+}
+
+// Inspect возвращает значение элемента вместе с его возрастом и оставшимся временем жизни,
+// например для заголовков Cache-Control и Age. Просроченный, но еще не удаленный элемент
+// возвращается с Expired = true, ok = false только для отсутствующего ключа
+func (c *InMemoryCache) Inspect(key string) (entry CacheEntry, ok bool) {
+	c.rmu.RLock()
+	defer c.rmu.RUnlock()
+
+	item, found := c.cache[key]
+	if !found {
+		return CacheEntry{}, false
+	}
+
+	entry = CacheEntry{
+		Value:        c.itemValue(item),
+		Age:          c.now().Sub(item.createdAt),
+		RemainingTTL: c.remaining(item),
+		Expired:      c.expired(item),
+		Source:       c.sources[key],
+	}
+
+	if entry.Expired && entry.RemainingTTL > 0 {
+		// Элемент просрочен предикатом WithExpirationPredicate раньше своего времени истечения
+		entry.RemainingTTL = 0
+	}
+
+	return entry, true
+}
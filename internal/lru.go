@@ -0,0 +1,65 @@
+package internal
+
+import "container/list"
+
+// lruPolicy вытесняет ключ, к которому дольше всего не обращались. Список упорядочен
+// от последнего обращения к самому давнему
+type lruPolicy struct {
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+func newLRUPolicy() *lruPolicy {
+	p := &lruPolicy{}
+	p.reset()
+
+	return p
+}
+
+func (p *lruPolicy) reset() {
+	p.order = list.New()
+	p.entries = make(map[string]*list.Element)
+}
+
+func (p *lruPolicy) added(key string) {
+	if elem, found := p.entries[key]; found {
+		p.order.MoveToFront(elem)
+		return
+	}
+
+	p.entries[key] = p.order.PushFront(key)
+}
+
+func (p *lruPolicy) accessed(key string) {
+	if elem, found := p.entries[key]; found {
+		p.order.MoveToFront(elem)
+	}
+}
+
+func (p *lruPolicy) removed(key string) {
+	if elem, found := p.entries[key]; found {
+		p.order.Remove(elem)
+		delete(p.entries, key)
+	}
+}
+
+func (p *lruPolicy) victim(_ string, skip func(key string) bool) (string, bool) {
+	for e := p.order.Back(); e != nil; e = e.Prev() {
+		if key := e.Value.(string); !skip(key) {
+			return key, true
+		}
+	}
+
+	return "", false
+}
+
+// fifoPolicy вытесняет ключ, записанный раньше остальных, обращения порядок не меняют
+type fifoPolicy struct {
+	*lruPolicy
+}
+
+func newFIFOPolicy() fifoPolicy {
+	return fifoPolicy{lruPolicy: newLRUPolicy()}
+}
+
+func (p fifoPolicy) accessed(string) {}
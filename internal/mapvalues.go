@@ -0,0 +1,55 @@
+package internal
+
+// MapValues применяет fn к каждому живому элементу и заменяет его значение результатом fn,
+// если она вернула true. Время записи и время истечения элементов сохраняются.
+// Это операция обслуживания, например для перехода кешированных значений на новый формат:
+// весь проход выполняется под блокировкой на запись, и остальные операции ждут его окончания.
+// Значение, которое не помещается в WithMaxBytes, не заменяется, как и значение, которое не удалось
+// записать в журнал. fn не должна обращаться к кешу, при ее панике значение элемента не меняется
+func (c *InMemoryCache) MapValues(fn func(key string, value interface{}) (interface{}, bool)) {
+	c.rmu.Lock()
+	defer c.unlock()
+
+	for key, old := range c.cache {
+		if c.expired(old) {
+			continue
+		}
+
+		value, replace := c.mapValue(fn, key, old)
+		if !replace {
+			continue
+		}
+
+		item := c.newItem(key, value, DefaultExpiration)
+		item.createdAt, item.expiration = old.createdAt, old.expiration
+		item.access, item.pinned = old.access, old.pinned
+
+		// Новое значение может быть больше прежнего: место под него освобождается как при записи
+		if err := c.admitBytes(key, item); err != nil {
+			c.logger.Warn("cache map value rejected", "key", key, "err", err)
+			continue
+		}
+
+		if err := c.logSet(key, item); err != nil {
+			c.logger.Warn("cache map value rejected", "key", key, "err", err)
+			continue
+		}
+
+		c.untrackSize(key, old)
+		c.cache[key] = item
+		c.trackSize(key, item)
+		c.maybeCompactWAL()
+		c.invalidate(key)
+		if c.events.active() {
+			c.events.publish(Event{Type: EventSet, Key: key, Value: value})
+		}
+		c.journal.record(OpSet, key, 0)
+	}
+}
+
+// mapValue вызывает fn из MapValues, при панике значение не заменяется
+func (c *InMemoryCache) mapValue(fn func(key string, value interface{}) (interface{}, bool), key string, item Item) (value interface{}, replace bool) {
+	defer c.recoverPanic()
+
+	return fn(key, c.itemValue(item))
+}
@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+// bytesCache создает кеш с ограничением maxBytes, размер строки - ее длина
+func bytesCache(maxBytes int64, opts ...Option) *InMemoryCache {
+	opts = append(opts,
+		WithMaxBytes(maxBytes),
+		WithSizeEstimator(func(v interface{}) int64 { return int64(len(v.(string))) }),
+	)
+
+	return NewInMemoryCache(0, 0, opts...).(*InMemoryCache)
+}
+
+func TestMapValuesEvictsToFitMaxBytes(t *testing.T) {
+	c := bytesCache(20)
+	defer c.Close()
+
+	c.Set("a", "1234", NoExpiration)
+	c.Set("b", "1234", NoExpiration)
+
+	// Новое значение a помещается, только если вытеснить b
+	c.MapValues(func(key string, value interface{}) (interface{}, bool) {
+		return strings.Repeat("x", 15), key == "a"
+	})
+
+	if value, _ := c.Get("a"); value != strings.Repeat("x", 15) {
+		t.Errorf("a = %v, want the mapped value", value)
+	}
+	if c.Has("b") {
+		t.Error("b is not evicted to make room for the mapped value")
+	}
+	if s := c.Stats(); s.KeyBytes+s.ValueBytes > 20 {
+		t.Errorf("cache holds %d bytes, limit is 20", s.KeyBytes+s.ValueBytes)
+	}
+}
+
+func TestMapValuesSkipsValuesOverMaxBytes(t *testing.T) {
+	for _, policy := range []EvictionPolicy{PolicyLRU, PolicyReject} {
+		t.Run(policy.String(), func(t *testing.T) {
+			c := bytesCache(20, WithEvictionPolicy(policy))
+			defer c.Close()
+
+			c.Set("a", "1234", NoExpiration)
+			c.Set("b", "1234", NoExpiration)
+
+			c.MapValues(func(key string, value interface{}) (interface{}, bool) {
+				if key == "a" {
+					return strings.Repeat("x", 30), true
+				}
+				if policy == PolicyReject {
+					return strings.Repeat("y", 15), true
+				}
+				return value.(string) + "5", true
+			})
+
+			if value, _ := c.Peek("a"); value != "1234" {
+				t.Errorf("a = %v, want the old value", value)
+			}
+			if s := c.Stats(); s.KeyBytes+s.ValueBytes > 20 {
+				t.Errorf("cache holds %d bytes, limit is 20", s.KeyBytes+s.ValueBytes)
+			}
+		})
+	}
+}
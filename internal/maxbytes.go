@@ -0,0 +1,53 @@
+package internal
+
+import "time"
+
+// SetWithSize записывает значение как SetE, но с заданным размером size вместо оценки
+// WithSizeEstimator, например когда размер сериализованного значения уже известен.
+// Размер учитывается в Stats и ограничении WithMaxBytes, без оценки размера он не используется
+func (c *InMemoryCache) SetWithSize(key string, value interface{}, duration time.Duration, size int64) error {
+	if err := c.checkKey(key); err != nil {
+		return err
+	}
+
+	if c.keyLocking {
+		defer c.keyLocks.lock(key)()
+	}
+
+	item := c.newItem(key, value, duration)
+	item.size = size
+
+	return c.store(key, item)
+}
+
+// admitBytes освобождает место под элемент item по ключу key в пределах WithMaxBytes,
+// вытесняя элементы по политике вытеснения. Возвращает ErrCacheFull, если элемент больше
+// всего ограничения или место освободить нельзя. Вызывается под блокировкой на запись
+func (c *InMemoryCache) admitBytes(key string, item Item) error {
+	if c.maxBytes <= 0 {
+		return nil
+	}
+
+	need := int64(len(key)) + item.size
+	if need > c.maxBytes {
+		return ErrCacheFull
+	}
+
+	if old, found := c.cache[key]; found {
+		need -= int64(len(key)) + old.size
+	}
+
+	for c.keyBytes+c.valueBytes+need > c.maxBytes {
+		victim, found := c.policy.victim(key, func(k string) bool {
+			return k == key || c.cache[k].pinned
+		})
+		if !found {
+			return ErrCacheFull
+		}
+
+		c.evict(victim, ReasonCapacity)
+		c.evictions++
+	}
+
+	return nil
+}
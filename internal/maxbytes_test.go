@@ -0,0 +1,27 @@
+package internal
+
+import "testing"
+
+func TestWithMaxBytesZeroKeepsSizeTrackingOff(t *testing.T) {
+	c := NewInMemoryCache(0, 0, WithMaxBytes(0)).(*InMemoryCache)
+	defer c.Close()
+
+	if c.sizeEstimator != nil {
+		t.Fatal("WithMaxBytes(0) enabled size estimation")
+	}
+
+	c.Set("key", []byte("value"), NoExpiration)
+	if stats := c.Stats(); stats.KeyBytes != 0 || stats.ValueBytes != 0 {
+		t.Errorf("KeyBytes, ValueBytes = %d, %d, want 0, 0", stats.KeyBytes, stats.ValueBytes)
+	}
+}
+
+func TestWithMaxBytesDefaultsToSizeOf(t *testing.T) {
+	c := NewInMemoryCache(0, 0, WithMaxBytes(1<<20)).(*InMemoryCache)
+	defer c.Close()
+
+	c.Set("key", []byte("value"), NoExpiration)
+	if stats := c.Stats(); stats.ValueBytes != SizeOf([]byte("value")) {
+		t.Errorf("ValueBytes = %d, want %d", stats.ValueBytes, SizeOf([]byte("value")))
+	}
+}
@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"fmt"
+	"time"
+)
+
+// computer реализуют кеши с GetOrCompute: *InMemoryCache и *ShardedCache
+type computer interface {
+	GetOrCompute(key string, compute func() (interface{}, time.Duration, error)) (interface{}, error)
+}
+
+// Memoize возвращает версию fn, запоминающую результаты в кеше c на ttl. Ключом служит
+// fmt.Sprint аргумента, поэтому в одном кеше не стоит хранить результаты функций
+// с совпадающими представлениями аргументов. Если c поддерживает GetOrCompute, конкурентные
+// вызовы с одним аргументом вычисляют fn один раз. Ошибки fn не запоминаются
+func Memoize[K comparable, V any](c Cache, ttl time.Duration, fn func(K) (V, error)) func(K) (V, error) {
+	return func(arg K) (V, error) {
+		key := fmt.Sprint(arg)
+		compute := func() (interface{}, time.Duration, error) {
+			value, err := fn(arg)
+			return value, ttl, err
+		}
+
+		var (
+			value interface{}
+			err   error
+		)
+
+		if cc, ok := c.(computer); ok {
+			value, err = cc.GetOrCompute(key, compute)
+		} else if v, found := c.Get(key); found {
+			value = v
+		} else {
+			var duration time.Duration
+			if value, duration, err = compute(); err == nil {
+				c.Set(key, value, duration)
+			}
+		}
+
+		if err != nil {
+			var zero V
+			return zero, err
+		}
+
+		// Под тем же ключом мог оказаться посторонний элемент
+		typed, ok := value.(V)
+		if !ok {
+			var zero V
+			return zero, ErrTypeMismatch
+		}
+
+		return typed, nil
+	}
+}
@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"runtime"
+	"time"
+)
+
+// memoryCheckInterval - период проверки размера кучи при WithMemoryWatermark по-умолчанию
+const memoryCheckInterval = time.Second
+
+// memoryEvictBatch - сколько элементов вытесняется под одной блокировкой при нехватке памяти
+const memoryEvictBatch = 256
+
+// watchMemory периодически проверяет размер кучи процесса и при превышении порога
+// вытесняет долю memoryEvictFraction элементов каждого из кешей, возвращаемых caches, пока не закрыт done
+func watchMemory(done <-chan struct{}, o options, caches func() []*InMemoryCache) {
+	interval := o.memoryCheckInterval
+	if interval == 0 {
+		interval = memoryCheckInterval
+	}
+
+	ticker := o.timeSource().NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C():
+		}
+
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+
+		if ms.HeapAlloc <= o.memoryWatermark {
+			continue
+		}
+
+		for _, c := range caches() {
+			c.evictLeastRecent(o.memoryEvictFraction)
+		}
+	}
+}
+
+// evictLeastRecent удаляет долю fraction элементов, к которым дольше всего не обращались,
+// но не меньше одного, защищенные Pin элементы пропускаются. Элементы удаляются пачками
+// по memoryEvictBatch, между пачками блокировка отпускается
+func (c *InMemoryCache) evictLeastRecent(fraction float64) {
+	c.rmu.RLock()
+	n := max(int(float64(len(c.cache))*fraction), 1)
+	c.rmu.RUnlock()
+
+	for n > 0 {
+		batch := min(n, memoryEvictBatch)
+		if c.evictRecentBatch(batch) < batch {
+			return
+		}
+		n -= batch
+	}
+}
+
+// evictRecentBatch удаляет до n давних элементов и возвращает число удаленных
+func (c *InMemoryCache) evictRecentBatch(n int) int {
+	c.rmu.Lock()
+	defer c.unlock()
+
+	skip := func(k string) bool { return c.cache[k].pinned }
+
+	evicted := 0
+	for evicted < n {
+		key, found := c.policy.leastRecent(skip)
+		if !found {
+			break
+		}
+		if !c.evict(key, ReasonMemoryPressure) {
+			break
+		}
+		evicted++
+	}
+
+	return evicted
+}
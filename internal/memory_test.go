@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+// memoryCache создает кеш с порогом памяти, который не срабатывает сам, вытеснение вызывается тестом
+func memoryCache(opts ...Option) *InMemoryCache {
+	opts = append(opts, WithMemoryWatermark(math.MaxUint64, 0.5))
+
+	return NewInMemoryCache(0, 0, opts...).(*InMemoryCache)
+}
+
+func TestEvictLeastRecentFollowsReads(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "without policy"},
+		{name: "lru", opts: []Option{WithMaxEntries(10)}},
+		{name: "fifo", opts: []Option{WithMaxEntries(10), WithEvictionPolicy(PolicyFIFO)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := memoryCache(tt.opts...)
+			defer c.Close()
+
+			c.Set("a", 1, NoExpiration)
+			c.Set("b", 2, NoExpiration)
+			c.Get("a")
+
+			c.evictLeastRecent(0.5)
+
+			if !c.Has("a") || c.Has("b") {
+				t.Errorf("keys after eviction = %v, want [a]", c.Keys())
+			}
+		})
+	}
+}
+
+func TestEvictLeastRecentSkipsPinnedAcrossBatches(t *testing.T) {
+	c := memoryCache()
+	defer c.Close()
+
+	var evicted int
+	c.OnEvicted(func(_ string, _ interface{}, reason EvictionReason) {
+		if reason == ReasonMemoryPressure {
+			evicted++
+		}
+	})
+
+	total := memoryEvictBatch*2 + 10
+	for i := 0; i < total; i++ {
+		c.Set(strconv.Itoa(i), i, NoExpiration)
+	}
+	c.Pin("0")
+
+	c.evictLeastRecent(1)
+
+	if evicted != total-1 {
+		t.Errorf("evicted = %d, want %d", evicted, total-1)
+	}
+	if keys := c.Keys(); len(keys) != 1 || keys[0] != "0" {
+		t.Errorf("keys after eviction = %v, want [0]", keys)
+	}
+}
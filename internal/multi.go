@@ -0,0 +1,156 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// GetMulti возвращает живые значения ключей keys и список ключей, которых в кеше нет,
+// под одной блокировкой на чтение. Каждый ключ учитывается как обращение Get
+func (c *InMemoryCache) GetMulti(keys []string) (map[string]interface{}, []string) {
+	found, _, missing := c.getMulti(keys, false)
+	return found, missing
+}
+
+// ExpiringValue - значение вместе с временем истечения, нулевым для бессрочного элемента
+type ExpiringValue struct {
+	Value      interface{}
+	Expiration time.Time
+}
+
+// GetMultiWithExpiration возвращает значения как GetMulti вместе с временем их истечения,
+// см. GetWithExpiration
+func (c *InMemoryCache) GetMultiWithExpiration(keys []string) (map[string]ExpiringValue, []string) {
+	found, expirations, missing := c.getMulti(keys, true)
+
+	values := make(map[string]ExpiringValue, len(found))
+	for k, v := range found {
+		values[k] = ExpiringValue{Value: v, Expiration: unixTime(expirations[k])}
+	}
+
+	return values, missing
+}
+
+// getMulti читает значения keys, expirations заполняется только при withExpiration
+func (c *InMemoryCache) getMulti(keys []string, withExpiration bool) (found map[string]interface{}, expirations map[string]int64, missing []string) {
+	found = make(map[string]interface{}, len(keys))
+	if withExpiration {
+		expirations = make(map[string]int64, len(keys))
+	}
+	var sliding []string
+	var slid []Item
+
+	c.rmu.RLock()
+	for _, k := range keys {
+		item, ok := c.cache[k]
+		if !ok || c.expired(item) {
+			c.journal.record(OpGetMiss, k, 0)
+			c.countLookup(k, false)
+			missing = append(missing, k)
+			continue
+		}
+
+		c.policy.accessed(k)
+		c.countLookup(k, true)
+		item.hit(c.now)
+		found[k] = c.itemValue(item)
+		if withExpiration {
+			expirations[k] = item.expiration
+		}
+
+		if item.idle > 0 {
+			sliding = append(sliding, k)
+			slid = append(slid, item)
+		}
+	}
+	c.rmu.RUnlock()
+
+	// Скользящие элементы продлеваем уже под блокировкой на запись
+	for i, k := range sliding {
+		expiration := c.slide(k, slid[i])
+		if withExpiration {
+			expirations[k] = expiration
+		}
+	}
+
+	return found, expirations, missing
+}
+
+// SetMulti записывает значения items с продолжительностью duration под одной блокировкой на запись.
+// Отклоненные ключи не мешают записи остальных, их ошибки объединяются через errors.Join
+func (c *InMemoryCache) SetMulti(items map[string]interface{}, duration time.Duration) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+
+	keys := make([]string, 0, len(items))
+	for k := range items {
+		keys = append(keys, k)
+	}
+
+	// Мьютексы ключей захватываем в одном порядке, чтобы два SetMulti не ждали друг друга
+	sort.Strings(keys)
+
+	var errs []error
+	prepared := make([]keyedItem, 0, len(keys))
+
+	for _, k := range keys {
+		if err := c.checkKey(k); err != nil {
+			errs = append(errs, fmt.Errorf("key '%s': %w", k, err))
+			continue
+		}
+
+		if c.keyLocking {
+			defer c.keyLocks.lock(k)()
+		}
+
+		prepared = append(prepared, keyedItem{key: k, item: c.newItem(k, items[k], duration)})
+	}
+
+	if c.async != nil {
+		for _, e := range prepared {
+			if !c.async.enqueue(e.key, e.item) {
+				return ErrClosed
+			}
+		}
+
+		return errors.Join(errs...)
+	}
+
+	c.rmu.Lock()
+	defer c.unlock()
+
+	for _, e := range prepared {
+		if c.skipEqual(e.key, e.item) {
+			continue
+		}
+
+		if err := c.storeItem(e.key, e.item); err != nil {
+			errs = append(errs, fmt.Errorf("key '%s': %w", e.key, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// DeleteMulti удаляет ключи keys под одной блокировкой на запись и возвращает количество удаленных.
+// После Close ничего не удаляет
+func (c *InMemoryCache) DeleteMulti(keys []string) int {
+	if c.closed.Load() {
+		return 0
+	}
+
+	c.rmu.Lock()
+	defer c.unlock()
+
+	deleted := 0
+	for _, k := range keys {
+		if c.evict(k, ReasonDeleted) {
+			deleted++
+		}
+	}
+
+	return deleted
+}
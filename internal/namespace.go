@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"strings"
+	"time"
+)
+
+// prefixStore - кеш с операциями над ключами по префиксу: *InMemoryCache и *ShardedCache
+type prefixStore interface {
+	Cache
+	KeysWithPrefix(prefix string) []string
+	DeleteByPrefix(prefix string) int
+}
+
+// NamespacedCache - представление кеша, в котором ко всем ключам добавляется префикс,
+// например идентификатор арендатора. Flush и FlushNamespace очищают только ключи префикса.
+// Префиксы разных пространств не должны быть префиксами друг друга, иначе очистка
+// одного пространства захватит ключи другого. При WithPrefixIndex очистка не перебирает весь кеш
+type NamespacedCache struct {
+	store  prefixStore
+	prefix string
+}
+
+// Namespace возвращает представление кеша с префиксом prefix
+func (c *InMemoryCache) Namespace(prefix string) *NamespacedCache {
+	return &NamespacedCache{store: c, prefix: prefix}
+}
+
+// Namespace - см. InMemoryCache.Namespace
+func (c *ShardedCache) Namespace(prefix string) *NamespacedCache {
+	return &NamespacedCache{store: c, prefix: prefix}
+}
+
+// Namespace возвращает вложенное пространство с префиксом, дописанным к текущему
+func (n *NamespacedCache) Namespace(prefix string) *NamespacedCache {
+	return &NamespacedCache{store: n.store, prefix: n.prefix + prefix}
+}
+
+func (n *NamespacedCache) Get(key string) (interface{}, bool) {
+	return n.store.Get(n.prefix + key)
+}
+
+func (n *NamespacedCache) Has(key string) bool {
+	return n.store.Has(n.prefix + key)
+}
+
+// Count возвращает количество живых элементов пространства
+func (n *NamespacedCache) Count() int {
+	return len(n.store.KeysWithPrefix(n.prefix))
+}
+
+func (n *NamespacedCache) Set(key string, value interface{}, duration time.Duration) {
+	n.store.Set(n.prefix+key, value, duration)
+}
+
+func (n *NamespacedCache) Delete(key string) error {
+	return n.store.Delete(n.prefix + key)
+}
+
+// Keys возвращает живые ключи пространства без префикса
+func (n *NamespacedCache) Keys() []string {
+	keys := n.store.KeysWithPrefix(n.prefix)
+	for i, k := range keys {
+		keys[i] = strings.TrimPrefix(k, n.prefix)
+	}
+
+	return keys
+}
+
+// Flush очищает только пространство, как FlushNamespace
+func (n *NamespacedCache) Flush() {
+	n.FlushNamespace()
+}
+
+// FlushNamespace удаляет все ключи пространства и возвращает их количество
+func (n *NamespacedCache) FlushNamespace() int {
+	return n.store.DeleteByPrefix(n.prefix)
+}
+
+// Close ничего не делает: представление не владеет кешем и не закрывает его
+func (n *NamespacedCache) Close() error {
+	return nil
+}
@@ -0,0 +1,527 @@
+package internal
+
+import (
+	"fmt"
+	"time"
+
+	"InMemoryCache/internal/clock"
+)
+
+// Option настраивает кеш при создании
+type Option func(*options)
+
+type options struct {
+	lockStripes   int
+	sizeEstimator func(value interface{}) int64
+
+	expirationPredicate func(item Item) bool
+	monotonicClock      bool
+	keyLocking          bool
+	gcBatchSize         int
+	compressThreshold   int
+	panicHandler        func(recovered interface{})
+	asyncWrites         int
+	maxKeyLength        int
+	transformOnSet      func(value interface{}) interface{}
+	transformOnGet      func(value interface{}) interface{}
+	initialCapacity     int
+	prefixIndex         bool
+	hitCounting         bool
+	persistPath         string
+	persistCodec        Codec
+	valueCodec          ValueCodec
+	ttlOverride         time.Duration
+	memoryWatermark     uint64
+	memoryEvictFraction float64
+	memoryCheckInterval time.Duration
+	strictTTL           bool
+	journalSize         int
+	latencyObserver     func(op string, lockWait, total time.Duration)
+	maxEntries          int
+	evictionPolicy      EvictionPolicy
+	coarseTick          time.Duration
+	equal               func(a, b interface{}) bool
+	equalMode           EqualSetMode
+	valueExpiry         bool
+	maxWatchers         int
+	gcBackoffMax        time.Duration
+	sourceTracking      bool
+	expirationGrace     time.Duration
+	bloomKeys           int
+	prefixDefaults      *prefixDefaults
+	prefixStats         func(key string) string
+	encryptionKey       []byte
+	configErr           error
+	maxBytes            int64
+	counterDefault      int64
+	slidingExpiration   bool
+	logger              Logger
+	defaultExpiration   time.Duration
+	cleanupInterval     time.Duration
+	clock               clock.Clock
+	invalidationBus     InvalidationBus
+	nodeID              string
+	refreshAhead        float64
+	refreshLoader       func(key string) (interface{}, error)
+	staleWindow         time.Duration
+	staleLoader         func(key string) (interface{}, error)
+	ttlJitter           float64
+	readMostly          bool
+	clone               func(value interface{}) interface{}
+	copyMode            CopyMode
+}
+
+func newOptions(opts []Option) options {
+	var o options
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.logger == nil {
+		o.logger = nopLogger{}
+	}
+
+	return o
+}
+
+// WithLockStriping делит кеш на stripes сегментов, каждый со своим мьютексом.
+// Ключ всегда попадает в один и тот же сегмент, поэтому записи в разные сегменты
+// не блокируют друг друга. Подходит для нагрузки с преобладанием записи,
+// где RWMutex не дает выигрыша
+func WithLockStriping(stripes int) Option {
+	return func(o *options) {
+		o.lockStripes = stripes
+	}
+}
+
+// WithSizeEstimator включает учет размера ключей и значений в Stats.
+// Размер значения оценивается функцией estimator при каждой записи,
+// готовая оценка - SizeOf
+func WithSizeEstimator(estimator func(value interface{}) int64) Option {
+	return func(o *options) {
+		o.sizeEstimator = estimator
+	}
+}
+
+// WithExpirationPredicate заменяет проверку времени истечения функцией expired,
+// которую вызывают Get и GC. Время истечения элемента доступно через item.Expiration()
+func WithExpirationPredicate(expired func(item Item) bool) Option {
+	return func(o *options) {
+		o.expirationPredicate = expired
+	}
+}
+
+// WithMonotonicClock отсчитывает время жизни по монотонным часам, а не по системным.
+// Перевод системных часов (NTP, пауза виртуальной машины) тогда не продлевает
+// и не сокращает время жизни элементов
+func WithMonotonicClock() Option {
+	return func(o *options) {
+		o.monotonicClock = true
+	}
+}
+
+// WithKeyLocking заставляет Set и Delete ждать GetOrCompute и Update над тем же ключом,
+// так что любые записи одного ключа выполняются строго по очереди.
+// Без опции по ключу сериализуются только GetOrCompute и Update
+func WithKeyLocking() Option {
+	return func(o *options) {
+		o.keyLocking = true
+	}
+}
+
+// WithGCBatchSize заставляет GC удалять просроченные элементы порциями по size штук,
+// снимая блокировку между порциями. Читатели не ждут окончания всей очистки,
+// но сама очистка выполняется дольше, чем удаление под одной блокировкой
+func WithGCBatchSize(size int) Option {
+	return func(o *options) {
+		o.gcBatchSize = size
+	}
+}
+
+// WithCompression включает сжатие gzip для значений []byte длиннее threshold байт.
+// Значения сжимаются в Set и распаковываются при чтении, что экономит память ценой процессорного времени
+func WithCompression(threshold int) Option {
+	return func(o *options) {
+		o.compressThreshold = threshold
+	}
+}
+
+// WithPanicHandler задает обработчик паник, перехваченных в пользовательских функциях
+// (обработчиках удаления, предикатах, функциях вычисления)
+func WithPanicHandler(handler func(recovered interface{})) Option {
+	return func(o *options) {
+		o.panicHandler = handler
+	}
+}
+
+// WithAsyncWrites включает асинхронную запись: Set кладет элемент в буфер на bufferSize записей,
+// а в хранилище его переносит одна фоновая горутина. Запись не конкурирует за блокировку
+// с читателями, но Get сразу после Set может не увидеть новое значение, а Delete
+// может выполниться раньше стоящей в буфере записи того же ключа.
+// GetOrCompute и Update пишут синхронно. Close дожидается применения всего буфера
+func WithAsyncWrites(bufferSize int) Option {
+	return func(o *options) {
+		o.asyncWrites = bufferSize
+	}
+}
+
+// WithMaxKeyLength запрещает запись ключей длиннее maxLength байт:
+// SetE и GetOrCompute возвращают ErrKeyTooLong, Set отбрасывает значение. По-умолчанию длина не ограничена
+func WithMaxKeyLength(maxLength int) Option {
+	return func(o *options) {
+		o.maxKeyLength = maxLength
+	}
+}
+
+// WithValueTransform задает преобразование onSet, которое применяется к значению перед записью,
+// например для приведения строк к каноническому виду. onGet, если задано, применяется
+// к значению при чтении и может восстанавливать исходную форму. По-умолчанию значения не меняются
+func WithValueTransform(onSet, onGet func(value interface{}) interface{}) Option {
+	return func(o *options) {
+		o.transformOnSet = onSet
+		o.transformOnGet = onGet
+	}
+}
+
+// WithInitialCapacity заранее выделяет хранилище под capacity элементов, чтобы избежать
+// повторного роста map при первичном заполнении большого кеша. Flush пересоздает хранилище
+// с той же емкостью. При WithLockStriping емкость делится между сегментами
+func WithInitialCapacity(capacity int) Option {
+	return func(o *options) {
+		o.initialCapacity = capacity
+	}
+}
+
+// WithPrefixIndex поддерживает префиксное дерево ключей, с которым KeysWithPrefix и DeleteByPrefix
+// обходят только подходящие ключи, а не весь кеш. Дерево замедляет каждую запись и удаление
+// и занимает память, пропорциональную суммарной длине ключей, поэтому без операций
+// по префиксу включать его не стоит
+func WithPrefixIndex() Option {
+	return func(o *options) {
+		o.prefixIndex = true
+	}
+}
+
+// WithHitCounting включает подсчет обращений к каждому элементу и запоминание времени последнего
+// из них (см. GetItem): Get и GetWithHits увеличивают счетчик, Peek и Lookup нет.
+// Счетчик сбрасывается при перезаписи элемента и не сохраняется SaveFile
+func WithHitCounting() Option {
+	return func(o *options) {
+		o.hitCounting = true
+	}
+}
+
+// WithPersistOnClose сохраняет кеш в файл path при Close, как SaveFile, после остановки GC
+// и применения асинхронных записей. Ошибка сохранения возвращается из Close
+func WithPersistOnClose(path string, codec ...Codec) Option {
+	return func(o *options) {
+		o.persistPath = path
+		o.persistCodec = pickCodec(codec)
+	}
+}
+
+// WithTTLOverride записывает все элементы со временем жизни d вместо запрошенного,
+// кроме записанных с NoExpiration. Предназначена для тестовых и staging окружений,
+// где нужно проверить поведение при истечении, не меняя вызывающий код. По-умолчанию выключена
+func WithTTLOverride(d time.Duration) Option {
+	return func(o *options) {
+		o.ttlOverride = d
+	}
+}
+
+// WithValueCodec хранит значения закодированными кодеком codec (GobValueCodec, JSONValueCodec или свой)
+// и декодирует при каждом чтении. Байтовые срезы вместо живых объектов с указателями меньше нагружают
+// сборщик мусора, а SizeOf для WithMaxBytes считает их размер точно. Сжатие WithCompression
+// и шифрование WithEncryption применяются к закодированным данным. Значение, которое кодек
+// не смог закодировать, хранится как есть, нечитаемые данные возвращаются как nil
+func WithValueCodec(codec ValueCodec) Option {
+	return func(o *options) {
+		o.valueCodec = codec
+	}
+}
+
+// WithMemoryWatermark раз в секунду (см. WithMemoryCheckInterval) проверяет размер кучи процесса
+// (runtime.MemStats.HeapAlloc) и, если он превышает heapBytes, вытесняет долю evictFraction (от 0 до 1)
+// элементов, к которым дольше всего не обращались. Позволяет кешу уступать память в контейнерах
+// с жестким лимитом. Вытесненные элементы передаются обработчикам с причиной ReasonMemoryPressure.
+// Давность обращений берется из политики PolicyLRU, при другой политике для нее ведется отдельный список
+func WithMemoryWatermark(heapBytes uint64, evictFraction float64) Option {
+	return func(o *options) {
+		o.memoryWatermark = heapBytes
+		o.memoryEvictFraction = evictFraction
+	}
+}
+
+// WithMemoryCheckInterval задает период проверки размера кучи для WithMemoryWatermark,
+// по-умолчанию секунда. runtime.ReadMemStats ненадолго останавливает программу,
+// поэтому слишком частая проверка заметна под нагрузкой
+func WithMemoryCheckInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.memoryCheckInterval = d
+	}
+}
+
+// WithStrictTTL меняет смысл нулевой продолжительности жизни: без опции элемент, записанный с 0,
+// получает время жизни по-умолчанию, с опцией он бессрочный, а время жизни по-умолчанию
+// нужно запрашивать явно через DefaultExpiration. Отрицательная продолжительность (NoExpiration)
+// в обоих режимах означает бессрочный элемент
+func WithStrictTTL() Option {
+	return func(o *options) {
+		o.strictTTL = true
+	}
+}
+
+// WithJournal ведет журнал последних size операций, доступный через RecentOps.
+// Помогает выяснить, почему пропал ключ. Каждая операция дополнительно захватывает
+// мьютекс журнала, поэтому по-умолчанию журнал выключен
+func WithJournal(size int) Option {
+	return func(o *options) {
+		o.journalSize = size
+	}
+}
+
+// WithLatencyObserver передает observer длительность каждой операции Get ("get") и Set ("set"):
+// lockWait - время ожидания блокировки хранилища, total - полное время операции.
+// Помогает обнаружить конкуренцию за блокировку. Наблюдатель вызывается после снятия блокировки,
+// без него время не замеряется
+func WithLatencyObserver(observer func(op string, lockWait, total time.Duration)) Option {
+	return func(o *options) {
+		o.latencyObserver = observer
+	}
+}
+
+// WithMaxEntries ограничивает количество элементов кеша, включая просроченные, но еще не удаленные GC.
+// Что происходит при записи нового ключа в заполненный кеш, определяет WithEvictionPolicy,
+// по-умолчанию вытесняется элемент, к которому дольше всего не обращались.
+// При PolicyReject об отклоненной записи сообщают только методы с ошибкой (SetE, SetWithDeps, IncrementWithTTL):
+// Set, SetKeepTTL, Update, Txn.Set, Rotate, LoadFile и асинхронная запись не поместившиеся
+// элементы просто отбрасывают, GetOrCompute возвращает вычисленное значение, не сохраняя его.
+// При WithLockStriping ограничение делится между сегментами поровну
+func WithMaxEntries(maxEntries int) Option {
+	return func(o *options) {
+		o.maxEntries = maxEntries
+	}
+}
+
+// WithEvictionPolicy задает поведение заполненного кеша, по-умолчанию PolicyLRU
+func WithEvictionPolicy(policy EvictionPolicy) Option {
+	return func(o *options) {
+		o.evictionPolicy = policy
+	}
+}
+
+// WithCoarseClock заменяет чтение часов при каждой проверке истечения сравнением с текущим временем,
+// которое фоновая горутина обновляет раз в tick. Проверки становятся дешевле, но элементы
+// истекают с опозданием до tick, а записанные между тиками отсчитывают время жизни от последнего тика.
+// Подходит для больших кешей, где точность времени жизни до tick не важна
+func WithCoarseClock(tick time.Duration) Option {
+	return func(o *options) {
+		o.coarseTick = tick
+	}
+}
+
+// WithEqualitySkip заставляет Set сравнивать новое значение с текущим живым и, если они равны,
+// не перезаписывать элемент: mode EqualSkip пропускает запись, EqualRefreshTTL обновляет только
+// время истечения. Обработчики и счетчики при этом не срабатывают. equal сравнивает значения,
+// nil означает reflect.DeepEqual. Сравнение выполняется под блокировкой при каждой записи
+// существующего ключа и для больших значений может стоить дороже самой записи
+func WithEqualitySkip(equal func(a, b interface{}) bool, mode EqualSetMode) Option {
+	return func(o *options) {
+		o.equal = equal
+		o.equalMode = mode
+	}
+}
+
+// WithValueExpiration берет время истечения значений, реализующих Expirer, из самих значений
+// вместо переданной продолжительности жизни. Остальные значения записываются как обычно
+func WithValueExpiration() Option {
+	return func(o *options) {
+		o.valueExpiry = true
+	}
+}
+
+// WithMaxWatchers ограничивает общее количество подписок Watch, сверх него Watch возвращает
+// ErrTooManyWatchers. По-умолчанию количество подписок не ограничено
+func WithMaxWatchers(n int) Option {
+	return func(o *options) {
+		o.maxWatchers = n
+	}
+}
+
+// WithGCBackoff удваивает интервал между проходами GC после каждого прохода, который ничего
+// не удалил, но не больше max. Как только GC удаляет хотя бы один элемент, интервал возвращается
+// к cleanupInterval. Простаивающий кеш реже будит планировщик, зато просроченные элементы
+// после долгого простоя могут занимать память до max дольше обычного
+func WithGCBackoff(max time.Duration) Option {
+	return func(o *options) {
+		o.gcBackoffMax = max
+	}
+}
+
+// WithSourceTracking включает хранение меток источника, переданных в SetWithSource.
+// Метки хранятся отдельно от элементов и без этой опции память не занимают
+func WithSourceTracking() Option {
+	return func(o *options) {
+		o.sourceTracking = true
+	}
+}
+
+// WithExpirationGrace считает элемент просроченным только спустя grace после его времени истечения,
+// например когда время истечения получено от внешней системы с расходящимися часами.
+// Касается Get, GC и всех остальных проверок истечения, но не оставшегося времени жизни,
+// которое возвращают Inspect и лизинги. По-умолчанию допуск нулевой
+func WithExpirationGrace(grace time.Duration) Option {
+	return func(o *options) {
+		o.expirationGrace = grace
+	}
+}
+
+// WithTTLJitter случайно изменяет время жизни каждого записанного элемента в пределах ±fraction,
+// чтобы элементы, записанные одновременно (например при прогреве кеша), не истекали в один момент.
+// Скользящее время жизни, срок обновления WithRefreshAhead и явный Expire не изменяются.
+// fraction должна быть в [0, 1), 0 отключает разброс
+func WithTTLJitter(fraction float64) Option {
+	return func(o *options) {
+		o.ttlJitter = fraction
+	}
+}
+
+// WithBloomFilter ведет фильтр Блума по записанным ключам, рассчитанный на expectedKeys ключей,
+// чтобы Get по никогда не записывавшемуся ключу возвращал промах без блокировки и поиска.
+// Удаленные ключи остаются в фильтре до его пересоздания: GC пересоздает переполненный фильтр,
+// Flush - всегда. Полезен, когда большая часть чтений - промахи
+func WithBloomFilter(expectedKeys int) Option {
+	return func(o *options) {
+		o.bloomKeys = expectedKeys
+	}
+}
+
+// WithPrefixDefault задает время жизни по-умолчанию ttl для ключей с префиксом prefix:
+// его получают записи с продолжительностью DefaultExpiration (или 0 без WithStrictTTL).
+// Опцию можно передать несколько раз, из подходящих префиксов выбирается самый длинный,
+// ключи без подходящего префикса получают общее время жизни по-умолчанию
+func WithPrefixDefault(prefix string, ttl time.Duration) Option {
+	return func(o *options) {
+		if o.prefixDefaults == nil {
+			o.prefixDefaults = &prefixDefaults{}
+		}
+
+		o.prefixDefaults.add(prefix, ttl)
+	}
+}
+
+// WithPrefixStats включает подсчет попаданий и промахов Get по пространствам имен, которые
+// namespace выделяет из ключа, например часть до первого ":". Результат доступен в PrefixStats.
+// namespace вызывается при каждом чтении, поэтому должна быть быстрой, не обращаться к кешу и возвращать небольшое число
+// различных значений: счетчики каждого пространства имен хранятся, пока существует кеш
+func WithPrefixStats(namespace func(key string) string) Option {
+	return func(o *options) {
+		o.prefixStats = namespace
+	}
+}
+
+// WithEncryption шифрует значения []byte в памяти алгоритмом AES-GCM с ключом key длиной 16, 24
+// или 32 байта: Set хранит шифротекст, Get расшифровывает его. Значения других типов не шифруются,
+// SaveFile и снимки получают расшифрованные значения. Шифрование защищает содержимое кеша в дампах
+// памяти и core-файлах, но не от того, кто может прочитать память живого процесса вместе с ключом.
+// Каждое чтение и запись расшифровывает и шифрует значение заново.
+// Поврежденный шифротекст не расшифровывается и читается как nil.
+// Для ключа другой длины CacheBuilder.Build и NewInMemoryCacheFromWAL возвращают ErrInvalidConfig,
+// а кеш, созданный New или NewInMemoryCache, отклоняет все записи с этой ошибкой (см. SetE)
+func WithEncryption(key []byte) Option {
+	if !validAESKey(len(key)) {
+		err := fmt.Errorf("%w: encryption key must be 16, 24 or 32 bytes", ErrInvalidConfig)
+		return func(o *options) {
+			o.configErr = err
+		}
+	}
+
+	key = append([]byte(nil), key...)
+
+	return func(o *options) {
+		o.encryptionKey = key
+	}
+}
+
+// WithMaxBytes ограничивает суммарный размер ключей и значений кеша примерно maxBytes байтами.
+// Размер значения оценивает WithSizeEstimator (без нее - SizeOf) или задает SetWithSize.
+// Запись, превышающая ограничение, вытесняет элементы по WithEvictionPolicy, при PolicyReject
+// отклоняется с ErrCacheFull, как и элемент больше всего ограничения.
+// При WithLockStriping ограничение делится между сегментами поровну, 0 снимает ограничение
+func WithMaxBytes(maxBytes int64) Option {
+	return func(o *options) {
+		o.maxBytes = maxBytes
+		if maxBytes > 0 && o.sizeEstimator == nil {
+			o.sizeEstimator = SizeOf
+		}
+	}
+}
+
+// WithCounterDefault задает начальное значение счетчиков, которые Increment, Decrement
+// и IncrementWithTTL создают для отсутствующего ключа. По-умолчанию 0
+func WithCounterDefault(initial int64) Option {
+	return func(o *options) {
+		o.counterDefault = initial
+	}
+}
+
+// WithSlidingExpiration включает скользящее время жизни для всех элементов с конечным сроком:
+// каждое чтение через Get продлевает элемент на его время жизни, и он истекает только после
+// такого же периода без чтений. Время истечения из WithValueExpiration не продлевается.
+// Для отдельных элементов скользящее время жизни задает SetWithSliding.
+// С WithWAL продление записывается в журнал, поэтому каждое такое чтение дописывает в него запись
+func WithSlidingExpiration() Option {
+	return func(o *options) {
+		o.slidingExpiration = true
+	}
+}
+
+// WithLogger передает события кеша в logger, например *slog.Logger: записи, удаления и проходы GC
+// на уровне Debug, отклоненные записи на уровне Warn, перехваченные паники обратных вызовов
+// без WithPanicHandler на уровне Error. По-умолчанию события не журналируются
+func WithLogger(logger Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithDefaultExpiration задает время жизни по-умолчанию для New, как первый аргумент NewInMemoryCache.
+// По-умолчанию 0: элементы без явного времени жизни не истекают
+func WithDefaultExpiration(d time.Duration) Option {
+	return func(o *options) {
+		o.defaultExpiration = d
+	}
+}
+
+// WithCleanupInterval задает интервал GC для New, как второй аргумент NewInMemoryCache.
+// По-умолчанию 0: GC не запускается, просроченные элементы просто не возвращаются
+func WithCleanupInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.cleanupInterval = interval
+	}
+}
+
+// WithClock подменяет часы, по которым считаются время записи и истечение элементов
+// и запускаются GC и таймеры ExpiryChan. С clock.Fake тесты двигают время через Advance
+// вместо ожидания, clock.Func подменяет только текущее время. Важнее WithMonotonicClock,
+// WithCoarseClock продолжает кешировать его показания. Время операций в Stats и журнале
+// по-прежнему измеряется системными часами
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithInvalidationBus рассылает через bus изменения ключей этого кеша - записи, явные удаления и Flush,
+// а ключи, измененные другими узлами, удаляет у себя с причиной ReasonInvalidated.
+// node отличает собственные сообщения кеша, при пустом node каждый созданный кеш получает случайный.
+// Сообщения отправляются в фоне пачками, при отставании шины часть изменений может быть
+// не доставлена, поэтому узлы остаются согласованы в пределах времени жизни элементов.
+// ShardedCache подписывается на шину один раз для всех сегментов
+func WithInvalidationBus(bus InvalidationBus, node string) Option {
+	return func(o *options) {
+		o.invalidationBus = bus
+		o.nodeID = node
+	}
+}
@@ -0,0 +1,320 @@
+// Package otelcache добавляет к кешу трассировку и метрики OpenTelemetry.
+// Основной пакет кеша при этом не зависит от OpenTelemetry
+package otelcache
+
+import (
+	"context"
+	"hash/fnv"
+	"strconv"
+	"time"
+
+	"InMemoryCache/internal"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// computer реализуют кеши с GetOrCompute, например *internal.InMemoryCache
+type computer interface {
+	GetOrCompute(key string, compute func() (interface{}, time.Duration, error)) (interface{}, error)
+}
+
+// checkedSetter реализуют кеши, сообщающие об отклоненной записи, например *internal.InMemoryCache
+type checkedSetter interface {
+	SetE(key string, value interface{}, duration time.Duration) error
+}
+
+// Cache - обертка над кешем, создающая спан и записывающая метрики на каждую операцию
+type Cache struct {
+	cache      internal.Cache
+	tracer     trace.Tracer
+	meter      metric.Meter
+	metrics    *metrics
+	hashedKeys bool
+}
+
+// metrics - инструменты метрик, атрибуты: cache.operation и cache.hit для чтений
+type metrics struct {
+	operations metric.Int64Counter
+	duration   metric.Float64Histogram
+}
+
+// Option настраивает обертку
+type Option func(*Cache)
+
+// WithTracer задает трассировщик для спанов. Без него обертка не создает спанов
+// и только передает вызовы кешу
+func WithTracer(tracer trace.Tracer) Option {
+	return func(c *Cache) {
+		c.tracer = tracer
+	}
+}
+
+// WithMeter задает источник метрик: счетчик операций cache.operations с атрибутами
+// cache.operation и cache.hit и гистограмму длительности cache.operation.duration в секундах.
+// Ключи в метрики не попадают
+func WithMeter(meter metric.Meter) Option {
+	return func(c *Cache) {
+		c.meter = meter
+	}
+}
+
+// WithHashedKeys записывает в спаны хеш ключа вместо самого ключа,
+// чтобы ключи с персональными данными не попадали в трассировку
+func WithHashedKeys() Option {
+	return func(c *Cache) {
+		c.hashedKeys = true
+	}
+}
+
+// Wrap возвращает кеш c с трассировкой операций
+func Wrap(c internal.Cache, opts ...Option) *Cache {
+	wrapped := &Cache{cache: c}
+
+	for _, opt := range opts {
+		opt(wrapped)
+	}
+
+	if wrapped.meter != nil {
+		wrapped.metrics = newMetrics(wrapped.meter)
+	}
+
+	return wrapped
+}
+
+// newMetrics создает инструменты, ошибки передаются в otel.Handle:
+// по контракту API инструмент пригоден к использованию и при ошибке
+func newMetrics(meter metric.Meter) *metrics {
+	operations, err := meter.Int64Counter("cache.operations",
+		metric.WithDescription("Количество операций кеша"), metric.WithUnit("{operation}"))
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	duration, err := meter.Float64Histogram("cache.operation.duration",
+		metric.WithDescription("Длительность операций кеша"), metric.WithUnit("s"))
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	return &metrics{operations: operations, duration: duration}
+}
+
+func (c *Cache) Get(key string) (interface{}, bool) {
+	value, found, _ := c.GetCtx(context.Background(), key)
+	return value, found
+}
+
+// GetCtx - Get, спан которого становится дочерним для спана из ctx.
+// Если ctx уже завершен, кеш не читается и возвращается ctx.Err()
+func (c *Cache) GetCtx(ctx context.Context, key string) (interface{}, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	if !c.instrumented() {
+		value, found := c.cache.Get(key)
+		return value, found, nil
+	}
+
+	op := c.begin(ctx, "Get", c.keyAttribute(key))
+	value, found := c.cache.Get(key)
+	op.end(nil, attribute.Bool("cache.hit", found))
+
+	return value, found, nil
+}
+
+func (c *Cache) Has(key string) bool {
+	if !c.instrumented() {
+		return c.cache.Has(key)
+	}
+
+	op := c.begin(context.Background(), "Has", c.keyAttribute(key))
+	found := c.cache.Has(key)
+	op.end(nil, attribute.Bool("cache.hit", found))
+
+	return found
+}
+
+func (c *Cache) Count() int {
+	return c.cache.Count()
+}
+
+func (c *Cache) Set(key string, value interface{}, duration time.Duration) {
+	c.SetCtx(context.Background(), key, value, duration)
+}
+
+// SetCtx - Set, спан которого становится дочерним для спана из ctx. Возвращает ctx.Err(),
+// если ctx уже завершен, и ошибку отклоненной записи, если обернутый кеш поддерживает SetE
+func (c *Cache) SetCtx(ctx context.Context, key string, value interface{}, duration time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if !c.instrumented() {
+		return c.set(key, value, duration)
+	}
+
+	op := c.begin(ctx, "Set", c.keyAttribute(key), attribute.Int64("cache.ttl_ms", duration.Milliseconds()))
+	err := c.set(key, value, duration)
+	op.end(err)
+
+	return err
+}
+
+func (c *Cache) set(key string, value interface{}, duration time.Duration) error {
+	if s, ok := c.cache.(checkedSetter); ok {
+		return s.SetE(key, value, duration)
+	}
+
+	c.cache.Set(key, value, duration)
+
+	return nil
+}
+
+func (c *Cache) Delete(key string) error {
+	return c.DeleteCtx(context.Background(), key)
+}
+
+// DeleteCtx - Delete, спан которого становится дочерним для спана из ctx.
+// Если ctx уже завершен, ключ не удаляется и возвращается ctx.Err()
+func (c *Cache) DeleteCtx(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if !c.instrumented() {
+		return c.cache.Delete(key)
+	}
+
+	op := c.begin(ctx, "Delete", c.keyAttribute(key))
+	err := c.cache.Delete(key)
+	op.end(err)
+
+	return err
+}
+
+func (c *Cache) Flush() {
+	if !c.instrumented() {
+		c.cache.Flush()
+		return
+	}
+
+	op := c.begin(context.Background(), "Flush")
+	c.cache.Flush()
+	op.end(nil)
+}
+
+// Close закрывает обернутый кеш
+func (c *Cache) Close() error {
+	return c.cache.Close()
+}
+
+// GetOrCompute трассирует вычисление значения при промахе. Если обернутый кеш
+// не поддерживает GetOrCompute, значение читается через Get и записывается через Set
+func (c *Cache) GetOrCompute(key string, compute func() (interface{}, time.Duration, error)) (interface{}, error) {
+	return c.GetOrComputeCtx(context.Background(), key, compute)
+}
+
+// GetOrComputeCtx - GetOrCompute, спан которого становится дочерним для спана из ctx.
+// Если ctx уже завершен, значение не читается и не вычисляется и возвращается ctx.Err()
+func (c *Cache) GetOrComputeCtx(ctx context.Context, key string, compute func() (interface{}, time.Duration, error)) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var op *operation
+	if c.instrumented() {
+		op = c.begin(ctx, "GetOrCompute", c.keyAttribute(key))
+	}
+
+	computed := false
+	traced := func() (interface{}, time.Duration, error) {
+		computed = true
+		return compute()
+	}
+
+	var (
+		value interface{}
+		err   error
+	)
+
+	if cc, ok := c.cache.(computer); ok {
+		value, err = cc.GetOrCompute(key, traced)
+	} else if v, found := c.cache.Get(key); found {
+		value = v
+	} else {
+		var duration time.Duration
+		if value, duration, err = traced(); err == nil {
+			c.cache.Set(key, value, duration)
+		}
+	}
+
+	if op != nil {
+		if err != nil && op.span != nil {
+			op.span.RecordError(err)
+		}
+		op.end(err, attribute.Bool("cache.hit", !computed))
+	}
+
+	return value, err
+}
+
+func (c *Cache) instrumented() bool {
+	return c.tracer != nil || c.metrics != nil
+}
+
+// operation - одна инструментированная операция: спан, если задан трассировщик,
+// контекст вызывающего и время начала для метрик
+type operation struct {
+	cache *Cache
+	ctx   context.Context
+	name  string
+	span  trace.Span
+	start time.Time
+}
+
+// begin начинает операцию name со спаном, дочерним для спана из ctx. attrs записываются только в спан
+func (c *Cache) begin(ctx context.Context, name string, attrs ...attribute.KeyValue) *operation {
+	op := &operation{cache: c, ctx: ctx, name: name}
+
+	if c.tracer != nil {
+		_, op.span = c.tracer.Start(ctx, "cache."+name, trace.WithAttributes(attrs...))
+	}
+	if c.metrics != nil {
+		op.start = time.Now()
+	}
+
+	return op
+}
+
+// end завершает операцию, attrs записываются и в спан, и в метрики
+func (op *operation) end(err error, attrs ...attribute.KeyValue) {
+	if op.span != nil {
+		op.span.SetAttributes(attrs...)
+		if err != nil {
+			op.span.SetStatus(codes.Error, err.Error())
+		}
+		op.span.End()
+	}
+
+	if m := op.cache.metrics; m != nil {
+		set := metric.WithAttributes(append(attrs, attribute.String("cache.operation", op.name))...)
+		m.operations.Add(op.ctx, 1, set)
+		m.duration.Record(op.ctx, time.Since(op.start).Seconds(), set)
+	}
+}
+
+func (c *Cache) keyAttribute(key string) attribute.KeyValue {
+	if !c.hashedKeys {
+		return attribute.String("cache.key", key)
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(key))
+
+	return attribute.String("cache.key_hash", strconv.FormatUint(h.Sum64(), 16))
+}
@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"fmt"
+	"time"
+)
+
+// Паника в пользовательских функциях перехватывается и передается обработчику
+// из WithPanicHandler (по-умолчанию выводится в stdout), дальше она не распространяется.
+// Перехватываются: обработчики OnEvicted, OnEvictedBatch и FlushWithCallback,
+// предикат WithExpirationPredicate, оценщик WithSizeEstimator, преобразования WithValueTransform
+// (значение тогда не меняется), наблюдатель WithLatencyObserver, сравнение WithEqualitySkip
+// (значения тогда считаются разными), Expirer.ExpireAt (тогда используется переданная продолжительность),
+// функция MapValues (значение тогда не меняется), кодек WithValueCodec (значение тогда хранится
+// как есть или читается как nil), функция WithPrefixStats (ключ тогда относится
+// к пустому пространству имен) и функции вычисления GetOrCompute и загрузки Fetch
+// (тогда возвращается ErrPanic).
+// Функции Atomic и Update выполняются в горутине вызывающего, их паника не перехватывается,
+// но захваченные ими блокировки освобождаются
+
+// recoverPanic перехватывает панику и передает ее обработчику, вызывается только через defer
+func (c *InMemoryCache) recoverPanic() {
+	if r := recover(); r != nil {
+		c.handlePanic(r)
+	}
+}
+
+func (c *InMemoryCache) handlePanic(recovered interface{}) {
+	if c.panicHandler != nil {
+		c.panicHandler(recovered)
+		return
+	}
+
+	c.logger.Error("cache callback panicked", "panic", recovered)
+}
+
+// callSafe вызывает пользовательскую функцию fn, перехватывая ее панику
+func (c *InMemoryCache) callSafe(fn func()) {
+	defer c.recoverPanic()
+
+	fn()
+}
+
+// callCompute вызывает функцию вычисления значения, паника превращается в ErrPanic
+func (c *InMemoryCache) callCompute(compute func() (interface{}, time.Duration, error)) (value interface{}, duration time.Duration, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.handlePanic(r)
+			err = fmt.Errorf("%w: %v", ErrPanic, r)
+		}
+	}()
+
+	return compute()
+}
+
+// estimateSize оценивает размер значения, при панике оценщика размер считается нулевым
+func (c *InMemoryCache) estimateSize(value interface{}) (size int64) {
+	defer c.recoverPanic()
+
+	return c.sizeEstimator(value)
+}
+
+// checkExpired вызывает пользовательский предикат, при панике элемент считается живым
+func (c *InMemoryCache) checkExpired(item Item) (expired bool) {
+	defer c.recoverPanic()
+
+	return c.expirationPredicate(item)
+}
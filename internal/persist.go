@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// SaveFile сохраняет живые элементы кеша в файл path в формате codec (по-умолчанию GobCodec).
+// Файл записывается целиком во временный файл и затем переименовывается,
+// поэтому при ошибке предыдущий снимок не портится
+func (c *InMemoryCache) SaveFile(path string, codec ...Codec) error {
+	return saveFile(path, pickCodec(codec), c.snapshotItems())
+}
+
+// Save записывает живые элементы кеша в w в формате codec (по-умолчанию GobCodec),
+// например чтобы передать снимок по сети. Время истечения сохраняется
+func (c *InMemoryCache) Save(w io.Writer, codec ...Codec) error {
+	return pickCodec(codec).Encode(w, c.snapshotItems())
+}
+
+// Load загружает в кеш элементы, записанные Save, как LoadFile
+func (c *InMemoryCache) Load(r io.Reader, codec ...Codec) error {
+	items, err := pickCodec(codec).Decode(r)
+	if err != nil {
+		return err
+	}
+
+	c.restoreItems(items)
+
+	return nil
+}
+
+// saveFile атомарно записывает элементы items в файл path
+func saveFile(path string, codec Codec, items map[string]Item) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	w := bufio.NewWriter(f)
+	if err := codec.Encode(w, items); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// LoadFile загружает в кеш элементы, сохраненные SaveFile. Просроченные элементы пропускаются,
+// время истечения остальных сохраняется, существующие ключи перезаписываются
+func (c *InMemoryCache) LoadFile(path string, codec ...Codec) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return c.Load(bufio.NewReader(f), codec...)
+}
+
+func pickCodec(codec []Codec) Codec {
+	if len(codec) > 0 && codec[0] != nil {
+		return codec[0]
+	}
+
+	return GobCodec{}
+}
+
+// snapshotItems копирует живые элементы со значениями в том виде, в каком их записали
+func (c *InMemoryCache) snapshotItems() map[string]Item {
+	c.rmu.RLock()
+	defer c.rmu.RUnlock()
+
+	items := make(map[string]Item, len(c.cache))
+	for k, i := range c.cache {
+		if c.expired(i) {
+			continue
+		}
+
+		items[k] = NewItem(c.itemValue(i), i.createdAt, i.Expiration())
+	}
+
+	return items
+}
+
+// restoreItems записывает загруженные элементы, сохраняя их время записи и истечения
+func (c *InMemoryCache) restoreItems(items map[string]Item) {
+	restored := make(map[string]Item, len(items))
+	for k, i := range items {
+		if c.expired(i) || c.checkKey(k) != nil {
+			continue
+		}
+
+		c.encodeValue(&i, i.value)
+		c.countHits(&i)
+		if c.sizeEstimator != nil {
+			i.size = c.estimateSize(i.value)
+		}
+
+		restored[k] = i
+	}
+
+	c.rmu.Lock()
+	defer c.unlock()
+
+	for k, i := range restored {
+		c.storeItem(k, i)
+	}
+}
@@ -0,0 +1,28 @@
+package internal
+
+// Pin защищает живой элемент от вытеснения при нехватке места (см. WithMemoryWatermark),
+// время жизни при этом продолжает действовать. Защита сохраняется при перезаписи ключа
+// и снимается Unpin или удалением. Возвращает false, если элемента нет
+func (c *InMemoryCache) Pin(key string) bool {
+	return c.setPinned(key, true)
+}
+
+// Unpin снимает защиту, установленную Pin. Возвращает false, если элемента нет
+func (c *InMemoryCache) Unpin(key string) bool {
+	return c.setPinned(key, false)
+}
+
+func (c *InMemoryCache) setPinned(key string, pinned bool) bool {
+	c.rmu.Lock()
+	defer c.unlock()
+
+	item, found := c.cache[key]
+	if !found || c.expired(item) {
+		return false
+	}
+
+	item.pinned = pinned
+	c.cache[key] = item
+
+	return true
+}
@@ -0,0 +1,172 @@
+package internal
+
+import (
+	"path"
+	"strings"
+)
+
+// KeysWithPrefix возвращает живые ключи, начинающиеся с prefix, например все ключи одного пространства имен.
+// Без WithPrefixIndex вызов перебирает все элементы кеша - O(n)
+func (c *InMemoryCache) KeysWithPrefix(prefix string) []string {
+	c.rmu.RLock()
+	defer c.rmu.RUnlock()
+
+	var keys []string
+	for _, k := range c.matchPrefix(prefix) {
+		if !c.expired(c.cache[k]) {
+			keys = append(keys, k)
+		}
+	}
+
+	return keys
+}
+
+// DeleteByPrefix удаляет все ключи, начинающиеся с prefix, под одной блокировкой
+// и возвращает количество удаленных. Без WithPrefixIndex вызов перебирает все элементы кеша - O(n)
+func (c *InMemoryCache) DeleteByPrefix(prefix string) int {
+	c.rmu.Lock()
+	defer c.unlock()
+
+	deleted := 0
+	for _, k := range c.matchPrefix(prefix) {
+		if c.evict(k, ReasonDeleted) {
+			deleted++
+		}
+	}
+
+	return deleted
+}
+
+// DeleteByPattern удаляет все ключи, подходящие под шаблон glob в синтаксисе path.Match
+// (например "user:42:*"), под одной блокировкой и возвращает количество удаленных.
+// Для неверного шаблона ничего не удаляет и возвращает 0. С WithPrefixIndex перебираются
+// только ключи с постоянным префиксом шаблона, без нее - все элементы кеша
+func (c *InMemoryCache) DeleteByPattern(glob string) int {
+	if _, err := path.Match(glob, ""); err != nil {
+		return 0
+	}
+
+	c.rmu.Lock()
+	defer c.unlock()
+
+	deleted := 0
+	for _, k := range c.matchPrefix(literalPrefix(glob)) {
+		if matched, _ := path.Match(glob, k); matched && c.evict(k, ReasonDeleted) {
+			deleted++
+		}
+	}
+
+	return deleted
+}
+
+// literalPrefix возвращает начало шаблона path.Match до первого специального символа
+func literalPrefix(glob string) string {
+	if i := strings.IndexAny(glob, `*?[\`); i >= 0 {
+		return glob[:i]
+	}
+
+	return glob
+}
+
+// matchPrefix возвращает все ключи с префиксом prefix, включая просроченные, вызывается под блокировкой
+func (c *InMemoryCache) matchPrefix(prefix string) []string {
+	if c.prefixes != nil {
+		return c.prefixes.keys(prefix)
+	}
+
+	var keys []string
+	for k := range c.cache {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+
+	return keys
+}
+
+// prefixTrie - префиксное дерево ключей хранилища, по байту на уровень
+type prefixTrie struct {
+	root trieNode
+}
+
+type trieNode struct {
+	children map[byte]*trieNode
+	leaf     bool
+}
+
+// insert добавляет ключ в дерево
+func (t *prefixTrie) insert(key string) {
+	n := &t.root
+	for i := 0; i < len(key); i++ {
+		child, found := n.children[key[i]]
+		if !found {
+			if n.children == nil {
+				n.children = make(map[byte]*trieNode)
+			}
+
+			child = &trieNode{}
+			n.children[key[i]] = child
+		}
+
+		n = child
+	}
+
+	n.leaf = true
+}
+
+// remove удаляет ключ из дерева вместе с опустевшими узлами
+func (t *prefixTrie) remove(key string) {
+	path := make([]*trieNode, 0, len(key)+1)
+
+	n := &t.root
+	path = append(path, n)
+	for i := 0; i < len(key); i++ {
+		child, found := n.children[key[i]]
+		if !found {
+			return
+		}
+
+		n = child
+		path = append(path, n)
+	}
+
+	n.leaf = false
+
+	// Поднимаемся к корню, пока узлы не хранят ни ключа, ни потомков
+	for i := len(key); i > 0; i-- {
+		if path[i].leaf || len(path[i].children) != 0 {
+			break
+		}
+
+		delete(path[i-1].children, key[i-1])
+	}
+}
+
+// keys возвращает все ключи дерева, начинающиеся с prefix
+func (t *prefixTrie) keys(prefix string) []string {
+	n := &t.root
+	for i := 0; i < len(prefix); i++ {
+		child, found := n.children[prefix[i]]
+		if !found {
+			return nil
+		}
+
+		n = child
+	}
+
+	var keys []string
+	n.collect([]byte(prefix), &keys)
+
+	return keys
+}
+
+// collect добавляет в keys ключи поддерева, path - префикс, ведущий к узлу
+func (n *trieNode) collect(path []byte, keys *[]string) {
+	if n.leaf {
+		*keys = append(*keys, string(path))
+	}
+
+	for b, child := range n.children {
+		child.collect(append(path, b), keys)
+	}
+}
@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// hitCounter - попадания и промахи одного пространства имен
+type hitCounter struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// prefixCounters - счетчики WithPrefixStats по пространствам имен, обновляются под блокировкой
+// на чтение, поэтому хранятся в sync.Map: набор пространств имен почти не меняется
+type prefixCounters struct {
+	counters sync.Map
+}
+
+func (p *prefixCounters) counter(namespace string) *hitCounter {
+	if counter, found := p.counters.Load(namespace); found {
+		return counter.(*hitCounter)
+	}
+
+	counter, _ := p.counters.LoadOrStore(namespace, &hitCounter{})
+	return counter.(*hitCounter)
+}
+
+// PrefixStats возвращает попадания и промахи Get по пространствам имен, которые выделяет
+// функция из WithPrefixStats. В CacheStats заполнены только Hits и Misses.
+// Без WithPrefixStats возвращает nil
+func (c *InMemoryCache) PrefixStats() map[string]CacheStats {
+	if c.prefixStats == nil {
+		return nil
+	}
+
+	stats := make(map[string]CacheStats)
+	c.prefixCounters.addTo(stats)
+
+	return stats
+}
+
+// addTo добавляет счетчики к stats по пространствам имен
+func (p *prefixCounters) addTo(stats map[string]CacheStats) {
+	p.counters.Range(func(namespace, counter interface{}) bool {
+		s := stats[namespace.(string)]
+		s.Hits += counter.(*hitCounter).hits.Load()
+		s.Misses += counter.(*hitCounter).misses.Load()
+		stats[namespace.(string)] = s
+
+		return true
+	})
+}
+
+// countLookup учитывает чтение key в Stats и при WithPrefixStats - в счетчиках его пространства имен
+func (c *InMemoryCache) countLookup(key string, hit bool) {
+	c.lookups.add(hit)
+
+	if c.prefixStats != nil {
+		c.prefixCounters.counter(c.namespace(key)).add(hit)
+	}
+}
+
+func (h *hitCounter) add(hit bool) {
+	if hit {
+		h.hits.Add(1)
+	} else {
+		h.misses.Add(1)
+	}
+}
+
+// namespace вызывает функцию из WithPrefixStats, при панике ключ относится к пустому пространству имен
+func (c *InMemoryCache) namespace(key string) (namespace string) {
+	defer c.recoverPanic()
+
+	return c.prefixStats(key)
+}
@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"sort"
+	"time"
+)
+
+// prefixDefaults - время жизни по-умолчанию для префиксов ключей из WithPrefixDefault
+type prefixDefaults struct {
+	ttls map[string]time.Duration
+	// lengths - различные длины префиксов по убыванию, поиск проверяет по одному префиксу каждой длины
+	lengths []int
+}
+
+func (p *prefixDefaults) add(prefix string, ttl time.Duration) {
+	if p.ttls == nil {
+		p.ttls = make(map[string]time.Duration)
+	}
+
+	if _, found := p.ttls[prefix]; !found {
+		i := sort.Search(len(p.lengths), func(i int) bool { return p.lengths[i] <= len(prefix) })
+		if i == len(p.lengths) || p.lengths[i] != len(prefix) {
+			p.lengths = append(p.lengths, 0)
+			copy(p.lengths[i+1:], p.lengths[i:])
+			p.lengths[i] = len(prefix)
+		}
+	}
+
+	p.ttls[prefix] = ttl
+}
+
+// lookup возвращает время жизни самого длинного префикса key. Выполняет не больше
+// одного поиска в map на каждую различную длину префикса
+func (p *prefixDefaults) lookup(key string) (time.Duration, bool) {
+	if p == nil {
+		return 0, false
+	}
+
+	for _, l := range p.lengths {
+		if l > len(key) {
+			continue
+		}
+
+		if ttl, found := p.ttls[key[:l]]; found {
+			return ttl, true
+		}
+	}
+
+	return 0, false
+}
+
+// defaultTTL возвращает время жизни по-умолчанию для ключа: по самому длинному префиксу
+// из WithPrefixDefault или общее время жизни кеша
+func (c *InMemoryCache) defaultTTL(key string) time.Duration {
+	if ttl, found := c.prefixDefaults.lookup(key); found {
+		return ttl
+	}
+
+	return time.Duration(c.defaultExpiration.Load())
+}
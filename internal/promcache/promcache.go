@@ -0,0 +1,106 @@
+// Package promcache отдает статистику кеша как prometheus.Collector.
+// Основной пакет кеша при этом не зависит от Prometheus
+package promcache
+
+import (
+	"InMemoryCache/internal"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Source реализуют кеши со статистикой: *internal.InMemoryCache и *internal.ShardedCache
+type Source interface {
+	Stats() internal.CacheStats
+}
+
+// Collector собирает метрики кеша из internal.CacheStats при каждом опросе
+type Collector struct {
+	source  Source
+	metrics []metric
+}
+
+type metric struct {
+	desc  *prometheus.Desc
+	kind  prometheus.ValueType
+	value func(s internal.CacheStats) float64
+}
+
+type options struct {
+	namespace string
+	labels    prometheus.Labels
+}
+
+// Option настраивает Collector
+type Option func(*options)
+
+// WithNamespace задает префикс имен метрик, по-умолчанию "cache"
+func WithNamespace(namespace string) Option {
+	return func(o *options) {
+		o.namespace = namespace
+	}
+}
+
+// WithCacheName добавляет ко всем метрикам метку cache="name",
+// чтобы различать несколько кешей одного процесса
+func WithCacheName(name string) Option {
+	return func(o *options) {
+		o.labels = prometheus.Labels{"cache": name}
+	}
+}
+
+// NewCollector создает Collector для кеша source, его регистрируют через prometheus.MustRegister
+func NewCollector(source Source, opts ...Option) *Collector {
+	o := options{namespace: "cache"}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(o.namespace, "", name), help, nil, o.labels)
+	}
+
+	return &Collector{
+		source: source,
+		metrics: []metric{
+			{desc("items", "Items stored, including expired ones not yet collected."), prometheus.GaugeValue,
+				func(s internal.CacheStats) float64 { return float64(s.Items) }},
+			{desc("key_bytes", "Estimated size of stored keys in bytes."), prometheus.GaugeValue,
+				func(s internal.CacheStats) float64 { return float64(s.KeyBytes) }},
+			{desc("value_bytes", "Estimated size of stored values in bytes."), prometheus.GaugeValue,
+				func(s internal.CacheStats) float64 { return float64(s.ValueBytes) }},
+			{desc("hits_total", "Reads that found a live item."), prometheus.CounterValue,
+				func(s internal.CacheStats) float64 { return float64(s.Hits) }},
+			{desc("misses_total", "Reads that found no live item."), prometheus.CounterValue,
+				func(s internal.CacheStats) float64 { return float64(s.Misses) }},
+			{desc("evictions_total", "Items evicted from a full cache."), prometheus.CounterValue,
+				func(s internal.CacheStats) float64 { return float64(s.Evictions) }},
+			{desc("expired_total", "Items removed after expiration."), prometheus.CounterValue,
+				func(s internal.CacheStats) float64 { return float64(s.Expired) }},
+			{desc("gc_runs_total", "Completed GC sweeps."), prometheus.CounterValue,
+				func(s internal.CacheStats) float64 { return float64(s.TotalGCRuns) }},
+			{desc("gc_last_duration_seconds", "Duration of the last GC sweep."), prometheus.GaugeValue,
+				func(s internal.CacheStats) float64 { return s.LastGCDuration.Seconds() }},
+			{desc("fetch_loads_total", "Loader calls made by Fetch."), prometheus.CounterValue,
+				func(s internal.CacheStats) float64 { return float64(s.FetchLoads) }},
+			{desc("fetch_load_seconds_total", "Total time spent in Fetch loaders."), prometheus.CounterValue,
+				func(s internal.CacheStats) float64 { return s.FetchLoadTime.Seconds() }},
+		},
+	}
+}
+
+// Describe передает описания всех метрик
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range c.metrics {
+		ch <- m.desc
+	}
+}
+
+// Collect читает Stats один раз и передает текущие значения всех метрик
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.source.Stats()
+
+	for _, m := range c.metrics {
+		ch <- prometheus.MustNewConstMetric(m.desc, m.kind, m.value(stats))
+	}
+}
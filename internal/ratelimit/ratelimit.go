@@ -0,0 +1,66 @@
+// Package ratelimit ограничивает частоту запросов по ключу, например по адресу клиента,
+// на счетчиках кеша: каждому ключу соответствует счетчик, который живет одно окно
+package ratelimit
+
+import "time"
+
+// Counter реализуют кеши со счетчиками: *internal.InMemoryCache и *internal.ShardedCache
+type Counter interface {
+	IncrementWithTTL(key string, delta int64, ttl time.Duration) (int64, error)
+}
+
+// Limiter пропускает не больше limit запросов по ключу за окно window.
+// Окно фиксированное: оно начинается с первого запроса и сбрасывается по истечению счетчика,
+// поэтому на границе окон возможен всплеск до 2*limit запросов
+type Limiter struct {
+	counter Counter
+	prefix  string
+}
+
+type options struct {
+	prefix string
+}
+
+// Option настраивает Limiter
+type Option func(*options)
+
+// WithPrefix задает префикс ключей счетчиков в кеше, по-умолчанию "ratelimit:"
+func WithPrefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// New создает Limiter на счетчиках кеша counter
+func New(counter Counter, opts ...Option) *Limiter {
+	o := options{prefix: "ratelimit:"}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Limiter{counter: counter, prefix: o.prefix}
+}
+
+// Allow учитывает запрос по ключу key и сообщает, укладывается ли он в limit запросов за window.
+// Ошибка кеша считается отказом, см. Take. Для одного ключа нужно использовать одно и то же окно
+func (l *Limiter) Allow(key string, limit int, window time.Duration) bool {
+	allowed, _ := l.Take(key, limit, window)
+
+	return allowed
+}
+
+// Take как Allow, но возвращает ошибку кеша, например internal.ErrCacheFull,
+// чтобы вызывающий сам решил, пропускать ли запрос
+func (l *Limiter) Take(key string, limit int, window time.Duration) (allowed bool, err error) {
+	if limit <= 0 || window <= 0 {
+		return false, nil
+	}
+
+	n, err := l.counter.IncrementWithTTL(l.prefix+key, 1, window)
+	if err != nil {
+		return false, err
+	}
+
+	return n <= int64(limit), nil
+}
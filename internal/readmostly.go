@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"InMemoryCache/internal/clock"
+)
+
+// ReadMostlyCache - кеш для нагрузки, где чтений 95% и больше: элементы хранятся в sync.Map,
+// и Get не захватывает общих блокировок. Запись дороже, чем в InMemoryCache, а из опций
+// учитываются только время жизни по-умолчанию, интервал GC и WithClock.
+// Создается через New с WithReadMostly
+type ReadMostlyCache struct {
+	items             sync.Map
+	defaultExpiration time.Duration
+	clock             clock.Clock
+	done              chan struct{}
+	closeOnce         sync.Once
+	closed            atomic.Bool
+}
+
+// readMostlyItem - неизменяемый элемент ReadMostlyCache, запись заменяет его целиком
+type readMostlyItem struct {
+	value      interface{}
+	expiration int64
+}
+
+// WithReadMostly выбирает в New реализацию ReadMostlyCache с чтением без блокировок
+// вместо InMemoryCache. Остальные опции, кроме WithDefaultExpiration, WithCleanupInterval
+// и WithClock, при этом не действуют
+func WithReadMostly() Option {
+	return func(o *options) {
+		o.readMostly = true
+	}
+}
+
+func newReadMostlyCache(o options) *ReadMostlyCache {
+	c := &ReadMostlyCache{
+		defaultExpiration: o.defaultExpiration,
+		clock:             o.timeSource(),
+		done:              make(chan struct{}),
+	}
+
+	if o.cleanupInterval > 0 {
+		go c.gc(o.cleanupInterval)
+	}
+
+	return c
+}
+
+func (c *ReadMostlyCache) Get(key string) (interface{}, bool) {
+	v, found := c.items.Load(key)
+	if !found {
+		return nil, false
+	}
+
+	item := v.(*readMostlyItem)
+	if c.expired(item) {
+		return nil, false
+	}
+
+	return item.value, true
+}
+
+func (c *ReadMostlyCache) Has(key string) bool {
+	_, found := c.Get(key)
+	return found
+}
+
+// Count возвращает количество элементов, включая просроченные, но еще не удаленные GC.
+// Обходит все элементы - O(n)
+func (c *ReadMostlyCache) Count() int {
+	n := 0
+	c.items.Range(func(_, _ interface{}) bool {
+		n++
+		return true
+	})
+
+	return n
+}
+
+// Set записывает значение, продолжительности DefaultExpiration, 0 и NoExpiration работают как в InMemoryCache.
+// После Close запись игнорируется
+func (c *ReadMostlyCache) Set(key string, value interface{}, duration time.Duration) {
+	_ = c.SetE(key, value, duration)
+}
+
+// SetE записывает значение как Set, после Close возвращает ErrClosed
+func (c *ReadMostlyCache) SetE(key string, value interface{}, duration time.Duration) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+
+	if duration == DefaultExpiration || duration == 0 {
+		duration = c.defaultExpiration
+	}
+
+	c.items.Store(key, &readMostlyItem{value: value, expiration: expirationFor(c.nowNano(), duration)})
+
+	return nil
+}
+
+func (c *ReadMostlyCache) Delete(key string) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+
+	if _, found := c.items.LoadAndDelete(key); !found {
+		return fmt.Errorf("key '%s': %w", key, ErrNotFound)
+	}
+
+	return nil
+}
+
+// Flush удаляет все элементы, после Close не действует
+func (c *ReadMostlyCache) Flush() {
+	if c.closed.Load() {
+		return
+	}
+
+	c.items.Clear()
+}
+
+// Close останавливает GC, после него записи отклоняются с ErrClosed
+func (c *ReadMostlyCache) Close() error {
+	c.closeOnce.Do(func() {
+		c.closed.Store(true)
+		close(c.done)
+	})
+
+	return nil
+}
+
+// gc периодически удаляет просроченные элементы. Элемент, перезаписанный во время обхода,
+// не удаляется: CompareAndDelete сравнивает его с просроченным
+func (c *ReadMostlyCache) gc(interval time.Duration) {
+	ticker := c.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C():
+		}
+
+		c.items.Range(func(k, v interface{}) bool {
+			if c.expired(v.(*readMostlyItem)) {
+				c.items.CompareAndDelete(k, v)
+			}
+			return true
+		})
+	}
+}
+
+func (c *ReadMostlyCache) expired(item *readMostlyItem) bool {
+	return item.expiration > 0 && c.nowNano() > item.expiration
+}
+
+func (c *ReadMostlyCache) nowNano() int64 {
+	return c.clock.Now().UnixNano()
+}
@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func TestReadMostlyCacheClosed(t *testing.T) {
+	c := New(WithReadMostly()).(*ReadMostlyCache)
+	c.Set("kept", 1, NoExpiration)
+	c.Close()
+
+	if err := c.SetE("key", 1, NoExpiration); !errors.Is(err, ErrClosed) {
+		t.Errorf("SetE err = %v, want %v", err, ErrClosed)
+	}
+	c.Set("key", 1, NoExpiration)
+	if c.Has("key") {
+		t.Error("Set after Close stored the value")
+	}
+
+	if err := c.Delete("kept"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Delete err = %v, want %v", err, ErrClosed)
+	}
+	c.Flush()
+	if !c.Has("kept") {
+		t.Error("Flush after Close removed items")
+	}
+}
+
+// BenchmarkReadMostly сравнивает реализации кеша на параллельной нагрузке с разной долей чтений:
+//
+//	go test ./internal -run '^$' -bench ReadMostly
+func BenchmarkReadMostly(b *testing.B) {
+	variants := []struct {
+		name string
+		opts []Option
+	}{
+		{"RWMutex", nil},
+		{"LockStriping(16)", []Option{WithLockStriping(16)}},
+		{"ReadMostly", []Option{WithReadMostly()}},
+	}
+
+	names := make([]string, 10000)
+	for i := range names {
+		names[i] = fmt.Sprint("key", i)
+	}
+
+	for _, reads := range []float64{0.95, 0.5} {
+		for _, v := range variants {
+			b.Run(fmt.Sprintf("reads=%.2f/%s", reads, v.name), func(b *testing.B) {
+				cache := New(append([]Option{WithDefaultExpiration(time.Hour)}, v.opts...)...)
+				defer cache.Close()
+
+				for _, k := range names {
+					cache.Set(k, k, DefaultExpiration)
+				}
+
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					r := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+					for pb.Next() {
+						k := names[r.IntN(len(names))]
+						if r.Float64() < reads {
+							cache.Get(k)
+						} else {
+							cache.Set(k, k, DefaultExpiration)
+						}
+					}
+				})
+			})
+		}
+	}
+}
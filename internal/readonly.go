@@ -0,0 +1,30 @@
+package internal
+
+// ReadOnlyCache - представление кеша только для чтения
+type ReadOnlyCache interface {
+	Get(key string) (interface{}, bool)
+	Has(key string) bool
+	Count() int
+}
+
+type readOnlyCache struct {
+	cache Cache
+}
+
+// ReadOnly возвращает представление кеша c без методов записи. Обертка не дает
+// привести результат обратно к Cache и изменить кеш
+func ReadOnly(c Cache) ReadOnlyCache {
+	return readOnlyCache{cache: c}
+}
+
+func (r readOnlyCache) Get(key string) (interface{}, bool) {
+	return r.cache.Get(key)
+}
+
+func (r readOnlyCache) Has(key string) bool {
+	return r.cache.Has(key)
+}
+
+func (r readOnlyCache) Count() int {
+	return r.cache.Count()
+}
@@ -0,0 +1,82 @@
+// Package redisbus - шина инвалидации кеша поверх Redis pub/sub для internal.WithInvalidationBus.
+// Основной пакет кеша при этом не зависит от клиента Redis
+package redisbus
+
+import (
+	"context"
+	"encoding/json"
+
+	"InMemoryCache/internal"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultChannel - канал Redis по-умолчанию
+const DefaultChannel = "cache:invalidation"
+
+// Bus публикует сообщения инвалидации в канал Redis и получает их оттуда
+type Bus struct {
+	client  redis.UniversalClient
+	channel string
+}
+
+// Option настраивает Bus
+type Option func(*Bus)
+
+// WithChannel задает канал Redis, например чтобы разделить несколько групп кешей
+func WithChannel(channel string) Option {
+	return func(b *Bus) {
+		b.channel = channel
+	}
+}
+
+// New создает шину поверх клиента Redis client
+func New(client redis.UniversalClient, opts ...Option) *Bus {
+	b := &Bus{client: client, channel: DefaultChannel}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+// Publish отправляет сообщение в канал в формате JSON
+func (b *Bus) Publish(ctx context.Context, msg internal.Invalidation) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	return b.client.Publish(ctx, b.channel, data).Err()
+}
+
+// Subscribe подписывается на канал и вызывает handler для каждого сообщения в отдельной горутине.
+// Клиент Redis сам переподключается при обрыве, сообщения за время обрыва теряются.
+// Сообщения, которые не удалось разобрать, пропускаются
+func (b *Bus) Subscribe(handler func(msg internal.Invalidation)) (func(), error) {
+	sub := b.client.Subscribe(context.Background(), b.channel)
+
+	// Дожидаемся подтверждения подписки, чтобы сообщить об ошибке подключения сразу
+	if _, err := sub.Receive(context.Background()); err != nil {
+		sub.Close()
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		for m := range sub.Channel() {
+			var msg internal.Invalidation
+			if json.Unmarshal([]byte(m.Payload), &msg) == nil {
+				handler(msg)
+			}
+		}
+	}()
+
+	return func() {
+		sub.Close()
+		<-done
+	}, nil
+}
@@ -0,0 +1,64 @@
+package internal
+
+import "time"
+
+// WithRefreshAhead включает упреждающее обновление: Get элемента, прожившего долю fraction
+// своего времени жизни (например 0.8), в фоне загружает новое значение loader и записывает его
+// на тот же срок. Часто читаемые ключи обновляются до истечения и не дают промахов.
+// Одновременно по ключу идет не больше одной загрузки. При ошибке или панике loader
+// прежнее значение остается до своего истечения, ошибка передается в Logger.
+// Касается только элементов со сроком жизни; fraction должна быть в (0, 1)
+func WithRefreshAhead(fraction float64, loader func(key string) (interface{}, error)) Option {
+	return func(o *options) {
+		o.refreshAhead = fraction
+		o.refreshLoader = loader
+	}
+}
+
+// refreshIfDue запускает фоновую загрузку key, если прочитанный item пора обновлять,
+// вызывается без блокировки
+func (c *InMemoryCache) refreshIfDue(key string, item Item) {
+	if item.ttl <= 0 || c.closed.Load() {
+		return
+	}
+
+	if c.nowNano() < item.expiration-int64(float64(item.ttl)*(1-c.refreshAhead)) {
+		return
+	}
+
+	c.reload(key, item, c.refreshLoader)
+}
+
+// reload в фоне загружает новое значение key через loader и записывает его на срок item,
+// если элемент не перезаписан за время загрузки. Одновременно по ключу идет не больше одной загрузки
+func (c *InMemoryCache) reload(key string, item Item, loader func(key string) (interface{}, error)) {
+	if _, busy := c.refreshing.LoadOrStore(key, struct{}{}); busy {
+		return
+	}
+
+	go func() {
+		defer c.refreshing.Delete(key)
+
+		value, _, err := c.callCompute(func() (interface{}, time.Duration, error) {
+			value, err := loader(key)
+			return value, item.ttl, err
+		})
+		if err != nil {
+			c.logger.Warn("cache refresh failed", "key", key, "error", err)
+			return
+		}
+
+		fresh := c.newItem(key, value, item.ttl)
+
+		c.rmu.Lock()
+		defer c.unlock()
+
+		// Пока шла загрузка, ключ могли перезаписать или удалить
+		current, found := c.cache[key]
+		if !found || !current.createdAt.Equal(item.createdAt) || c.closed.Load() {
+			return
+		}
+
+		c.storeItem(key, fresh)
+	}()
+}
@@ -0,0 +1,47 @@
+package internal
+
+import "time"
+
+// Rotate атомарно заменяет все содержимое кеша элементами newItems со временем жизни duration.
+// Читатели видят либо старое, либо новое содержимое целиком, без промежутка с пустым кешем.
+// Для всех старых элементов вызываются обработчики удаления с причиной ReasonRotated.
+// Отклоненные ключи и элементы (см. SetE) пропускаются, возвращается первая такая ошибка.
+// После Close кеш не меняется и возвращается ErrClosed
+func (c *InMemoryCache) Rotate(newItems map[string]interface{}, duration time.Duration) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+
+	var first error
+	items := make(map[string]Item, len(newItems))
+	for k, v := range newItems {
+		if err := c.checkKey(k); err != nil {
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+
+		items[k] = c.newItem(k, v, duration)
+	}
+
+	c.rmu.Lock()
+	defer c.unlock()
+
+	// Close мог успеть до захвата блокировки
+	if c.closed.Load() {
+		return ErrClosed
+	}
+
+	for k := range c.cache {
+		c.evict(k, ReasonRotated)
+	}
+
+	for k, i := range items {
+		if err := c.storeItem(k, i); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
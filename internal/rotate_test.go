@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRotateReplacesContents(t *testing.T) {
+	c := NewInMemoryCache(0, 0).(*InMemoryCache)
+	defer c.Close()
+
+	var rotated []string
+	c.OnEvicted(func(key string, _ interface{}, reason EvictionReason) {
+		if reason == ReasonRotated {
+			rotated = append(rotated, key)
+		}
+	})
+
+	c.Set("old", 1, NoExpiration)
+	if err := c.Rotate(map[string]interface{}{"new": 2}, NoExpiration); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+
+	if c.Has("old") || !c.Has("new") {
+		t.Errorf("keys after Rotate = %v, want [new]", c.Keys())
+	}
+	if len(rotated) != 1 || rotated[0] != "old" {
+		t.Errorf("rotated = %v, want [old]", rotated)
+	}
+}
+
+func TestRotateAfterCloseKeepsContents(t *testing.T) {
+	c := NewInMemoryCache(0, 0).(*InMemoryCache)
+	c.Set("old", 1, NoExpiration)
+	c.Close()
+
+	if err := c.Rotate(map[string]interface{}{"new": 2}, NoExpiration); !errors.Is(err, ErrClosed) {
+		t.Errorf("Rotate err = %v, want %v", err, ErrClosed)
+	}
+	if !c.Has("old") {
+		t.Error("Rotate after Close removed existing items")
+	}
+}
+
+func TestRotateReportsRejectedItems(t *testing.T) {
+	c := NewInMemoryCache(0, 0, WithMaxEntries(1), WithEvictionPolicy(PolicyReject), WithMaxKeyLength(8)).(*InMemoryCache)
+	defer c.Close()
+
+	err := c.Rotate(map[string]interface{}{"a": 1, "b": 2}, NoExpiration)
+	if !errors.Is(err, ErrCacheFull) {
+		t.Errorf("Rotate err = %v, want %v", err, ErrCacheFull)
+	}
+	if c.Count() != 1 {
+		t.Errorf("Count = %d, want 1", c.Count())
+	}
+
+	if err := c.Rotate(map[string]interface{}{"too-long-key": 1}, NoExpiration); !errors.Is(err, ErrKeyTooLong) {
+		t.Errorf("Rotate err = %v, want %v", err, ErrKeyTooLong)
+	}
+}
@@ -0,0 +1,278 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"InMemoryCache/internal"
+)
+
+const (
+	errSyntax     = "ERR syntax error"
+	errNotInteger = "ERR value is not an integer or out of range"
+)
+
+// exec выполняет команду args и записывает ответ в w. Возвращает true для QUIT
+func (s *Server) exec(w writer, args []string) (quit bool) {
+	name := strings.ToLower(args[0])
+	args = args[1:]
+
+	want, known := arities[name]
+	switch {
+	case !known:
+		w.error(fmt.Sprintf("ERR unknown command '%s'", truncate(name)))
+		return false
+	case len(args) < want.min, want.max >= 0 && len(args) > want.max:
+		w.error(fmt.Sprintf("ERR wrong number of arguments for '%s' command", name))
+		return false
+	}
+
+	switch name {
+	case "ping":
+		if len(args) == 1 {
+			w.bulk(args[0])
+		} else {
+			w.simple("PONG")
+		}
+	case "echo":
+		w.bulk(args[0])
+	case "quit":
+		w.simple("OK")
+		return true
+	case "select":
+		if args[0] != "0" {
+			w.error("ERR DB index is out of range")
+			return false
+		}
+		w.simple("OK")
+	case "command":
+		// redis-cli запрашивает описание команд при подключении
+		w.array(0)
+	case "get":
+		s.get(w, args[0])
+	case "set":
+		s.set(w, args)
+	case "del":
+		deleted := 0
+		for _, key := range args {
+			if s.store.Delete(key) == nil {
+				deleted++
+			}
+		}
+		w.integer(int64(deleted))
+	case "exists":
+		found := 0
+		for _, key := range args {
+			if s.store.Has(key) {
+				found++
+			}
+		}
+		w.integer(int64(found))
+	case "expire", "pexpire":
+		s.expire(w, name, args, unitFor(name))
+	case "ttl", "pttl":
+		s.ttl(w, args[0], unitFor(name))
+	case "incr":
+		s.increment(w, args[0], "1", false)
+	case "decr":
+		s.increment(w, args[0], "1", true)
+	case "incrby":
+		s.increment(w, args[0], args[1], false)
+	case "decrby":
+		s.increment(w, args[0], args[1], true)
+	case "dbsize":
+		w.integer(int64(s.store.Len()))
+	case "flushall", "flushdb":
+		s.store.Flush()
+		w.simple("OK")
+	}
+
+	return false
+}
+
+// arity - допустимое число аргументов команды без ее имени, max < 0 - без ограничения
+type arity struct {
+	min, max int
+}
+
+var arities = map[string]arity{
+	"ping": {0, 1}, "echo": {1, 1}, "quit": {0, 0}, "select": {1, 1}, "command": {0, -1},
+	"get": {1, 1}, "set": {2, -1}, "del": {1, -1}, "exists": {1, -1},
+	"expire": {2, 2}, "pexpire": {2, 2}, "ttl": {1, 1}, "pttl": {1, 1},
+	"incr": {1, 1}, "decr": {1, 1}, "incrby": {2, 2}, "decrby": {2, 2},
+	// FLUSHALL ASYNC и SYNC выполняются одинаково
+	"dbsize": {0, 0}, "flushall": {0, 1}, "flushdb": {0, 1},
+}
+
+// truncate обрезает имя неизвестной команды для сообщения об ошибке
+func truncate(name string) string {
+	if len(name) > 128 {
+		return name[:128]
+	}
+
+	return name
+}
+
+// unitFor возвращает единицу времени команды: миллисекунды для PEXPIRE и PTTL, иначе секунды
+func unitFor(name string) time.Duration {
+	if strings.HasPrefix(name, "p") {
+		return time.Millisecond
+	}
+
+	return time.Second
+}
+
+func (s *Server) get(w writer, key string) {
+	value, found := s.store.Get(key)
+	if !found {
+		w.null()
+		return
+	}
+
+	w.bulk(format(value))
+}
+
+// set выполняет SET key value [EX seconds | PX milliseconds]
+func (s *Server) set(w writer, args []string) {
+	ttl := internal.DefaultExpiration
+
+	options := args[2:]
+	for len(options) > 0 {
+		if len(options) < 2 || ttl != internal.DefaultExpiration {
+			w.error(errSyntax)
+			return
+		}
+
+		n, err := strconv.ParseInt(options[1], 10, 64)
+		if err != nil || n <= 0 {
+			w.error("ERR invalid expire time in 'set' command")
+			return
+		}
+
+		var unit time.Duration
+		switch strings.ToLower(options[0]) {
+		case "ex":
+			unit = time.Second
+		case "px":
+			unit = time.Millisecond
+		default:
+			w.error(errSyntax)
+			return
+		}
+
+		var ok bool
+		if ttl, ok = expireDuration(n, unit); !ok {
+			w.error("ERR invalid expire time in 'set' command")
+			return
+		}
+
+		options = options[2:]
+	}
+
+	if err := s.store.SetE(args[0], parse(args[1]), ttl); err != nil {
+		w.error("ERR " + err.Error())
+		return
+	}
+	w.simple("OK")
+}
+
+// expire выполняет EXPIRE и PEXPIRE, неположительный срок удаляет ключ
+func (s *Server) expire(w writer, name string, args []string, unit time.Duration) {
+	n, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		w.error(errNotInteger)
+		return
+	}
+
+	// Ноль и отрицательный срок не должны совпасть с NoExpiration
+	ttl := time.Duration(0)
+	if n > 0 {
+		var ok bool
+		if ttl, ok = expireDuration(n, unit); !ok {
+			w.error("ERR invalid expire time in '" + name + "' command")
+			return
+		}
+	}
+
+	if s.store.Expire(args[0], ttl) {
+		w.integer(1)
+	} else {
+		w.integer(0)
+	}
+}
+
+// expireDuration переводит положительный срок n в единицах unit в time.Duration,
+// ok = false, если он не помещается в time.Duration
+func expireDuration(n int64, unit time.Duration) (time.Duration, bool) {
+	if n > math.MaxInt64/int64(unit) {
+		return 0, false
+	}
+
+	return time.Duration(n) * unit, true
+}
+
+// ttl выполняет TTL и PTTL: -2 для отсутствующего ключа, -1 для бессрочного
+func (s *Server) ttl(w writer, key string, unit time.Duration) {
+	entry, found := s.store.Inspect(key)
+	switch {
+	case !found || entry.Expired:
+		w.integer(-2)
+	case entry.RemainingTTL < 0:
+		w.integer(-1)
+	default:
+		// Округляем, как Redis
+		w.integer(int64((entry.RemainingTTL + unit/2) / unit))
+	}
+}
+
+func (s *Server) increment(w writer, key, by string, negate bool) {
+	delta, err := strconv.ParseInt(by, 10, 64)
+	if err != nil {
+		w.error(errNotInteger)
+		return
+	}
+
+	if negate {
+		delta = -delta
+	}
+
+	n, err := s.store.Increment(key, delta)
+	switch {
+	case errors.Is(err, internal.ErrTypeMismatch):
+		w.error(errNotInteger)
+	case err != nil:
+		w.error("ERR " + err.Error())
+	default:
+		w.integer(n)
+	}
+}
+
+// parse сохраняет целое число в каноничной записи как int64, чтобы с ним работали INCR и DECR,
+// остальные значения - строками. GET отдает число в той же записи, поэтому значение не меняется
+func parse(value string) interface{} {
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil && strconv.FormatInt(n, 10) == value {
+		return n
+	}
+
+	return value
+}
+
+// format возвращает значение кеша строкой для ответа GET
+func format(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case int:
+		return strconv.Itoa(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
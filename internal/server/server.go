@@ -0,0 +1,189 @@
+// Package server отдает кеш по протоколу Redis (RESP2), чтобы обычные клиенты Redis
+// могли работать с ним при локальной разработке. Поддерживаются GET, SET (с EX и PX), DEL, EXISTS,
+// EXPIRE, PEXPIRE, TTL, PTTL, INCR, INCRBY, DECR, DECRBY, DBSIZE, FLUSHALL, FLUSHDB, PING, ECHO,
+// SELECT 0 и QUIT. Базы данных, типы кроме строк, транзакции и подписки не поддерживаются
+package server
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"InMemoryCache/internal"
+)
+
+// ErrServerClosed возвращается из Serve и ListenAndServe после Close
+var ErrServerClosed = errors.New("server: closed")
+
+// Store - кеш, который обслуживает сервер: *internal.InMemoryCache или *internal.ShardedCache
+type Store interface {
+	internal.Cache
+
+	SetE(key string, value interface{}, duration time.Duration) error
+	Len() int
+	Inspect(key string) (internal.CacheEntry, bool)
+	Expire(key string, ttl time.Duration) bool
+	Increment(key string, delta int64) (int64, error)
+}
+
+// Server принимает соединения клиентов Redis и выполняет их команды над кешем
+type Server struct {
+	store Store
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// New создает сервер для кеша store. SET без EX и PX записывает значение
+// со временем жизни кеша по-умолчанию, а не бессрочно, как Redis
+func New(store Store) *Server {
+	return &Server{
+		store:     store,
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]struct{}),
+	}
+}
+
+// ListenAndServe слушает TCP-адрес addr, например ":6379", и обслуживает соединения до Close
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(l)
+}
+
+// Serve обслуживает соединения из l до Close, каждое в своей горутине. l закрывается при возврате
+func (s *Server) Serve(l net.Listener) error {
+	if !s.track(l) {
+		l.Close()
+		return ErrServerClosed
+	}
+	defer s.untrack(l)
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+
+			return err
+		}
+
+		if !s.trackConn(conn) {
+			conn.Close()
+			return ErrServerClosed
+		}
+
+		go s.serveConn(conn)
+	}
+}
+
+// Close закрывает все слушатели и соединения и ждет завершения их обработки. Кеш не закрывается
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+
+	var err error
+	for l := range s.listeners {
+		if closeErr := l.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+
+	return err
+}
+
+// serveConn читает команды соединения и отвечает на них. Ответы на команды,
+// присланные пачкой (pipelining), отправляются вместе после последней из них
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.untrackConn(conn)
+
+	r := bufio.NewReader(conn)
+	w := writer{bufio.NewWriter(conn)}
+
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			if errors.Is(err, errProtocol) {
+				w.error("ERR Protocol error")
+				w.Flush()
+			}
+
+			return
+		}
+
+		quit := len(args) > 0 && s.exec(w, args)
+
+		if r.Buffered() == 0 || quit {
+			if err := w.Flush(); err != nil || quit {
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) track(l net.Listener) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+
+	s.listeners[l] = struct{}{}
+
+	return true
+}
+
+func (s *Server) untrack(l net.Listener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.listeners, l)
+	l.Close()
+}
+
+func (s *Server) trackConn(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+
+	return true
+}
+
+func (s *Server) untrackConn(conn net.Conn) {
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+
+	conn.Close()
+	s.wg.Done()
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.closed
+}
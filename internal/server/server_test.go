@@ -0,0 +1,267 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"InMemoryCache/internal"
+)
+
+// client - соединение с тестовым сервером
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// serve запускает сервер над кешем на случайном порту и подключается к нему
+func serve(t *testing.T, opts ...internal.Option) (*client, *internal.InMemoryCache) {
+	t.Helper()
+
+	c := internal.New(opts...).(*internal.InMemoryCache)
+	t.Cleanup(func() { c.Close() })
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+
+	s := New(c)
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(l) }()
+	t.Cleanup(func() {
+		s.Close()
+		if err := <-done; !errors.Is(err, ErrServerClosed) {
+			t.Errorf("Serve = %v, want %v", err, ErrServerClosed)
+		}
+	})
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &client{t: t, conn: conn, r: bufio.NewReader(conn)}, c
+}
+
+// send отправляет сырые байты запроса
+func (c *client) send(raw string) {
+	c.t.Helper()
+
+	if _, err := c.conn.Write([]byte(raw)); err != nil {
+		c.t.Fatalf("Write: %v", err)
+	}
+}
+
+// reply читает один ответ и возвращает его одной строкой без \r\n,
+// для строки переменной длины - заголовок и содержимое через пробел
+func (c *client) reply() string {
+	c.t.Helper()
+
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	line, err := readLine(c.r)
+	if err != nil {
+		c.t.Fatalf("reading reply: %v", err)
+	}
+
+	if strings.HasPrefix(line, "$") && line != "$-1" {
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			c.t.Fatalf("bulk reply header %q: %v", line, err)
+		}
+
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(c.r, data); err != nil {
+			c.t.Fatalf("reading bulk reply: %v", err)
+		}
+		return line + " " + string(data[:size])
+	}
+
+	return line
+}
+
+// do отправляет inline-команду и возвращает ответ
+func (c *client) do(cmd string) string {
+	c.t.Helper()
+
+	c.send(cmd + "\r\n")
+
+	return c.reply()
+}
+
+func TestCommands(t *testing.T) {
+	cl, _ := serve(t, internal.WithDefaultExpiration(internal.NoExpiration))
+
+	steps := []struct {
+		cmd  string
+		want string
+	}{
+		{"PING", "+PONG"},
+		{"PING hello", "$5 hello"},
+		{"ECHO hi", "$2 hi"},
+		{"SELECT 0", "+OK"},
+		{"SELECT 1", "-ERR DB index is out of range"},
+		{"GET a", "$-1"},
+		{"SET a 10", "+OK"},
+		{"GET a", "$2 10"},
+		{"INCR a", ":11"},
+		{"INCRBY a 5", ":16"},
+		{"DECR a", ":15"},
+		{"DECRBY a 20", ":-5"},
+		{"SET s text", "+OK"},
+		{"INCR s", "-" + errNotInteger},
+		{"INCRBY a x", "-" + errNotInteger},
+		{"EXISTS a s missing", ":2"},
+		{"DBSIZE", ":2"},
+		{"TTL a", ":-1"},
+		{"TTL missing", ":-2"},
+		{"EXPIRE a 100", ":1"},
+		{"TTL a", ":100"},
+		{"EXPIRE missing 100", ":0"},
+		{"EXPIRE a x", "-" + errNotInteger},
+		{"EXPIRE s 0", ":1"},
+		{"EXISTS s", ":0"},
+		{"DEL a s missing", ":1"},
+		{"FLUSHALL", "+OK"},
+		{"DBSIZE", ":0"},
+		{"NOPE", "-ERR unknown command 'nope'"},
+		{"GET", "-ERR wrong number of arguments for 'get' command"},
+		{"DBSIZE extra", "-ERR wrong number of arguments for 'dbsize' command"},
+	}
+
+	for _, step := range steps {
+		if got := cl.do(step.cmd); got != step.want {
+			t.Errorf("%s = %q, want %q", step.cmd, got, step.want)
+		}
+	}
+}
+
+func TestSetExpireBounds(t *testing.T) {
+	cl, _ := serve(t)
+
+	steps := []struct {
+		cmd  string
+		want string
+	}{
+		{"SET a v EX 0", "-ERR invalid expire time in 'set' command"},
+		{"SET a v PX -1", "-ERR invalid expire time in 'set' command"},
+		{"SET a v EX x", "-ERR invalid expire time in 'set' command"},
+		{"SET a v EX 9223372036854775807", "-ERR invalid expire time in 'set' command"},
+		{"SET a v PX 9223372036854775807", "-ERR invalid expire time in 'set' command"},
+		{"SET a v EX 5 PX 5", "-" + errSyntax},
+		{"SET a v EX", "-" + errSyntax},
+		{"SET a v KEEPTTL 1", "-" + errSyntax},
+		{"EXISTS a", ":0"},
+		{"SET a v PX 9223372036854", "+OK"},
+		{"SET b v EX 60", "+OK"},
+		{"TTL b", ":60"},
+		{"EXPIRE b 9223372036854775807", "-ERR invalid expire time in 'expire' command"},
+		{"PEXPIRE b 9223372036854775807", "-ERR invalid expire time in 'pexpire' command"},
+		{"PEXPIRE b 2000", ":1"},
+		{"TTL b", ":2"},
+		{"PEXPIRE b -1", ":1"},
+		{"EXISTS b", ":0"},
+	}
+
+	for _, step := range steps {
+		if got := cl.do(step.cmd); got != step.want {
+			t.Errorf("%s = %q, want %q", step.cmd, got, step.want)
+		}
+	}
+}
+
+func TestTTLUnits(t *testing.T) {
+	cl, _ := serve(t)
+
+	cl.do("SET a v PX 100000")
+
+	if got := cl.do("TTL a"); got != ":100" {
+		t.Errorf("TTL = %q, want :100", got)
+	}
+
+	got := cl.do("PTTL a")
+	if ms, err := strconv.Atoi(strings.TrimPrefix(got, ":")); err != nil || ms <= 99000 || ms > 100000 {
+		t.Errorf("PTTL = %q, want about :100000", got)
+	}
+}
+
+func TestSetReportsStoreErrors(t *testing.T) {
+	cl, _ := serve(t, internal.WithMaxKeyLength(3))
+
+	if got, want := cl.do("SET abcd v"), "-ERR "+internal.ErrKeyTooLong.Error(); got != want {
+		t.Errorf("SET of a long key = %q, want %q", got, want)
+	}
+}
+
+func TestRESPArrayAndPipelining(t *testing.T) {
+	cl, c := serve(t)
+
+	cl.send("*3\r\n$3\r\nSET\r\n$3\r\nk y\r\n$4\r\na\r\nb\r\n" +
+		"*2\r\n$3\r\nGET\r\n$3\r\nk y\r\n" +
+		"\r\n" +
+		"GET missing\r\n")
+
+	// Значение с \r\n внутри передается целиком
+	for _, want := range []string{"+OK", "$4 a\r\nb", "$-1"} {
+		if got := cl.reply(); got != want {
+			t.Errorf("reply = %q, want %q", got, want)
+		}
+	}
+
+	if value, _ := c.Get("k y"); value != "a\r\nb" {
+		t.Errorf("stored value = %q, want %q", value, "a\r\nb")
+	}
+}
+
+func TestProtocolErrorClosesConnection(t *testing.T) {
+	requests := []string{
+		"*x\r\n",
+		"*1\r\nGET\r\n",
+		"*1\r\n$-1\r\n",
+		"*1\r\n$3\r\nGETX\r\n",
+	}
+
+	for _, req := range requests {
+		cl, _ := serve(t)
+		cl.send(req)
+
+		if got := cl.reply(); got != "-ERR Protocol error" {
+			t.Errorf("reply to %q = %q, want protocol error", req, got)
+		}
+		if _, err := cl.r.ReadByte(); err == nil {
+			t.Errorf("connection stays open after %q", req)
+		}
+	}
+}
+
+func TestQuitClosesConnection(t *testing.T) {
+	cl, _ := serve(t)
+
+	if got := cl.do("QUIT"); got != "+OK" {
+		t.Fatalf("QUIT = %q, want +OK", got)
+	}
+	if _, err := cl.r.ReadByte(); err == nil {
+		t.Error("connection stays open after QUIT")
+	}
+}
+
+func TestServeAfterClose(t *testing.T) {
+	s := New(internal.New().(*internal.InMemoryCache))
+	s.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+
+	if err := s.Serve(l); !errors.Is(err, ErrServerClosed) {
+		t.Errorf("Serve after Close = %v, want %v", err, ErrServerClosed)
+	}
+}
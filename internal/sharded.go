@@ -0,0 +1,911 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"InMemoryCache/internal/hashring"
+)
+
+// ShardedCache - кеш, разделенный на сегменты с независимыми блокировками.
+// Ключи распределяются по сегментам консистентным хешированием, поэтому AddShard
+// и RemoveShard переносят лишь небольшую долю ключей
+type ShardedCache struct {
+	// mu защищает состав сегментов: операции с ключами держат ее на чтение,
+	// изменение количества сегментов - на запись
+	mu              sync.RWMutex
+	shards          []*InMemoryCache
+	names           []string
+	byName          map[string]*InMemoryCache
+	ring            *hashring.Ring
+	nextShard       int
+	handlers        evictionHandlers
+	options         options
+	cleanupInterval time.Duration
+	done            chan struct{}
+	closeOnce       sync.Once
+	persistPath     string
+	persistCodec    Codec
+	userLocks       keyMutex
+	invalidator     *invalidator
+}
+
+// NewShardedCache создает кеш из shards сегментов с независимыми блокировками, как NewInMemoryCache
+// с WithLockStriping(shards), но возвращает *ShardedCache и при shards < 2 тоже.
+// Опция WithLockStriping в opts не учитывается
+func NewShardedCache(shards int, defaultExpiration, cleanupInterval time.Duration, opts ...Option) *ShardedCache {
+	o := newOptions(opts)
+	o.lockStripes = max(shards, 1)
+
+	return newShardedCache(o, defaultExpiration, cleanupInterval)
+}
+
+func newShardedCache(o options, defaultExpiration, cleanupInterval time.Duration) *ShardedCache {
+	c := &ShardedCache{
+		byName:          make(map[string]*InMemoryCache),
+		ring:            hashring.New(hashring.DefaultReplicas),
+		cleanupInterval: cleanupInterval,
+		done:            make(chan struct{}),
+		persistPath:     o.persistPath,
+		persistCodec:    o.persistCodec,
+	}
+
+	// Сегменты сохраняются одним файлом при закрытии всего кеша
+	o.persistPath = ""
+
+	// На шину инвалидации подписывается весь кеш, а не каждый сегмент
+	bus := o.invalidationBus
+	o.invalidationBus = nil
+
+	// Ожидаемое и предельное количество элементов распределяются по сегментам поровну
+	if o.initialCapacity > 0 {
+		o.initialCapacity = (o.initialCapacity + o.lockStripes - 1) / o.lockStripes
+	}
+
+	if o.maxEntries > 0 {
+		o.maxEntries = (o.maxEntries + o.lockStripes - 1) / o.lockStripes
+	}
+
+	if o.maxBytes > 0 {
+		o.maxBytes = (o.maxBytes + int64(o.lockStripes) - 1) / int64(o.lockStripes)
+	}
+
+	if o.bloomKeys > 0 {
+		o.bloomKeys = (o.bloomKeys + o.lockStripes - 1) / o.lockStripes
+	}
+
+	c.options = o
+	for i := 0; i < o.lockStripes; i++ {
+		c.addShard(defaultExpiration)
+	}
+
+	if bus != nil {
+		c.invalidator = newInvalidator(bus, o.nodeID, o.logger, c.applyInvalidation)
+		for _, s := range c.shards {
+			s.invalidator = c.invalidator
+		}
+	}
+
+	// Один GC на все сегменты вместо отдельной горутины на каждый
+	if cleanupInterval > 0 {
+		go c.GC()
+	}
+
+	if o.memoryWatermark > 0 {
+		go watchMemory(c.done, o, c.currentShards)
+	}
+
+	if o.coarseTick > 0 {
+		go tickClock(c.done, o.timeSource(), o.coarseTick, c.currentShards)
+	}
+
+	return c
+}
+
+func (c *ShardedCache) Get(key string) (interface{}, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.shard(key).Get(key)
+}
+
+// GetE - см. InMemoryCache.GetE
+func (c *ShardedCache) GetE(key string) (interface{}, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.shard(key).GetE(key)
+}
+
+// GetWithExpiration - см. InMemoryCache.GetWithExpiration
+func (c *ShardedCache) GetWithExpiration(key string) (interface{}, time.Time, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.shard(key).GetWithExpiration(key)
+}
+
+func (c *ShardedCache) Has(key string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.shard(key).Has(key)
+}
+
+func (c *ShardedCache) Count() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	count := 0
+	for _, s := range c.shards {
+		count += s.Count()
+	}
+
+	return count
+}
+
+func (c *ShardedCache) Set(key string, value interface{}, duration time.Duration) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	c.shard(key).Set(key, value, duration)
+}
+
+// SetE - см. InMemoryCache.SetE
+func (c *ShardedCache) SetE(key string, value interface{}, duration time.Duration) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.shard(key).SetE(key, value, duration)
+}
+
+// SetWithSliding - см. InMemoryCache.SetWithSliding
+func (c *ShardedCache) SetWithSliding(key string, value interface{}, ttl time.Duration) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.shard(key).SetWithSliding(key, value, ttl)
+}
+
+func (c *ShardedCache) Delete(key string) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.shard(key).Delete(key)
+}
+
+// GetOrCompute - см. InMemoryCache.GetOrCompute. Вычисление выполняется без блокировки состава
+// сегментов, чтобы compute могла обращаться к кешу, поэтому значение, вычисленное
+// во время AddShard или RemoveShard, может остаться в прежнем сегменте и будет вычислено заново
+func (c *ShardedCache) GetOrCompute(key string, compute func() (interface{}, time.Duration, error)) (interface{}, error) {
+	c.mu.RLock()
+	s := c.shard(key)
+	c.mu.RUnlock()
+
+	return s.GetOrCompute(key, compute)
+}
+
+// OnEvicted - см. InMemoryCache.OnEvicted, обработчик получают все сегменты, включая добавленные позже
+func (c *ShardedCache) OnEvicted(fn func(key string, value interface{}, reason EvictionReason)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.handlers.onEvicted = fn
+	for _, s := range c.shards {
+		s.OnEvicted(fn)
+	}
+}
+
+// OnEvictedBatch - см. InMemoryCache.OnEvictedBatch. Пакет содержит элементы одного сегмента,
+// поэтому операция над несколькими сегментами вызывает обработчик для каждого из них
+func (c *ShardedCache) OnEvictedBatch(fn func(items []EvictedItem)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.handlers.onEvictedBatch = fn
+	for _, s := range c.shards {
+		s.OnEvictedBatch(fn)
+	}
+}
+
+// Fetch - см. InMemoryCache.Fetch. Как и GetOrCompute, загружает значение без блокировки
+// состава сегментов
+func (c *ShardedCache) Fetch(key string, ttl time.Duration, loader func() (interface{}, error)) (interface{}, error) {
+	c.mu.RLock()
+	s := c.shard(key)
+	c.mu.RUnlock()
+
+	return s.Fetch(key, ttl, loader)
+}
+
+// MapValues - см. InMemoryCache.MapValues. Сегменты обрабатываются по очереди,
+// каждый под своей блокировкой
+func (c *ShardedCache) MapValues(fn func(key string, value interface{}) (interface{}, bool)) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, s := range c.shards {
+		s.MapValues(fn)
+	}
+}
+
+// Keys - см. InMemoryCache.Keys
+func (c *ShardedCache) Keys() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var keys []string
+	for _, s := range c.shards {
+		keys = append(keys, s.Keys()...)
+	}
+
+	return keys
+}
+
+// Len - см. InMemoryCache.Len
+func (c *ShardedCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	n := 0
+	for _, s := range c.shards {
+		n += s.Len()
+	}
+
+	return n
+}
+
+// Range - см. InMemoryCache.Range. Сегменты копируются по очереди,
+// поэтому снимок согласован только в пределах сегмента
+func (c *ShardedCache) Range(fn func(key string, value interface{}) bool) {
+	for _, s := range c.currentShards() {
+		stop := false
+		s.Range(func(key string, value interface{}) bool {
+			stop = !fn(key, value)
+			return !stop
+		})
+
+		if stop {
+			return
+		}
+	}
+}
+
+// GetMulti - см. InMemoryCache.GetMulti. Ключи группируются по сегментам,
+// и каждый сегмент блокируется один раз
+func (c *ShardedCache) GetMulti(keys []string) (map[string]interface{}, []string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	found := make(map[string]interface{}, len(keys))
+	var missing []string
+
+	for s, keys := range c.groupKeys(keys) {
+		values, miss := s.GetMulti(keys)
+		for k, v := range values {
+			found[k] = v
+		}
+		missing = append(missing, miss...)
+	}
+
+	return found, missing
+}
+
+// GetMultiWithExpiration - см. InMemoryCache.GetMultiWithExpiration
+func (c *ShardedCache) GetMultiWithExpiration(keys []string) (map[string]ExpiringValue, []string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	found := make(map[string]ExpiringValue, len(keys))
+	var missing []string
+
+	for s, keys := range c.groupKeys(keys) {
+		values, miss := s.GetMultiWithExpiration(keys)
+		for k, v := range values {
+			found[k] = v
+		}
+		missing = append(missing, miss...)
+	}
+
+	return found, missing
+}
+
+// SetMulti - см. InMemoryCache.SetMulti
+func (c *ShardedCache) SetMulti(items map[string]interface{}, duration time.Duration) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	groups := make(map[*InMemoryCache]map[string]interface{})
+	for k, v := range items {
+		s := c.shard(k)
+		if groups[s] == nil {
+			groups[s] = make(map[string]interface{})
+		}
+		groups[s][k] = v
+	}
+
+	var errs []error
+	for s, items := range groups {
+		if err := s.SetMulti(items, duration); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// DeleteMulti - см. InMemoryCache.DeleteMulti
+func (c *ShardedCache) DeleteMulti(keys []string) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	deleted := 0
+	for s, keys := range c.groupKeys(keys) {
+		deleted += s.DeleteMulti(keys)
+	}
+
+	return deleted
+}
+
+// groupKeys раскладывает ключи по сегментам, вызывается под блокировкой
+func (c *ShardedCache) groupKeys(keys []string) map[*InMemoryCache][]string {
+	groups := make(map[*InMemoryCache][]string)
+	for _, k := range keys {
+		s := c.shard(k)
+		groups[s] = append(groups[s], k)
+	}
+
+	return groups
+}
+
+// Inspect - см. InMemoryCache.Inspect
+func (c *ShardedCache) Inspect(key string) (CacheEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.shard(key).Inspect(key)
+}
+
+// TopKeys - см. InMemoryCache.TopKeys, объединяет первые n ключей каждого сегмента
+func (c *ShardedCache) TopKeys(n int) []KeyStat {
+	if n <= 0 || !c.options.hitCounting {
+		return nil
+	}
+
+	var stats []KeyStat
+	for _, s := range c.currentShards() {
+		stats = append(stats, s.TopKeys(n)...)
+	}
+
+	return topKeyStats(stats, n)
+}
+
+// WithLock - см. InMemoryCache.WithLock. Блокировки общие для всех сегментов,
+// поэтому изменение количества сегментов их не затрагивает
+func (c *ShardedCache) WithLock(key string, fn func()) {
+	defer c.userLocks.lock(key)()
+
+	fn()
+}
+
+// LockKey - см. InMemoryCache.LockKey
+func (c *ShardedCache) LockKey(key string) (unlock func()) {
+	return c.userLocks.lock(key)
+}
+
+// GetItem - см. InMemoryCache.GetItem
+func (c *ShardedCache) GetItem(key string) (ItemInfo, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.shard(key).GetItem(key)
+}
+
+// Expire - см. InMemoryCache.Expire
+func (c *ShardedCache) Expire(key string, ttl time.Duration) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.shard(key).Expire(key, ttl)
+}
+
+// Add - см. InMemoryCache.Add
+func (c *ShardedCache) Add(key string, value interface{}, duration time.Duration) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.shard(key).Add(key, value, duration)
+}
+
+// Replace - см. InMemoryCache.Replace
+func (c *ShardedCache) Replace(key string, value interface{}, duration time.Duration) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.shard(key).Replace(key, value, duration)
+}
+
+// CompareAndSwap - см. InMemoryCache.CompareAndSwap
+func (c *ShardedCache) CompareAndSwap(key string, expected, value interface{}, duration time.Duration) (bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.shard(key).CompareAndSwap(key, expected, value, duration)
+}
+
+// KeysWithPrefix - см. InMemoryCache.KeysWithPrefix
+func (c *ShardedCache) KeysWithPrefix(prefix string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var keys []string
+	for _, s := range c.shards {
+		keys = append(keys, s.KeysWithPrefix(prefix)...)
+	}
+
+	return keys
+}
+
+// DeleteByPrefix - см. InMemoryCache.DeleteByPrefix. Сегменты очищаются по очереди
+func (c *ShardedCache) DeleteByPrefix(prefix string) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	deleted := 0
+	for _, s := range c.shards {
+		deleted += s.DeleteByPrefix(prefix)
+	}
+
+	return deleted
+}
+
+// DeleteByPattern - см. InMemoryCache.DeleteByPattern. Сегменты очищаются по очереди
+func (c *ShardedCache) DeleteByPattern(glob string) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	deleted := 0
+	for _, s := range c.shards {
+		deleted += s.DeleteByPattern(glob)
+	}
+
+	return deleted
+}
+
+// Persist - см. InMemoryCache.Persist
+func (c *ShardedCache) Persist(key string) bool {
+	return c.Expire(key, NoExpiration)
+}
+
+// TTL - см. InMemoryCache.TTL
+func (c *ShardedCache) TTL(key string) (time.Duration, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.shard(key).TTL(key)
+}
+
+// Increment - см. InMemoryCache.Increment
+func (c *ShardedCache) Increment(key string, delta int64) (int64, error) {
+	return c.IncrementWithTTL(key, delta, DefaultExpiration)
+}
+
+// Decrement - см. InMemoryCache.Decrement
+func (c *ShardedCache) Decrement(key string, delta int64) (int64, error) {
+	return c.IncrementWithTTL(key, -delta, DefaultExpiration)
+}
+
+// IncrementWithTTL - см. InMemoryCache.IncrementWithTTL
+func (c *ShardedCache) IncrementWithTTL(key string, delta int64, ttl time.Duration) (int64, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.shard(key).IncrementWithTTL(key, delta, ttl)
+}
+
+// LPush - см. InMemoryCache.LPush
+func (c *ShardedCache) LPush(key string, ttl time.Duration, values ...interface{}) (int, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.shard(key).LPush(key, ttl, values...)
+}
+
+// RPush - см. InMemoryCache.RPush
+func (c *ShardedCache) RPush(key string, ttl time.Duration, values ...interface{}) (int, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.shard(key).RPush(key, ttl, values...)
+}
+
+// LPop - см. InMemoryCache.LPop
+func (c *ShardedCache) LPop(key string) (interface{}, bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.shard(key).LPop(key)
+}
+
+// RPop - см. InMemoryCache.RPop
+func (c *ShardedCache) RPop(key string) (interface{}, bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.shard(key).RPop(key)
+}
+
+// LLen - см. InMemoryCache.LLen
+func (c *ShardedCache) LLen(key string) (int, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.shard(key).LLen(key)
+}
+
+// SAdd - см. InMemoryCache.SAdd
+func (c *ShardedCache) SAdd(key string, ttl time.Duration, members ...string) (int, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.shard(key).SAdd(key, ttl, members...)
+}
+
+// SRem - см. InMemoryCache.SRem
+func (c *ShardedCache) SRem(key string, members ...string) (int, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.shard(key).SRem(key, members...)
+}
+
+// SIsMember - см. InMemoryCache.SIsMember
+func (c *ShardedCache) SIsMember(key string, member string) (bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.shard(key).SIsMember(key, member)
+}
+
+// SMembers - см. InMemoryCache.SMembers
+func (c *ShardedCache) SMembers(key string) ([]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.shard(key).SMembers(key)
+}
+
+// GetOrSet - см. InMemoryCache.GetOrSet
+func (c *ShardedCache) GetOrSet(key string, fn func() (interface{}, error), ttl time.Duration) (interface{}, error) {
+	return c.Fetch(key, ttl, fn)
+}
+
+func (c *ShardedCache) Flush() {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, s := range c.shards {
+		s.Flush()
+	}
+}
+
+// SetDefaultExpiration меняет время жизни по-умолчанию во всех сегментах
+func (c *ShardedCache) SetDefaultExpiration(d time.Duration) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, s := range c.shards {
+		s.SetDefaultExpiration(d)
+	}
+}
+
+// Stats возвращает статистику, просуммированную по всем сегментам
+func (c *ShardedCache) Stats() CacheStats {
+	var stats CacheStats
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, s := range c.shards {
+		stats.add(s.Stats())
+	}
+
+	return stats
+}
+
+// TTLHistogram возвращает распределение TTL живых элементов, объединенное по всем сегментам
+func (c *ShardedCache) TTLHistogram() map[string]int {
+	histogram := make(map[string]int)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, s := range c.shards {
+		s.rmu.RLock()
+		s.ttlHistogram(histogram)
+		s.rmu.RUnlock()
+	}
+
+	return histogram
+}
+
+// PrefixStats возвращает попадания и промахи по пространствам имен, объединенные по всем сегментам
+func (c *ShardedCache) PrefixStats() map[string]CacheStats {
+	if c.options.prefixStats == nil {
+		return nil
+	}
+
+	stats := make(map[string]CacheStats)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, s := range c.shards {
+		s.prefixCounters.addTo(stats)
+	}
+
+	return stats
+}
+
+// WaitForSize - см. InMemoryCache.WaitForSize, живые элементы считаются по всем сегментам
+func (c *ShardedCache) WaitForSize(ctx context.Context, n int) error {
+	return waitForSize(ctx, n, func() int {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+
+		live := 0
+		for _, s := range c.shards {
+			live += s.liveCount()
+		}
+
+		return live
+	})
+}
+
+func (c *ShardedCache) GC() {
+	interval := c.cleanupInterval
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-c.options.timeSource().After(interval):
+		}
+
+		collected := 0
+		for _, s := range c.currentShards() {
+			collected += s.sweep()
+		}
+
+		interval = c.options.nextGCInterval(interval, c.cleanupInterval, collected)
+	}
+}
+
+// SetWithTags - см. InMemoryCache.SetWithTags
+func (c *ShardedCache) SetWithTags(key string, value interface{}, d time.Duration, tags ...string) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.shard(key).SetWithTags(key, value, d, tags...)
+}
+
+// InvalidateTag - см. InMemoryCache.InvalidateTag, удаляет элементы с тегом во всех сегментах
+func (c *ShardedCache) InvalidateTag(tag string) int {
+	deleted := 0
+	for _, s := range c.currentShards() {
+		deleted += s.InvalidateTag(tag)
+	}
+
+	return deleted
+}
+
+// GetStale - см. InMemoryCache.GetStale
+func (c *ShardedCache) GetStale(key string) (interface{}, bool, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.shard(key).GetStale(key)
+}
+
+// DeleteExpired - см. InMemoryCache.DeleteExpired
+func (c *ShardedCache) DeleteExpired() int {
+	deleted := 0
+	for _, s := range c.currentShards() {
+		deleted += s.DeleteExpired()
+	}
+
+	return deleted
+}
+
+// Close останавливает GC и закрывает все сегменты, при WithPersistOnClose
+// сохраняет элементы всех сегментов в один файл
+func (c *ShardedCache) Close() error {
+	var err error
+
+	c.closeOnce.Do(func() {
+		close(c.done)
+
+		if c.invalidator != nil {
+			c.invalidator.close()
+		}
+
+		shards := c.currentShards()
+		for _, s := range shards {
+			s.Close()
+		}
+
+		if c.persistPath != "" {
+			err = saveFile(c.persistPath, c.persistCodec, snapshotShards(shards))
+		}
+	})
+
+	return err
+}
+
+// SaveFile сохраняет живые элементы всех сегментов в один файл, см. InMemoryCache.SaveFile
+func (c *ShardedCache) SaveFile(path string, codec ...Codec) error {
+	return saveFile(path, pickCodec(codec), snapshotShards(c.currentShards()))
+}
+
+// Save записывает живые элементы всех сегментов в w, см. InMemoryCache.Save
+func (c *ShardedCache) Save(w io.Writer, codec ...Codec) error {
+	return pickCodec(codec).Encode(w, snapshotShards(c.currentShards()))
+}
+
+// LoadFile загружает элементы, сохраненные SaveFile, распределяя их по сегментам
+func (c *ShardedCache) LoadFile(path string, codec ...Codec) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return c.Load(bufio.NewReader(f), codec...)
+}
+
+// Load загружает элементы, записанные Save, распределяя их по сегментам
+func (c *ShardedCache) Load(r io.Reader, codec ...Codec) error {
+	items, err := pickCodec(codec).Decode(r)
+	if err != nil {
+		return err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	byShard := make(map[*InMemoryCache]map[string]Item)
+	for k, i := range items {
+		s := c.shard(k)
+		if byShard[s] == nil {
+			byShard[s] = make(map[string]Item)
+		}
+		byShard[s][k] = i
+	}
+
+	for s, shardItems := range byShard {
+		s.restoreItems(shardItems)
+	}
+
+	return nil
+}
+
+// snapshotShards объединяет живые элементы сегментов
+func snapshotShards(shards []*InMemoryCache) map[string]Item {
+	items := make(map[string]Item)
+	for _, s := range shards {
+		for k, i := range s.snapshotItems() {
+			items[k] = i
+		}
+	}
+
+	return items
+}
+
+// AddShard добавляет сегмент и переносит в него ключи, которые теперь ему принадлежат,
+// в среднем 1/n всех ключей. Ограничения на сегмент (WithMaxEntries, WithInitialCapacity)
+// у нового сегмента такие же, как у существующих. На время переноса операции с кешем ждут
+func (c *ShardedCache) AddShard() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.addShard(time.Duration(c.shards[0].defaultExpiration.Load()))
+	c.rebalance(c.shards[:len(c.shards)-1])
+}
+
+// RemoveShard удаляет последний добавленный сегмент и распределяет его ключи по остальным.
+// Последний сегмент удалить нельзя, тогда возвращается ErrInvalidConfig
+func (c *ShardedCache) RemoveShard() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.shards) == 1 {
+		return fmt.Errorf("%w: cannot remove the last shard", ErrInvalidConfig)
+	}
+
+	last := len(c.shards) - 1
+	removed, name := c.shards[last], c.names[last]
+	c.shards, c.names = c.shards[:last], c.names[:last]
+	delete(c.byName, name)
+	c.ring.Remove(name)
+
+	// Close применяет отложенные асинхронные записи
+	removed.Close()
+	c.rebalance([]*InMemoryCache{removed})
+
+	return nil
+}
+
+// addShard создает сегмент и добавляет его в кольцо, вызывается под блокировкой на запись
+func (c *ShardedCache) addShard(defaultExpiration time.Duration) {
+	name := "shard-" + strconv.Itoa(c.nextShard)
+	c.nextShard++
+
+	s := newInMemoryCache(c.options, &exclusiveLock{}, defaultExpiration, c.cleanupInterval)
+	s.handlers = c.handlers
+	s.invalidator = c.invalidator
+	c.shards = append(c.shards, s)
+	c.names = append(c.names, name)
+	c.byName[name] = s
+	c.ring.Add(name)
+}
+
+// rebalance переносит из сегментов from ключи, которые принадлежат другим сегментам,
+// вызывается под блокировкой на запись. Элемент, который сегмент-получатель отклонил,
+// удаляется из кеша: заполненный сегмент передает его обработчикам с причиной ReasonCapacity
+func (c *ShardedCache) rebalance(from []*InMemoryCache) {
+	for _, s := range from {
+		if s.async != nil {
+			s.async.flush()
+		}
+
+		for k, i := range s.extract(func(key string) bool { return c.shard(key) != s }) {
+			target := c.shard(k)
+
+			target.rmu.Lock()
+			if err := target.storeItem(k, i); err != nil {
+				target.logger.Warn("cache rebalance dropped item", "key", k, "err", err)
+				if errors.Is(err, ErrCacheFull) && !target.handlers.empty() {
+					target.pending = append(target.pending, EvictedItem{Key: k, Value: target.itemValue(i), Reason: ReasonCapacity})
+				}
+			}
+			target.unlock()
+		}
+	}
+}
+
+// currentShards возвращает текущий список сегментов
+func (c *ShardedCache) currentShards() []*InMemoryCache {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return append([]*InMemoryCache(nil), c.shards...)
+}
+
+// shard возвращает сегмент, в котором хранится ключ, вызывается под блокировкой
+func (c *ShardedCache) shard(key string) *InMemoryCache {
+	name, _ := c.ring.Get(key)
+	return c.byName[name]
+}
+
+// extract удаляет из хранилища элементы, для которых moves истинна, и возвращает их.
+// Обработчики удаления не вызываются: элементы не удаляются из кеша, а переносятся
+func (c *InMemoryCache) extract(moves func(key string) bool) map[string]Item {
+	c.rmu.Lock()
+	defer c.unlock()
+
+	items := make(map[string]Item)
+	for k := range c.cache {
+		if moves(k) {
+			items[k], _ = c.removeItem(k)
+		}
+	}
+
+	return items
+}
@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+// owners возвращает сегмент, которому принадлежит каждый из ключей
+func owners(c *ShardedCache, keys []string) map[string]*InMemoryCache {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	owner := make(map[string]*InMemoryCache, len(keys))
+	for _, key := range keys {
+		owner[key] = c.shard(key)
+	}
+
+	return owner
+}
+
+func TestShardedCacheReshardRemapsFewKeys(t *testing.T) {
+	const shards, total = 8, 20000
+
+	c := NewShardedCache(shards, 0, 0)
+	defer c.Close()
+
+	keys := make([]string, total)
+	for i := range keys {
+		keys[i] = "key:" + strconv.Itoa(i)
+		c.Set(keys[i], i, NoExpiration)
+	}
+
+	before := owners(c, keys)
+	c.AddShard()
+	after := owners(c, keys)
+
+	added := c.currentShards()[shards]
+	moved := 0
+	for _, key := range keys {
+		if before[key] == after[key] {
+			continue
+		}
+		if after[key] != added {
+			t.Fatalf("key %s moved between existing shards", key)
+		}
+		moved++
+	}
+
+	// В среднем в новый сегмент переходит 1/(shards+1) ключей, при делении по модулю - почти все
+	fraction := float64(moved) / total
+	if want := 1.0 / (shards + 1); fraction < want/2 || fraction > want*2 {
+		t.Errorf("remapped fraction = %.3f, want about %.3f", fraction, want)
+	}
+
+	if err := c.RemoveShard(); err != nil {
+		t.Fatalf("RemoveShard: %v", err)
+	}
+	restored := owners(c, keys)
+	for _, key := range keys {
+		if restored[key] != before[key] {
+			t.Fatalf("key %s did not return to its shard after RemoveShard", key)
+		}
+	}
+
+	for i, key := range keys {
+		if value, found := c.Get(key); !found || value != i {
+			t.Fatalf("Get(%s) = %v, %v after resharding", key, value, found)
+		}
+	}
+}
+
+// benchmarkParallel выполняет параллельную нагрузку с одной записью на три чтения
+func benchmarkParallel(b *testing.B, c Cache) {
+	defer c.Close()
+
+	keys := make([]string, 4096)
+	for i := range keys {
+		keys[i] = "key:" + strconv.Itoa(i)
+		c.Set(keys[i], i, NoExpiration)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := keys[i%len(keys)]
+			if i%4 == 0 {
+				c.Set(key, i, NoExpiration)
+			} else {
+				c.Get(key)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkSingleLock(b *testing.B) {
+	benchmarkParallel(b, NewInMemoryCache(0, 0))
+}
+
+func BenchmarkShardedCache(b *testing.B) {
+	for _, shards := range []int{4, 16, 64} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			benchmarkParallel(b, NewShardedCache(shards, 0, 0))
+		})
+	}
+}
+
+func TestShardedCacheRemoveShardReportsRejectedItems(t *testing.T) {
+	c := NewShardedCache(2, 0, 0, WithMaxEntries(8), WithEvictionPolicy(PolicyReject))
+	defer c.Close()
+
+	var dropped []string
+	c.OnEvicted(func(key string, _ interface{}, reason EvictionReason) {
+		if reason != ReasonCapacity {
+			t.Errorf("OnEvicted(%s) reason = %v, want %v", key, reason, ReasonCapacity)
+		}
+		dropped = append(dropped, key)
+	})
+
+	stored := 0
+	for i := 0; i < 16; i++ {
+		if err := c.SetE("key:"+strconv.Itoa(i), i, NoExpiration); err == nil {
+			stored++
+		}
+	}
+
+	if err := c.RemoveShard(); err != nil {
+		t.Fatalf("RemoveShard: %v", err)
+	}
+
+	if len(dropped) == 0 {
+		t.Fatal("no items were reported after moving into a full shard")
+	}
+	if got := c.Count() + len(dropped); got != stored {
+		t.Errorf("Count + dropped = %d, want %d", got, stored)
+	}
+}
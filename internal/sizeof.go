@@ -0,0 +1,109 @@
+package internal
+
+import "reflect"
+
+// sizeOfMaxDepth ограничивает глубину обхода вложенных значений,
+// защищает от бесконечного обхода циклических структур
+const sizeOfMaxDepth = 16
+
+// Sizer реализуют значения, которые сами знают свой размер в байтах,
+// SizeOf использует его вместо оценки через reflect
+type Sizer interface {
+	Size() int64
+}
+
+// SizeOf приблизительно оценивает размер значения в байтах. Подходит как оценщик
+// для WithSizeEstimator: WithSizeEstimator(SizeOf).
+//
+// Оценка приближенная: для строк и []byte учитывается длина данных, для чисел - их размер,
+// для структур, срезов, map и указателей размер считается обходом через reflect.
+// Не учитываются служебные накладные расходы map и аллокатора, разделяемые
+// указателями данные считаются для каждого указателя заново,
+// вложенность глубже sizeOfMaxDepth не учитывается. Каналы и функции считаются по размеру ссылки
+func SizeOf(value interface{}) int64 {
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case Sizer:
+		return v.Size()
+	case string:
+		return int64(len(v))
+	case []byte:
+		return int64(len(v))
+	case bool, int8, uint8:
+		return 1
+	case int16, uint16:
+		return 2
+	case int32, uint32, float32:
+		return 4
+	case int, uint, int64, uint64, float64, uintptr, complex64:
+		return 8
+	case complex128:
+		return 16
+	}
+
+	v := reflect.ValueOf(value)
+
+	return int64(v.Type().Size()) + indirectSize(v, 0)
+}
+
+// indirectSize считает размер данных, на которые ссылается значение, без размера самого значения
+func indirectSize(v reflect.Value, depth int) int64 {
+	if depth > sizeOfMaxDepth {
+		return 0
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return int64(v.Len())
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+
+		elem := v.Elem()
+
+		return int64(elem.Type().Size()) + indirectSize(elem, depth+1)
+	case reflect.Slice:
+		if v.IsNil() {
+			return 0
+		}
+
+		size := int64(v.Cap()) * int64(v.Type().Elem().Size())
+		for i := 0; i < v.Len(); i++ {
+			size += indirectSize(v.Index(i), depth+1)
+		}
+
+		return size
+	case reflect.Array:
+		var size int64
+		for i := 0; i < v.Len(); i++ {
+			size += indirectSize(v.Index(i), depth+1)
+		}
+
+		return size
+	case reflect.Map:
+		if v.IsNil() {
+			return 0
+		}
+
+		entry := int64(v.Type().Key().Size() + v.Type().Elem().Size())
+		size := int64(v.Len()) * entry
+
+		iter := v.MapRange()
+		for iter.Next() {
+			size += indirectSize(iter.Key(), depth+1) + indirectSize(iter.Value(), depth+1)
+		}
+
+		return size
+	case reflect.Struct:
+		var size int64
+		for i := 0; i < v.NumField(); i++ {
+			size += indirectSize(v.Field(i), depth+1)
+		}
+
+		return size
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,49 @@
+package internal
+
+import "time"
+
+// SetWithSliding записывает значение со скользящим временем жизни ttl: каждое чтение через Get
+// продлевает элемент на ttl, и он истекает только после ttl без чтений, как сессия.
+// Peek, Inspect и остальные методы мониторинга элемент не продлевают.
+// Продолжительности DefaultExpiration и NoExpiration работают как в SetE, бессрочный элемент не продлевается
+func (c *InMemoryCache) SetWithSliding(key string, value interface{}, ttl time.Duration) error {
+	if err := c.checkKey(key); err != nil {
+		return err
+	}
+
+	if c.keyLocking {
+		defer c.keyLocks.lock(key)()
+	}
+
+	item := c.newItem(key, value, ttl)
+	if d := c.itemTTL(key, ttl); d > 0 && item.expiration > 0 {
+		item.idle = d
+	}
+
+	return c.store(key, item)
+}
+
+// slide продлевает прочитанный элемент со скользящим временем жизни и записывает продление в журнал.
+// Элемент, который успели перезаписать, удалить или который истек, не трогаем, как и элемент,
+// продление которого не удалось записать в журнал. Возвращает время истечения элемента после продления
+func (c *InMemoryCache) slide(key string, item Item) (expiration int64) {
+	c.rmu.Lock()
+	defer c.unlock()
+
+	current, found := c.cache[key]
+	if !found || current.idle <= 0 || !current.createdAt.Equal(item.createdAt) || c.expired(current) {
+		return item.expiration
+	}
+
+	current.expiration = expirationFor(c.nowNano(), current.idle)
+	if err := c.logSet(key, current); err != nil {
+		c.logger.Warn("cache sliding expiration rejected", "key", key, "err", err)
+		return item.expiration
+	}
+
+	c.cache[key] = current
+	c.maybeCompactWAL()
+	c.rescheduleExpiry(key, current)
+
+	return current.expiration
+}
@@ -0,0 +1,55 @@
+package internal
+
+import "time"
+
+// CacheSnapshot - сводное состояние кеша для отладочных и административных страниц
+type CacheSnapshot struct {
+	Entries         int           `json:"entries"`
+	ExpiredPending  int           `json:"expired_pending"`
+	OldestCreatedAt time.Time     `json:"oldest_created_at"`
+	NewestCreatedAt time.Time     `json:"newest_created_at"`
+	DefaultTTL      time.Duration `json:"default_ttl"`
+	CleanupInterval time.Duration `json:"cleanup_interval"`
+	Stats           CacheStats    `json:"stats"`
+	// Количество живых элементов по меткам SetWithSource, только при WithSourceTracking
+	Sources map[string]int `json:"sources,omitempty"`
+}
+
+// Snapshot собирает сводное состояние кеша за одно чтение под блокировкой,
+// поэтому все поля согласованы между собой. Время записи считается по живым элементам
+func (c *InMemoryCache) Snapshot() CacheSnapshot {
+	c.rmu.RLock()
+	defer c.rmu.RUnlock()
+
+	s := CacheSnapshot{
+		Entries:         len(c.cache),
+		DefaultTTL:      time.Duration(c.defaultExpiration.Load()),
+		CleanupInterval: c.cleanupInterval,
+		Stats:           c.stats(),
+	}
+
+	if c.sources != nil {
+		s.Sources = make(map[string]int)
+	}
+
+	for k, i := range c.cache {
+		if c.expired(i) {
+			s.ExpiredPending++
+			continue
+		}
+
+		if source, found := c.sources[k]; found {
+			s.Sources[source]++
+		}
+
+		if s.OldestCreatedAt.IsZero() || i.createdAt.Before(s.OldestCreatedAt) {
+			s.OldestCreatedAt = i.createdAt
+		}
+
+		if i.createdAt.After(s.NewestCreatedAt) {
+			s.NewestCreatedAt = i.createdAt
+		}
+	}
+
+	return s
+}
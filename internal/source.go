@@ -0,0 +1,35 @@
+package internal
+
+import "time"
+
+// SetWithSource записывает значение как SetE и запоминает source - метку компонента,
+// записавшего ключ. Метка видна в Inspect и Snapshot и сбрасывается любой другой записью ключа.
+// Без WithSourceTracking метка отбрасывается. Пишет синхронно и при WithAsyncWrites
+func (c *InMemoryCache) SetWithSource(key string, value interface{}, duration time.Duration, source string) error {
+	if err := c.checkKey(key); err != nil {
+		return err
+	}
+
+	if c.keyLocking {
+		defer c.keyLocks.lock(key)()
+	}
+
+	item := c.newItem(key, value, duration)
+
+	c.rmu.Lock()
+	defer c.unlock()
+
+	if c.skipEqual(key, item) {
+		return nil
+	}
+
+	if err := c.storeItem(key, item); err != nil {
+		return err
+	}
+
+	if c.sources != nil {
+		c.sources[key] = source
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package internal
+
+import "time"
+
+// WithStaleWhileRevalidate оставляет просроченные элементы в хранилище еще на window:
+// Get для них возвращает промах, а GetStale - прежнее значение с признаком устаревания
+// и запускает фоновую загрузку нового значения через loader (nil - без загрузки).
+// Загруженное значение записывается на прежний срок жизни элемента. GC и DeleteExpired
+// удаляют элемент только по окончании окна
+func WithStaleWhileRevalidate(window time.Duration, loader func(key string) (interface{}, error)) Option {
+	return func(o *options) {
+		o.staleWindow = window
+		o.staleLoader = loader
+	}
+}
+
+// GetStale возвращает значение как Get, а для элемента, истекшего не более WithStaleWhileRevalidate
+// назад, - прежнее значение со stale = true, запуская его фоновую загрузку
+func (c *InMemoryCache) GetStale(key string) (value interface{}, stale bool, found bool) {
+	if value, _, found := c.GetWithHits(key); found {
+		return value, false, true
+	}
+
+	if c.staleWindow <= 0 {
+		return nil, false, false
+	}
+
+	c.rmu.RLock()
+	item, found := c.cache[key]
+	if !found || !c.expired(item) || c.reapable(item) {
+		c.rmu.RUnlock()
+		return nil, false, false
+	}
+	value = c.itemValue(item)
+	c.rmu.RUnlock()
+
+	if c.staleLoader != nil && !c.closed.Load() {
+		c.reload(key, item, c.staleLoader)
+	}
+
+	return value, true, true
+}
+
+// reapable проверяет, пора ли удалить просроченный элемент с учетом окна WithStaleWhileRevalidate
+func (c *InMemoryCache) reapable(item Item) bool {
+	if !c.expired(item) {
+		return false
+	}
+
+	return c.staleWindow <= 0 || item.expiration == 0 ||
+		c.nowNano() > item.expiration+int64(c.expirationGrace+c.staleWindow)
+}
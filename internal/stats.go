@@ -0,0 +1,107 @@
+package internal
+
+import "time"
+
+// CacheStats - статистика кеша. Счетчики копятся с момента создания кеша и Flush не сбрасываются
+type CacheStats struct {
+	// Количество элементов в хранилище, включая просроченные, но еще не удаленные
+	Items int `json:"items"`
+
+	// Оценка суммарного размера ключей и значений в байтах,
+	// при выключенной оценке размера (см. WithSizeEstimator) равны 0
+	KeyBytes   int64 `json:"key_bytes"`
+	ValueBytes int64 `json:"value_bytes"`
+
+	// Длительность последнего прохода GC, количество удаленных им элементов
+	// и общее число проходов с момента создания кеша
+	LastGCDuration  time.Duration `json:"last_gc_duration"`
+	LastGCCollected int           `json:"last_gc_collected"`
+	TotalGCRuns     int64         `json:"total_gc_runs"`
+
+	// Попадания и промахи Fetch, количество вызовов загрузчика и их суммарная длительность
+	FetchHits     uint64        `json:"fetch_hits"`
+	FetchMisses   uint64        `json:"fetch_misses"`
+	FetchLoads    uint64        `json:"fetch_loads"`
+	FetchLoadTime time.Duration `json:"fetch_load_time"`
+
+	// Количество элементов, вытесненных из заполненного кеша (см. WithMaxEntries и WithMaxBytes),
+	// и удаленных по истечению времени жизни
+	Evictions uint64 `json:"evictions"`
+	Expired   uint64 `json:"expired"`
+
+	// Попадания и промахи Get и основанных на нем чтений
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
+
+func (s *CacheStats) add(other CacheStats) {
+	s.Items += other.Items
+	s.KeyBytes += other.KeyBytes
+	s.ValueBytes += other.ValueBytes
+	// Сегменты очищаются по очереди, поэтому длительности складываются
+	s.LastGCDuration += other.LastGCDuration
+	s.LastGCCollected += other.LastGCCollected
+	s.TotalGCRuns += other.TotalGCRuns
+	s.FetchHits += other.FetchHits
+	s.FetchMisses += other.FetchMisses
+	s.FetchLoads += other.FetchLoads
+	s.FetchLoadTime += other.FetchLoadTime
+	s.Evictions += other.Evictions
+	s.Expired += other.Expired
+	s.Hits += other.Hits
+	s.Misses += other.Misses
+}
+
+// gcStats - результаты проходов GC, изменяются под блокировкой на запись
+type gcStats struct {
+	lastDuration  time.Duration
+	lastCollected int
+	runs          int64
+}
+
+// Stats возвращает текущую статистику кеша
+func (c *InMemoryCache) Stats() CacheStats {
+	c.rmu.RLock()
+	defer c.rmu.RUnlock()
+
+	return c.stats()
+}
+
+// stats собирает статистику, вызывается под блокировкой
+func (c *InMemoryCache) stats() CacheStats {
+	stats := CacheStats{
+		Items:           len(c.cache),
+		KeyBytes:        c.keyBytes,
+		ValueBytes:      c.valueBytes,
+		LastGCDuration:  c.gc.lastDuration,
+		LastGCCollected: c.gc.lastCollected,
+		TotalGCRuns:     c.gc.runs,
+		Evictions:       c.evictions,
+		Expired:         c.expiredRemovals,
+		Hits:            c.lookups.hits.Load(),
+		Misses:          c.lookups.misses.Load(),
+	}
+	c.fetches.addTo(&stats)
+
+	return stats
+}
+
+// trackSize учитывает размер добавленного элемента, вызывается под блокировкой на запись
+func (c *InMemoryCache) trackSize(key string, item Item) {
+	if c.sizeEstimator == nil {
+		return
+	}
+
+	c.keyBytes += int64(len(key))
+	c.valueBytes += item.size
+}
+
+// untrackSize вычитает размер удаленного элемента, вызывается под блокировкой на запись
+func (c *InMemoryCache) untrackSize(key string, item Item) {
+	if c.sizeEstimator == nil {
+		return
+	}
+
+	c.keyBytes -= int64(len(key))
+	c.valueBytes -= item.size
+}
@@ -0,0 +1,86 @@
+package internal
+
+import "time"
+
+// tagIndex - обратный индекс тегов, изменяется под блокировкой на запись
+type tagIndex struct {
+	// keys - ключи, помеченные тегом
+	keys map[string]map[string]struct{}
+	// tags - теги ключа
+	tags map[string][]string
+}
+
+// SetWithTags записывает значение и помечает его тегами tags: InvalidateTag удаляет
+// все элементы с тегом одним вызовом. Повторная запись ключа через Set снимает его теги
+func (c *InMemoryCache) SetWithTags(key string, value interface{}, d time.Duration, tags ...string) error {
+	if err := c.checkKey(key); err != nil {
+		return err
+	}
+
+	item := c.newItem(key, value, d)
+
+	c.rmu.Lock()
+	defer c.unlock()
+
+	if err := c.storeItem(key, item); err != nil {
+		return err
+	}
+
+	c.tags.link(key, tags)
+
+	return nil
+}
+
+// InvalidateTag удаляет все элементы с тегом tag (причина ReasonDeleted)
+// и возвращает количество удаленных
+func (c *InMemoryCache) InvalidateTag(tag string) int {
+	c.rmu.Lock()
+	defer c.unlock()
+
+	keys := make([]string, 0, len(c.tags.keys[tag]))
+	for k := range c.tags.keys[tag] {
+		keys = append(keys, k)
+	}
+
+	deleted := 0
+	for _, k := range keys {
+		if c.evict(k, ReasonDeleted) {
+			deleted++
+		}
+	}
+
+	return deleted
+}
+
+// link помечает ключ key тегами tags
+func (t *tagIndex) link(key string, tags []string) {
+	if len(tags) == 0 {
+		return
+	}
+
+	if t.keys == nil {
+		t.keys = make(map[string]map[string]struct{})
+		t.tags = make(map[string][]string)
+	}
+
+	for _, tag := range tags {
+		if t.keys[tag] == nil {
+			t.keys[tag] = make(map[string]struct{})
+		}
+		t.keys[tag][key] = struct{}{}
+	}
+
+	t.tags[key] = append(t.tags[key], tags...)
+}
+
+// unlink снимает все теги ключа key
+func (t *tagIndex) unlink(key string) {
+	for _, tag := range t.tags[key] {
+		delete(t.keys[tag], key)
+		if len(t.keys[tag]) == 0 {
+			delete(t.keys, tag)
+		}
+	}
+
+	delete(t.tags, key)
+}
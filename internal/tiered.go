@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// RemoteStore - удаленное хранилище второго уровня TieredCache, например адаптер Redis или memcached.
+// Сериализация значений остается на стороне адаптера. Get возвращает found = false для
+// отсутствующего ключа, а Delete отсутствующего ключа не считается ошибкой
+type RemoteStore interface {
+	Get(ctx context.Context, key string) (value interface{}, found bool, err error)
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// errRemoteMiss передает промах второго уровня через flightGroup
+var errRemoteMiss = errors.New("remote miss")
+
+// TieredCache - двухуровневый кеш: кеш в памяти (L1) перед удаленным хранилищем (L2).
+// Промах L1 читает L2 и записывает найденное значение в L1, записи и удаления идут в оба уровня.
+// Одновременные промахи L1 по одному ключу читают L2 один раз
+type TieredCache struct {
+	l1    Cache
+	l2    RemoteStore
+	l1TTL time.Duration
+	// loads объединяет чтения L2, fetches - загрузки FetchCtx: у них разные результаты промаха
+	loads   flightGroup
+	fetches flightGroup
+}
+
+// TieredOption настраивает TieredCache
+type TieredOption func(*TieredCache)
+
+// WithL1TTL задает время жизни значений, которые промах L1 прочитал из L2.
+// По-умолчанию - время жизни L1 по-умолчанию
+func WithL1TTL(ttl time.Duration) TieredOption {
+	return func(c *TieredCache) {
+		c.l1TTL = ttl
+	}
+}
+
+// NewTieredCache создает двухуровневый кеш. Закрывать l1 и l2 по-прежнему должен вызывающий
+func NewTieredCache(l1 Cache, l2 RemoteStore, opts ...TieredOption) *TieredCache {
+	c := &TieredCache{l1: l1, l2: l2, l1TTL: DefaultExpiration}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// GetCtx возвращает значение из L1, а при промахе - из L2, записывая его в L1.
+// Ошибка L2 возвращается как есть
+func (c *TieredCache) GetCtx(ctx context.Context, key string) (interface{}, bool, error) {
+	if value, found := c.l1.Get(key); found {
+		return value, true, nil
+	}
+
+	value, _, err := c.loads.do(ctx, key, func() (interface{}, time.Duration, error) {
+		value, found, err := c.l2.Get(ctx, key)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !found {
+			return nil, 0, errRemoteMiss
+		}
+
+		c.l1.Set(key, value, c.l1TTL)
+
+		return value, 0, nil
+	})
+	if errors.Is(err, errRemoteMiss) {
+		return nil, false, nil
+	}
+
+	return value, err == nil, err
+}
+
+// SetCtx записывает значение сначала в L2, затем в L1 на тот же ttl. Если запись в L2
+// не удалась, значение удаляется из L1, чтобы уровни не расходились
+func (c *TieredCache) SetCtx(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if err := c.l2.Set(ctx, key, value, ttl); err != nil {
+		c.l1.Delete(key)
+		return err
+	}
+
+	c.l1.Set(key, value, ttl)
+
+	return nil
+}
+
+// DeleteCtx удаляет ключ из обоих уровней. Отсутствие ключа в L1 ошибкой не считается
+func (c *TieredCache) DeleteCtx(ctx context.Context, key string) error {
+	c.l1.Delete(key)
+
+	return c.l2.Delete(ctx, key)
+}
+
+// FetchCtx возвращает значение из L1 или L2, а если его нет и там, загружает его loader
+// и записывает в оба уровня на ttl. Значение из L2 записывается в L1 на время WithL1TTL
+func (c *TieredCache) FetchCtx(ctx context.Context, key string, ttl time.Duration, loader func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	value, found, err := c.GetCtx(ctx, key)
+	if err != nil || found {
+		return value, err
+	}
+
+	value, _, err = c.fetches.do(ctx, key, func() (interface{}, time.Duration, error) {
+		value, err := loader(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if err := c.SetCtx(ctx, key, value, ttl); err != nil {
+			return nil, 0, err
+		}
+
+		return value, 0, nil
+	})
+
+	return value, err
+}
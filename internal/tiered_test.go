@@ -0,0 +1,186 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// remoteStore - L2 в памяти. gate, если задан, задерживает Get до закрытия
+type remoteStore struct {
+	mu     sync.Mutex
+	values map[string]interface{}
+	gets   atomic.Int32
+	setErr error
+	gate   chan struct{}
+}
+
+func newRemoteStore() *remoteStore {
+	return &remoteStore{values: make(map[string]interface{})}
+}
+
+func (s *remoteStore) Get(_ context.Context, key string) (interface{}, bool, error) {
+	s.gets.Add(1)
+	if s.gate != nil {
+		<-s.gate
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, found := s.values[key]
+
+	return value, found, nil
+}
+
+func (s *remoteStore) Set(_ context.Context, key string, value interface{}, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.setErr != nil {
+		return s.setErr
+	}
+	s.values[key] = value
+
+	return nil
+}
+
+func (s *remoteStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.values, key)
+
+	return nil
+}
+
+func TestTieredCacheFallsThroughToL2(t *testing.T) {
+	l1 := NewInMemoryCache(0, 0)
+	defer l1.Close()
+	l2 := newRemoteStore()
+	l2.values["key"] = "remote"
+
+	c := NewTieredCache(l1, l2)
+
+	value, found, err := c.GetCtx(context.Background(), "key")
+	if err != nil || !found || value != "remote" {
+		t.Fatalf("GetCtx = %v, %v, %v, want remote", value, found, err)
+	}
+	if value, _ := l1.Get("key"); value != "remote" {
+		t.Errorf("L1 = %v after a miss, want the L2 value", value)
+	}
+
+	if _, found, _ := c.GetCtx(context.Background(), "missing"); found {
+		t.Error("GetCtx found a key missing in both levels")
+	}
+	if _, found := l1.Get("missing"); found {
+		t.Error("a miss in both levels was stored in L1")
+	}
+}
+
+func TestTieredCacheRollsBackFailedL2Set(t *testing.T) {
+	l1 := NewInMemoryCache(0, 0)
+	defer l1.Close()
+	l2 := newRemoteStore()
+	c := NewTieredCache(l1, l2)
+
+	if err := c.SetCtx(context.Background(), "key", "old", NoExpiration); err != nil {
+		t.Fatalf("SetCtx: %v", err)
+	}
+
+	l2.setErr = errors.New("remote is down")
+	if err := c.SetCtx(context.Background(), "key", "new", NoExpiration); !errors.Is(err, l2.setErr) {
+		t.Fatalf("SetCtx err = %v, want %v", err, l2.setErr)
+	}
+	if _, found := l1.Get("key"); found {
+		t.Error("L1 kept the key after a failed L2 write")
+	}
+
+	_, err := c.FetchCtx(context.Background(), "fetched", NoExpiration, func(context.Context) (interface{}, error) {
+		return "loaded", nil
+	})
+	if !errors.Is(err, l2.setErr) {
+		t.Errorf("FetchCtx err = %v, want %v", err, l2.setErr)
+	}
+	if _, found := l1.Get("fetched"); found {
+		t.Error("L1 kept a fetched value that L2 rejected")
+	}
+}
+
+func TestTieredCacheConcurrentMissesReadL2Once(t *testing.T) {
+	l1 := NewInMemoryCache(0, 0)
+	defer l1.Close()
+	l2 := newRemoteStore()
+	l2.values["key"] = "remote"
+	l2.gate = make(chan struct{})
+
+	c := NewTieredCache(l1, l2)
+
+	const readers = 8
+	var started, done sync.WaitGroup
+	started.Add(readers)
+	done.Add(readers)
+	for i := 0; i < readers; i++ {
+		go func() {
+			defer done.Done()
+			started.Done()
+			if value, _, err := c.GetCtx(context.Background(), "key"); err != nil || value != "remote" {
+				t.Errorf("GetCtx = %v, %v", value, err)
+			}
+		}()
+	}
+
+	started.Wait()
+	// Даем всем читателям присоединиться к первому чтению L2
+	time.Sleep(20 * time.Millisecond)
+	close(l2.gate)
+	done.Wait()
+
+	if n := l2.gets.Load(); n != 1 {
+		t.Errorf("L2 reads = %d, want 1", n)
+	}
+}
+
+func TestTieredCacheFetchJoiningGetRunsLoader(t *testing.T) {
+	l1 := NewInMemoryCache(0, 0)
+	defer l1.Close()
+	l2 := newRemoteStore()
+	l2.gate = make(chan struct{})
+
+	c := NewTieredCache(l1, l2)
+
+	got := make(chan error, 1)
+	go func() {
+		_, _, err := c.GetCtx(context.Background(), "key")
+		got <- err
+	}()
+
+	// Дожидаемся чтения L2, начатого GetCtx
+	for l2.gets.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	fetched := make(chan interface{}, 1)
+	go func() {
+		value, err := c.FetchCtx(context.Background(), "key", NoExpiration, func(context.Context) (interface{}, error) {
+			return "loaded", nil
+		})
+		if err != nil {
+			t.Errorf("FetchCtx: %v", err)
+		}
+		fetched <- value
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	close(l2.gate)
+
+	if err := <-got; err != nil {
+		t.Errorf("GetCtx: %v", err)
+	}
+	if value := <-fetched; value != "loaded" {
+		t.Errorf("FetchCtx = %v, want the loader value", value)
+	}
+}
@@ -0,0 +1,64 @@
+package internal
+
+import "time"
+
+// ttlBuckets - верхние границы корзин TTLHistogram по возрастанию
+var ttlBuckets = []struct {
+	limit time.Duration
+	label string
+}{
+	{time.Second, "<1s"},
+	{10 * time.Second, "<10s"},
+	{time.Minute, "<1m"},
+	{10 * time.Minute, "<10m"},
+	{time.Hour, "<1h"},
+	{24 * time.Hour, "<24h"},
+}
+
+const (
+	// ttlBucketLong - корзина для TTL от суток и больше
+	ttlBucketLong = ">=24h"
+	// ttlBucketNone - корзина для бессрочных элементов
+	ttlBucketNone = "none"
+)
+
+// TTLHistogram возвращает распределение заданных при записи TTL живых элементов по корзинам
+// ("<1s", "<10s", "<1m", "<10m", "<1h", "<24h", ">=24h", "none" для бессрочных).
+// Много элементов в одной корзине означает, что они истекут примерно одновременно.
+// Считается за O(n) при каждом вызове, пустые корзины не включаются
+func (c *InMemoryCache) TTLHistogram() map[string]int {
+	histogram := make(map[string]int)
+
+	c.rmu.RLock()
+	defer c.rmu.RUnlock()
+
+	c.ttlHistogram(histogram)
+
+	return histogram
+}
+
+// ttlHistogram добавляет элементы кеша в histogram, вызывается под блокировкой
+func (c *InMemoryCache) ttlHistogram(histogram map[string]int) {
+	for _, item := range c.cache {
+		if c.expired(item) {
+			continue
+		}
+
+		histogram[ttlBucket(item)]++
+	}
+}
+
+func ttlBucket(item Item) string {
+	if item.expiration == 0 {
+		return ttlBucketNone
+	}
+
+	ttl := time.Duration(item.expiration - item.createdAt.UnixNano())
+	for _, b := range ttlBuckets {
+		if ttl < b.limit {
+			return b.label
+		}
+	}
+
+	return ttlBucketLong
+}
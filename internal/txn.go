@@ -0,0 +1,37 @@
+package internal
+
+import "time"
+
+// Txn дает доступ к кешу внутри Atomic, все операции выполняются
+// под уже захваченной блокировкой. Txn нельзя использовать после выхода из Atomic
+type Txn struct {
+	cache *InMemoryCache
+}
+
+func (tx *Txn) Get(key string) (interface{}, bool) {
+	return tx.cache.get(key)
+}
+
+// Set записывает значение, недопустимый ключ (см. SetE) пропускается
+func (tx *Txn) Set(key string, value interface{}, duration time.Duration) {
+	if tx.cache.checkKey(key) != nil {
+		return
+	}
+
+	tx.cache.storeItem(key, tx.cache.newItem(key, value, duration))
+}
+
+func (tx *Txn) Delete(key string) error {
+	return tx.cache.delete(key)
+}
+
+// Atomic выполняет fn под блокировкой на запись, поэтому операции
+// других пользователей кеша не вклиниваются между операциями tx.
+// Внутри fn нельзя вызывать методы кеша напрямую - это приведет к взаимной блокировке,
+// также fn не должна выполнять долгих или блокирующих действий
+func (c *InMemoryCache) Atomic(fn func(tx *Txn)) {
+	c.rmu.Lock()
+	defer c.unlock()
+
+	fn(&Txn{cache: c})
+}
@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TypedCache - кеш с ключами типа K и значениями типа V. Ключи не приводятся к строке,
+// поэтому, например, числовые идентификаторы не требуют strconv и лишних аллокаций.
+// Время жизни и GC работают так же, как у InMemoryCache, но опции не поддерживаются
+type TypedCache[K comparable, V any] struct {
+	mu                sync.RWMutex
+	items             map[K]typedItem[V]
+	defaultExpiration time.Duration
+	cleanupInterval   time.Duration
+	done              chan struct{}
+	closeOnce         sync.Once
+	closed            atomic.Bool
+}
+
+// StringCache - типизированный кеш со строковыми ключами, как у InMemoryCache
+type StringCache[V any] = TypedCache[string, V]
+
+type typedItem[V any] struct {
+	value      V
+	expiration int64
+}
+
+// NewTypedCache создает типизированный кеш, при cleanupInterval > 0 запускает GC
+func NewTypedCache[K comparable, V any](defaultExpiration, cleanupInterval time.Duration) *TypedCache[K, V] {
+	c := &TypedCache[K, V]{
+		items:             make(map[K]typedItem[V]),
+		defaultExpiration: defaultExpiration,
+		cleanupInterval:   cleanupInterval,
+		done:              make(chan struct{}),
+	}
+
+	if cleanupInterval > 0 {
+		go c.GC()
+	}
+
+	return c
+}
+
+func (c *TypedCache[K, V]) Get(key K) (V, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	item, found := c.items[key]
+	if !found || item.expired(time.Now().UnixNano()) {
+		var zero V
+		return zero, false
+	}
+
+	return item.value, true
+}
+
+func (c *TypedCache[K, V]) Has(key K) bool {
+	_, found := c.Get(key)
+	return found
+}
+
+// Count возвращает количество элементов, включая просроченные, но еще не удаленные GC
+func (c *TypedCache[K, V]) Count() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return len(c.items)
+}
+
+// Set записывает значение, продолжительности DefaultExpiration, 0 и NoExpiration работают как в InMemoryCache.
+// После Close запись игнорируется
+func (c *TypedCache[K, V]) Set(key K, value V, duration time.Duration) {
+	_ = c.SetE(key, value, duration)
+}
+
+// SetE записывает значение как Set, после Close возвращает ErrClosed
+func (c *TypedCache[K, V]) SetE(key K, value V, duration time.Duration) error {
+	if duration == DefaultExpiration || duration == 0 {
+		duration = c.defaultExpiration
+	}
+
+	item := typedItem[V]{value: value, expiration: expirationFor(time.Now().UnixNano(), duration)}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed.Load() {
+		return ErrClosed
+	}
+
+	c.items[key] = item
+
+	return nil
+}
+
+func (c *TypedCache[K, V]) Delete(key K) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed.Load() {
+		return ErrClosed
+	}
+
+	if _, found := c.items[key]; !found {
+		return fmt.Errorf("key '%v': %w", key, ErrNotFound)
+	}
+
+	delete(c.items, key)
+
+	return nil
+}
+
+// Flush удаляет все элементы, после Close не действует
+func (c *TypedCache[K, V]) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed.Load() {
+		return
+	}
+
+	c.items = make(map[K]typedItem[V])
+}
+
+func (c *TypedCache[K, V]) GC() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-time.After(c.cleanupInterval):
+		}
+
+		c.sweep()
+	}
+}
+
+// Close останавливает GC, после него записи отклоняются с ErrClosed
+func (c *TypedCache[K, V]) Close() error {
+	c.closeOnce.Do(func() {
+		c.closed.Store(true)
+		close(c.done)
+	})
+
+	return nil
+}
+
+// sweep удаляет элементы с истекшим временем жизни
+func (c *TypedCache[K, V]) sweep() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now().UnixNano()
+	for k, i := range c.items {
+		if i.expired(now) {
+			delete(c.items, k)
+		}
+	}
+}
+
+func (i typedItem[V]) expired(now int64) bool {
+	return i.expiration > 0 && now > i.expiration
+}
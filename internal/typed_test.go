@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTypedCacheDefaultExpiration(t *testing.T) {
+	c := NewTypedCache[int, string](time.Minute, 0)
+	defer c.Close()
+
+	c.Set(1, "default", DefaultExpiration)
+	c.Set(2, "zero", 0)
+	c.Set(3, "forever", NoExpiration)
+
+	now := time.Now().UnixNano()
+	for _, key := range []int{1, 2} {
+		expiration := c.items[key].expiration
+		if expiration <= now || expiration > now+int64(time.Minute) {
+			t.Errorf("key %d expires in %v, want the default TTL", key, time.Duration(expiration-now))
+		}
+	}
+	if expiration := c.items[3].expiration; expiration != 0 {
+		t.Errorf("key 3 expiration = %d, want no expiration", expiration)
+	}
+}
+
+func TestTypedCacheClosed(t *testing.T) {
+	c := NewTypedCache[string, int](0, 0)
+	c.Set("kept", 1, NoExpiration)
+	c.Close()
+
+	if err := c.SetE("key", 1, NoExpiration); !errors.Is(err, ErrClosed) {
+		t.Errorf("SetE err = %v, want %v", err, ErrClosed)
+	}
+	c.Set("key", 1, NoExpiration)
+	if c.Has("key") {
+		t.Error("Set after Close stored the value")
+	}
+
+	if err := c.Delete("kept"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Delete err = %v, want %v", err, ErrClosed)
+	}
+	c.Flush()
+	if !c.Has("kept") {
+		t.Error("Flush after Close removed items")
+	}
+}
@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// encodeValue готовит значение к хранению и записывает в item хранимое значение
+// и признаки того, что оно закодировано, сжато и зашифровано
+func (c *InMemoryCache) encodeValue(item *Item, value interface{}) {
+	value = c.copyValue(value, CopyOnSet)
+
+	if c.transformOnSet != nil {
+		value = c.transform(c.transformOnSet, value)
+	}
+
+	item.encoded, item.compressed, item.encrypted = false, false, false
+
+	// Значение, которое кодек не смог закодировать, хранится как есть
+	if c.valueCodec != nil {
+		if data, err := c.marshalValue(value); err != nil {
+			c.logger.Warn("cache value encode failed", "err", err)
+		} else {
+			value, item.encoded = data, true
+		}
+	}
+
+	item.value = value
+
+	data, ok := value.([]byte)
+	if !ok {
+		return
+	}
+
+	if compressed, ok := c.compress(data); ok {
+		data, item.value, item.compressed = compressed, compressed, true
+	}
+
+	// Шифруем после сжатия: шифротекст уже не сжимается
+	if c.aead != nil {
+		item.value, item.encrypted = c.encrypt(data), true
+	}
+}
+
+// compress сжимает данные длиннее WithCompression, несжимаемые данные не меняются
+func (c *InMemoryCache) compress(data []byte) ([]byte, bool) {
+	if c.compressThreshold <= 0 || len(data) <= c.compressThreshold {
+		return nil, false
+	}
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(data)
+	w.Close()
+
+	// Несжимаемые данные храним как есть
+	if buf.Len() >= len(data) {
+		return nil, false
+	}
+
+	return buf.Bytes(), true
+}
+
+// itemValue возвращает значение элемента в том виде, в каком его записали
+func (c *InMemoryCache) itemValue(item Item) interface{} {
+	value := item.value
+
+	// Измененный шифротекст не расшифровывается, такое значение читается как nil
+	if item.encrypted {
+		data, ok := c.decrypt(value.([]byte))
+		if !ok {
+			return nil
+		}
+		value = data
+	}
+
+	if item.compressed {
+		value = decompress(value.([]byte))
+	}
+
+	if item.encoded {
+		data, ok := value.([]byte)
+		if !ok {
+			return nil
+		}
+		value = c.unmarshalValue(data)
+	}
+
+	if c.transformOnGet != nil {
+		value = c.transform(c.transformOnGet, value)
+	}
+
+	return c.copyValue(value, CopyOnGet)
+}
+
+func decompress(data []byte) interface{} {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+
+	data, err = io.ReadAll(r)
+	if err != nil {
+		return nil
+	}
+
+	return data
+}
+
+// transform применяет пользовательское преобразование, при панике значение остается прежним
+func (c *InMemoryCache) transform(fn func(value interface{}) interface{}, value interface{}) (result interface{}) {
+	result = value
+	defer c.recoverPanic()
+
+	return fn(value)
+}
@@ -0,0 +1,39 @@
+package internal
+
+import (
+	"bytes"
+	"testing"
+)
+
+// benchmarkValue измеряет запись и чтение JSON размером около 16 КиБ
+func benchmarkValue(b *testing.B, opts ...Option) {
+	c := NewInMemoryCache(0, 0, opts...)
+	defer c.Close()
+
+	blob := bytes.Repeat([]byte(`{"id":12345,"name":"item","tags":["a","b","c"]},`), 340)
+
+	b.Run("Set", func(b *testing.B) {
+		b.SetBytes(int64(len(blob)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			c.Set("blob", blob, 0)
+		}
+	})
+
+	c.Set("blob", blob, 0)
+	b.Run("Get", func(b *testing.B) {
+		b.SetBytes(int64(len(blob)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			c.Get("blob")
+		}
+	})
+}
+
+func BenchmarkValueUncompressed(b *testing.B) {
+	benchmarkValue(b)
+}
+
+func BenchmarkValueCompressed(b *testing.B) {
+	benchmarkValue(b, WithCompression(1024))
+}
@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+)
+
+// ValueCodec кодирует значения элементов для хранения, см. WithValueCodec.
+// В отличие от Codec, который сохраняет весь кеш в файл, работает с одним значением
+type ValueCodec interface {
+	Marshal(value interface{}) ([]byte, error)
+	Unmarshal(data []byte) (interface{}, error)
+}
+
+// GobValueCodec кодирует значения в gob и возвращает их с исходным типом.
+// Типы значений, кроме встроенных, нужно зарегистрировать через gob.Register
+type GobValueCodec struct{}
+
+func (GobValueCodec) Marshal(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&value); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (GobValueCodec) Unmarshal(data []byte) (interface{}, error) {
+	var value interface{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// JSONValueCodec кодирует значения в JSON. Прочитанные значения имеют типы,
+// которые дает encoding/json: float64 для чисел, map[string]interface{} для объектов и т.д.
+type JSONValueCodec struct{}
+
+func (JSONValueCodec) Marshal(value interface{}) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (JSONValueCodec) Unmarshal(data []byte) (interface{}, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// marshalValue кодирует значение кодеком WithValueCodec, паника кодека возвращается как ErrPanic
+func (c *InMemoryCache) marshalValue(value interface{}) (data []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.handlePanic(r)
+			err = fmt.Errorf("%w: %v", ErrPanic, r)
+		}
+	}()
+
+	return c.valueCodec.Marshal(value)
+}
+
+// unmarshalValue декодирует значение, неразборчивые данные читаются как nil
+func (c *InMemoryCache) unmarshalValue(data []byte) (value interface{}) {
+	defer c.recoverPanic()
+
+	value, err := c.valueCodec.Unmarshal(data)
+	if err != nil {
+		c.logger.Warn("cache value decode failed", "err", err)
+		return nil
+	}
+
+	return value
+}
@@ -0,0 +1,46 @@
+package internal
+
+import (
+	"context"
+	"time"
+)
+
+// waitPollInterval - период проверки количества элементов в WaitForSize
+const waitPollInterval = 10 * time.Millisecond
+
+// WaitForSize ждет, пока в кеше окажется не меньше n живых элементов, и возвращает ctx.Err(),
+// если ctx завершится раньше. Количество проверяется раз в 10мс перебором всех элементов,
+// поэтому метод предназначен для тестов и прогрева, а не для горячего пути
+func (c *InMemoryCache) WaitForSize(ctx context.Context, n int) error {
+	return waitForSize(ctx, n, c.liveCount)
+}
+
+// liveCount возвращает количество живых элементов
+func (c *InMemoryCache) liveCount() int {
+	c.rmu.RLock()
+	defer c.rmu.RUnlock()
+
+	live := 0
+	for _, i := range c.cache {
+		if !c.expired(i) {
+			live++
+		}
+	}
+
+	return live
+}
+
+func waitForSize(ctx context.Context, n int, count func() int) error {
+	ticker := time.NewTicker(waitPollInterval)
+	defer ticker.Stop()
+
+	for count() < n {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,348 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// walCompactMin - количество записей журнала, до которого он не сжимается
+	walCompactMin = 1024
+	// walMaxRecord ограничивает размер одной записи журнала: большая длина в префиксе
+	// означает поврежденный журнал, а не повод выделить гигабайты памяти
+	walMaxRecord = 64 << 20
+)
+
+// errWALTornTail - последняя запись журнала оборвана: процесс упал во время ее записи
+var errWALTornTail = errors.New("torn final record")
+
+type walOp uint8
+
+const (
+	walSet walOp = iota + 1
+	walDelete
+	walFlush
+)
+
+// walRecord - запись журнала. Каждая запись кодируется gob отдельно и предваряется длиной,
+// поэтому оборванная при падении процесса последняя запись просто отбрасывается,
+// а запись, которую не удалось закодировать, в журнал не попадает и не портит его
+type walRecord struct {
+	Op         walOp
+	Key        string
+	Value      interface{}
+	CreatedAt  time.Time
+	Expiration int64
+	// Idle - период продления элемента со скользящим временем жизни
+	Idle time.Duration
+}
+
+// writeAheadLog дописывает изменения кеша в файл. Методы вызываются под блокировкой кеша
+// на запись и nil-безопасны. Ошибка записи в файл запоминается: после нее файл может оканчиваться
+// оборванной записью, поэтому следующие записи отклоняются с той же ошибкой, она же возвращается из Close
+type writeAheadLog struct {
+	path    string
+	file    *os.File
+	records int
+	err     error
+}
+
+// NewInMemoryCacheFromWAL создает кеш, который дописывает каждую запись, удаление и Flush
+// в журнал path и при создании восстанавливает содержимое, воспроизводя этот журнал.
+// Журнал сжимается до текущего содержимого кеша при создании и когда записей в нем становится
+// вдвое больше, чем элементов. Записи не синхронизируются с диском (fsync), поэтому при сбое
+// ОС последние изменения могут потеряться, но не при падении процесса.
+// Значения кодируются gob, типы значений, кроме встроенных, нужно зарегистрировать через gob.Register.
+// Значения пишутся в журнал в том виде, в каком их записали, в том числе при WithEncryption.
+// Истечение элементов в журнал не пишется: просроченные элементы пропускаются при воспроизведении.
+// Запись, которую не удалось занести в журнал (например, значение незарегистрированного типа),
+// отклоняется и не меняет кеш, ошибку возвращает SetE. Поврежденный журнал, в котором оборвана
+// не последняя запись, не воспроизводится: NewInMemoryCacheFromWAL возвращает ошибку.
+// WithLockStriping не поддерживается
+func NewInMemoryCacheFromWAL(path string, defaultExpiration, cleanupInterval time.Duration, opts ...Option) (*InMemoryCache, error) {
+	o := newOptions(opts)
+	if o.configErr != nil {
+		return nil, o.configErr
+	}
+
+	if o.lockStripes > 1 {
+		return nil, fmt.Errorf("%w: write-ahead log cannot be combined with lock striping", ErrInvalidConfig)
+	}
+
+	c := newInMemoryCache(o, &sync.RWMutex{}, defaultExpiration, cleanupInterval)
+	if err := c.replayWAL(path); err != nil {
+		return nil, err
+	}
+
+	c.wal = &writeAheadLog{path: path}
+
+	c.rmu.Lock()
+	err := c.compactWAL()
+	c.unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	c.start()
+
+	return c, nil
+}
+
+// replayWAL применяет записи журнала path, отсутствующий журнал означает пустой кеш.
+// Отбрасывается только оборванная последняя запись, любое другое повреждение возвращается ошибкой
+func (c *InMemoryCache) replayWAL(path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(f)
+	remaining := info.Size()
+
+	c.rmu.Lock()
+	defer c.unlock()
+
+	for n := 1; ; n++ {
+		rec, size, err := readWALRecord(r, remaining)
+		// Конец журнала или оборванная запись, за которой уже ничего нет:
+		// процесс упал во время записи
+		if err == io.EOF || err == errWALTornTail {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("write-ahead log %s: record %d: %w", path, n, err)
+		}
+		remaining -= size
+
+		switch rec.Op {
+		case walSet:
+			item := Item{createdAt: rec.CreatedAt, expiration: rec.Expiration, idle: rec.Idle}
+			if c.expired(item) {
+				c.removeItem(rec.Key)
+				continue
+			}
+
+			c.encodeValue(&item, rec.Value)
+			c.countHits(&item)
+			if c.sizeEstimator != nil {
+				item.size = c.estimateSize(item.value)
+			}
+			c.storeItem(rec.Key, item)
+		case walDelete:
+			c.removeItem(rec.Key)
+		case walFlush:
+			for key := range c.cache {
+				c.removeItem(key)
+			}
+		}
+	}
+}
+
+// readWALRecord читает запись журнала, remaining - количество непрочитанных байт файла.
+// Возвращает размер записи вместе с префиксом. io.EOF означает конец журнала на границе записей,
+// errWALTornTail - оборванную последнюю запись, длина которой больше остатка файла.
+// Длина сверх walMaxRecord, короткое чтение внутри файла и ошибка gob означают поврежденный журнал
+func readWALRecord(r io.Reader, remaining int64) (walRecord, int64, error) {
+	var rec walRecord
+
+	switch {
+	case remaining == 0:
+		return rec, 0, io.EOF
+	case remaining < 4:
+		return rec, 0, errWALTornTail
+	}
+
+	var prefix [4]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return rec, 0, fmt.Errorf("reading record length: %w", err)
+	}
+
+	size := int64(binary.BigEndian.Uint32(prefix[:]))
+	if size > walMaxRecord {
+		return rec, 0, fmt.Errorf("record length %d exceeds %d bytes", size, walMaxRecord)
+	}
+	if size > remaining-4 {
+		return rec, 0, errWALTornTail
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return rec, 0, fmt.Errorf("reading record: %w", err)
+	}
+
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&rec); err != nil {
+		return rec, 0, fmt.Errorf("decoding record: %w", err)
+	}
+
+	return rec, size + 4, nil
+}
+
+// append дописывает запись в журнал. Запись, которую не удалось закодировать,
+// пропускается и журнал не отключает
+func (w *writeAheadLog) append(rec walRecord) error {
+	if w == nil {
+		return nil
+	}
+
+	if w.err != nil {
+		return w.err
+	}
+
+	data, err := encodeWALRecord(rec)
+	if err != nil {
+		return fmt.Errorf("write-ahead log: %w", err)
+	}
+
+	if _, w.err = w.file.Write(data); w.err != nil {
+		return w.err
+	}
+	w.records++
+
+	return nil
+}
+
+// encodeWALRecord кодирует запись вместе с префиксом длины
+func encodeWALRecord(rec walRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write(make([]byte, 4))
+
+	if err := gob.NewEncoder(&buf).Encode(rec); err != nil {
+		return nil, err
+	}
+
+	data := buf.Bytes()
+	if len(data)-4 > walMaxRecord {
+		return nil, fmt.Errorf("record %d bytes exceeds %d bytes", len(data)-4, walMaxRecord)
+	}
+	binary.BigEndian.PutUint32(data, uint32(len(data)-4))
+
+	return data, nil
+}
+
+// close закрывает файл журнала и возвращает первую ошибку его записи
+func (w *writeAheadLog) close() error {
+	if w == nil || w.file == nil {
+		return nil
+	}
+
+	if err := w.file.Close(); w.err == nil {
+		w.err = err
+	}
+
+	return w.err
+}
+
+// logSet записывает в журнал элемент до того, как он попадет в хранилище: при ошибке
+// вызывающий не меняет кеш. Вызывается под блокировкой на запись, после изменения хранилища
+// нужно вызвать maybeCompactWAL
+func (c *InMemoryCache) logSet(key string, item Item) error {
+	if c.wal == nil {
+		return nil
+	}
+
+	return c.wal.append(walRecord{Op: walSet, Key: key, Value: c.itemValue(item), CreatedAt: item.createdAt, Expiration: item.expiration, Idle: item.idle})
+}
+
+// logDelete записывает в журнал удаление ключа, вызывается под блокировкой на запись после удаления.
+// Удаление кодируется всегда, поэтому ошибка возможна только при записи файла: она запоминается
+// журналом и возвращается из Close
+func (c *InMemoryCache) logDelete(key string) {
+	if c.wal == nil {
+		return
+	}
+
+	if err := c.wal.append(walRecord{Op: walDelete, Key: key}); err != nil {
+		c.logger.Error("cache write-ahead log failed", "key", key, "err", err)
+	}
+	c.maybeCompactWAL()
+}
+
+// logFlush записывает в журнал удаление всех элементов, вызывается под блокировкой на запись
+func (c *InMemoryCache) logFlush() {
+	if err := c.wal.append(walRecord{Op: walFlush}); err != nil {
+		c.logger.Error("cache write-ahead log failed", "err", err)
+	}
+}
+
+// maybeCompactWAL сжимает журнал, когда записей в нем вдвое больше, чем элементов в кеше.
+// Прежний журнал при неудачном сжатии остается рабочим, сжатие повторится при следующей записи
+func (c *InMemoryCache) maybeCompactWAL() {
+	if c.wal == nil || c.wal.err != nil {
+		return
+	}
+
+	if c.wal.records > walCompactMin && c.wal.records > 2*len(c.cache) {
+		if err := c.compactWAL(); err != nil {
+			c.logger.Warn("cache write-ahead log compaction failed", "err", err)
+		}
+	}
+}
+
+// compactWAL заменяет журнал записями текущих живых элементов: новый журнал пишется
+// во временный файл и переименовывается, поэтому при ошибке прежний журнал не портится.
+// Вызывается под блокировкой на запись
+func (c *InMemoryCache) compactWAL() error {
+	w := c.wal
+
+	f, err := os.CreateTemp(filepath.Dir(w.path), filepath.Base(w.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	records := 0
+	bw := bufio.NewWriter(f)
+	for key, item := range c.cache {
+		if c.expired(item) {
+			continue
+		}
+
+		var data []byte
+		rec := walRecord{Op: walSet, Key: key, Value: c.itemValue(item), CreatedAt: item.createdAt, Expiration: item.expiration, Idle: item.idle}
+		if data, err = encodeWALRecord(rec); err != nil {
+			break
+		}
+		if _, err = bw.Write(data); err != nil {
+			break
+		}
+		records++
+	}
+
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), w.path)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+
+	// Переименованный файл остается открытым и становится новым журналом
+	if w.file != nil {
+		w.file.Close()
+	}
+	w.file, w.records = f, records
+
+	return nil
+}
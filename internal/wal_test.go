@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"InMemoryCache/internal/clock"
+)
+
+// writeWAL создает журнал с элементами keys и возвращает его путь и содержимое
+func writeWAL(t *testing.T, keys ...string) (string, []byte) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "cache.wal")
+	c, err := NewInMemoryCacheFromWAL(path, 0, 0)
+	if err != nil {
+		t.Fatalf("NewInMemoryCacheFromWAL: %v", err)
+	}
+	for _, key := range keys {
+		if err := c.SetE(key, key, NoExpiration); err != nil {
+			t.Fatalf("SetE(%s): %v", key, err)
+		}
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path, data
+}
+
+func TestWALReplayDropsTornTail(t *testing.T) {
+	path, data := writeWAL(t, "a", "b", "c")
+	if err := os.WriteFile(path, data[:len(data)-3], 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewInMemoryCacheFromWAL(path, 0, 0)
+	if err != nil {
+		t.Fatalf("NewInMemoryCacheFromWAL: %v", err)
+	}
+	defer c.Close()
+
+	if c.Count() != 2 {
+		t.Errorf("Count = %d, want 2 records before the torn one", c.Count())
+	}
+}
+
+func TestWALReplayFailsOnCorruptedLength(t *testing.T) {
+	path, data := writeWAL(t, "a", "b", "c")
+
+	// Старший байт длины второй записи: длина превышает walMaxRecord
+	second := 4 + binary.BigEndian.Uint32(data)
+	corrupted := append([]byte(nil), data...)
+	corrupted[second] ^= 0xff
+	if err := os.WriteFile(path, corrupted, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewInMemoryCacheFromWAL(path, 0, 0); err == nil {
+		t.Fatal("replay of a corrupted log succeeded")
+	}
+
+	// Журнал не сжимается после ошибки, поврежденные записи можно восстановить вручную
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, corrupted) {
+		t.Error("corrupted log was rewritten")
+	}
+}
+
+func TestWALReplayFailsOnShortRecordInside(t *testing.T) {
+	path, data := writeWAL(t, "a", "b", "c")
+
+	// Длина первой записи меньше настоящей: следующая запись читается со смещением
+	corrupted := append([]byte(nil), data...)
+	corrupted[3] -= 2
+	if err := os.WriteFile(path, corrupted, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewInMemoryCacheFromWAL(path, 0, 0); err == nil {
+		t.Fatal("replay of a corrupted log succeeded")
+	}
+}
+
+func TestWALReplaysSlidingExpiration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.wal")
+	fake := clock.NewFake(time.Unix(1000, 0))
+
+	// reopen закрывает кеш и восстанавливает его из журнала
+	reopen := func(c *InMemoryCache) *InMemoryCache {
+		t.Helper()
+
+		if c != nil {
+			if err := c.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+		}
+
+		c, err := NewInMemoryCacheFromWAL(path, 0, 0, WithClock(fake))
+		if err != nil {
+			t.Fatalf("NewInMemoryCacheFromWAL: %v", err)
+		}
+
+		return c
+	}
+
+	c := reopen(nil)
+	defer func() { c.Close() }()
+
+	if err := c.SetWithSliding("a", "v", time.Minute); err != nil {
+		t.Fatalf("SetWithSliding: %v", err)
+	}
+
+	// Каждое чтение продлевает элемент, после восстановления продление должно сохраниться
+	for range 3 {
+		fake.Advance(30 * time.Second)
+		if _, found := c.Get("a"); !found {
+			t.Fatal("sliding item expired before replay")
+		}
+
+		fake.Advance(20 * time.Second)
+		c = reopen(c)
+		if _, found := c.Peek("a"); !found {
+			t.Fatal("sliding item expired after replay")
+		}
+	}
+
+	fake.Advance(time.Minute)
+	if _, found := c.Peek("a"); found {
+		t.Error("sliding item outlived its idle period")
+	}
+}
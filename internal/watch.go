@@ -0,0 +1,211 @@
+package internal
+
+import (
+	"path"
+	"sync"
+	"sync/atomic"
+)
+
+// watchBuffer - размер буфера канала подписчика Watch
+const watchBuffer = 16
+
+// EventType - вид изменения ключа
+type EventType int
+
+const (
+	// EventSet - ключ записан
+	EventSet EventType = iota + 1
+	// EventEvicted - ключ удален, причина в Event.Reason
+	EventEvicted
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventSet:
+		return "set"
+	case EventEvicted:
+		return "evicted"
+	default:
+		return "unknown"
+	}
+}
+
+// Event - изменение ключа, на который подписан Watch или Subscribe.
+// Удаление, истечение и вытеснение различаются по Reason события EventEvicted
+type Event struct {
+	Type   EventType
+	Key    string
+	Value  interface{}
+	Reason EvictionReason
+}
+
+// eventHub рассылает изменения ключей подписчикам. Рассылка выполняется под блокировкой кеша
+// на запись, подписка и отписка - только под собственным мьютексом
+type eventHub struct {
+	mu       sync.Mutex
+	watchers map[string]map[*watcher]struct{}
+	patterns map[*watcher]string
+	count    atomic.Int64
+	max      int
+}
+
+type watcher struct {
+	ch     chan Event
+	cancel sync.Once
+}
+
+// Watch подписывается на изменения ключа key и возвращает канал событий и функцию отписки,
+// которую нужно вызвать, когда события больше не нужны: она закрывает канал, повторные вызовы
+// ничего не делают. Если подписчик не успевает читать и буфер канала заполнен,
+// новые события для него отбрасываются, запись в кеш не ждет подписчиков.
+// При превышении WithMaxWatchers возвращает ErrTooManyWatchers
+func (c *InMemoryCache) Watch(key string) (<-chan Event, func(), error) {
+	return c.events.watch(key)
+}
+
+func (h *eventHub) watch(key string) (<-chan Event, func(), error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.max > 0 && int(h.count.Load()) >= h.max {
+		return nil, nil, ErrTooManyWatchers
+	}
+
+	if h.watchers == nil {
+		h.watchers = make(map[string]map[*watcher]struct{})
+	}
+
+	if h.watchers[key] == nil {
+		h.watchers[key] = make(map[*watcher]struct{})
+	}
+
+	w := &watcher{ch: make(chan Event, watchBuffer)}
+	h.watchers[key][w] = struct{}{}
+	h.count.Add(1)
+
+	cancel := func() {
+		w.cancel.Do(func() {
+			h.unwatch(key, w)
+		})
+	}
+
+	return w.ch, cancel, nil
+}
+
+// Subscribe подписывается на изменения всех ключей, подходящих под шаблон pattern
+// в синтаксисе path.Match (например "user:*"), и возвращает канал событий и функцию отписки,
+// как Watch. Буфер и отбрасывание событий для медленного подписчика такие же, как у Watch,
+// подписка учитывается в WithMaxWatchers. Для неверного шаблона возвращает path.ErrBadPattern
+func (c *InMemoryCache) Subscribe(pattern string) (<-chan Event, func(), error) {
+	return c.events.subscribe(pattern)
+}
+
+func (h *eventHub) subscribe(pattern string) (<-chan Event, func(), error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, nil, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.max > 0 && int(h.count.Load()) >= h.max {
+		return nil, nil, ErrTooManyWatchers
+	}
+
+	if h.patterns == nil {
+		h.patterns = make(map[*watcher]string)
+	}
+
+	w := &watcher{ch: make(chan Event, watchBuffer)}
+	h.patterns[w] = pattern
+	h.count.Add(1)
+
+	cancel := func() {
+		w.cancel.Do(func() {
+			h.unsubscribe(w)
+		})
+	}
+
+	return w.ch, cancel, nil
+}
+
+func (h *eventHub) unsubscribe(w *watcher) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.patterns, w)
+	h.count.Add(-1)
+	close(w.ch)
+}
+
+func (h *eventHub) unwatch(key string, w *watcher) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.watchers[key], w)
+	if len(h.watchers[key]) == 0 {
+		delete(h.watchers, key)
+	}
+	h.count.Add(-1)
+
+	// Рассылка идет под тем же мьютексом, поэтому в закрытый канал никто не пишет
+	close(w.ch)
+}
+
+// active сообщает, есть ли подписчики, чтобы не готовить события впустую
+func (h *eventHub) active() bool {
+	return h.count.Load() != 0
+}
+
+// publish отправляет событие подписчикам ключа и подходящих шаблонов, не дожидаясь медленных
+func (h *eventHub) publish(e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for w := range h.watchers[e.Key] {
+		w.send(e)
+	}
+
+	for w, pattern := range h.patterns {
+		if matched, _ := path.Match(pattern, e.Key); matched {
+			w.send(e)
+		}
+	}
+}
+
+func (w *watcher) send(e Event) {
+	select {
+	case w.ch <- e:
+	default:
+	}
+}
+
+// publishFlush отправляет событие удаления подписчикам ключей из flushed
+func (h *eventHub) publishFlush(flushed map[string]Item, value func(Item) interface{}) {
+	if !h.active() {
+		return
+	}
+
+	h.mu.Lock()
+	keys := make([]string, 0, len(h.watchers))
+	for k := range h.watchers {
+		keys = append(keys, k)
+	}
+	subscribed := len(h.patterns) > 0
+	h.mu.Unlock()
+
+	// Подписчикам шаблонов подходит любой ключ, поэтому рассылаем все удаленные
+	if subscribed {
+		for k, i := range flushed {
+			h.publish(Event{Type: EventEvicted, Key: k, Value: value(i), Reason: ReasonFlushed})
+		}
+
+		return
+	}
+
+	for _, k := range keys {
+		if i, found := flushed[k]; found {
+			h.publish(Event{Type: EventEvicted, Key: k, Value: value(i), Reason: ReasonFlushed})
+		}
+	}
+}
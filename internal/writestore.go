@@ -0,0 +1,467 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Store - основное хранилище за кешем, например таблица базы данных. Load возвращает found = false
+// для отсутствующего ключа, а Delete отсутствующего ключа не считается ошибкой
+type Store interface {
+	Load(ctx context.Context, key string) (value interface{}, found bool, err error)
+	Save(ctx context.Context, key string, value interface{}) error
+	Delete(ctx context.Context, key string) error
+}
+
+// BatchStore реализуют хранилища, которые сохраняют несколько значений одним запросом.
+// В режиме WithWriteBehind такое хранилище получает накопленные записи одним вызовом SaveMany
+type BatchStore interface {
+	Store
+	SaveMany(ctx context.Context, values map[string]interface{}) error
+}
+
+// WriteCache - кеш перед хранилищем Store: промах Get загружает значение из хранилища,
+// а записи и удаления передаются в хранилище сразу (write-through, по-умолчанию) или
+// копятся и сохраняются пачками в фоне (WithWriteBehind)
+type WriteCache struct {
+	cache    Cache
+	store    Store
+	loadTTL  time.Duration
+	retries  int
+	backoff  time.Duration
+	onError  func(key string, err error)
+	interval time.Duration
+	batch    int
+
+	mu      sync.Mutex
+	pending map[string]pendingWrite
+	// flushing - изменения, которые flushPending сохраняет прямо сейчас
+	flushing map[string]pendingWrite
+	closed   bool
+	wake     chan struct{}
+	done     chan struct{}
+	stopped  chan struct{}
+	flushes  []chan struct{}
+	// loads объединяет промахи GetCtx, fetches - загрузки FetchCtx: у них разные результаты промаха
+	loads   flightGroup
+	fetches flightGroup
+}
+
+// pendingWrite - отложенная запись или удаление ключа
+type pendingWrite struct {
+	value   interface{}
+	deleted bool
+}
+
+// WriteOption настраивает WriteCache
+type WriteOption func(*WriteCache)
+
+// WithWriteBehind включает отложенную запись: Set и Delete меняют только кеш и ставят изменение
+// в очередь, а фоновая горутина раз в interval или при накоплении batch ключей сохраняет их в хранилище.
+// Повторные изменения одного ключа в очереди объединяются, в хранилище попадает последнее
+func WithWriteBehind(interval time.Duration, batch int) WriteOption {
+	return func(c *WriteCache) {
+		c.interval = interval
+		c.batch = batch
+	}
+}
+
+// WithWriteRetry повторяет неудачную запись в хранилище до attempts раз, удваивая паузу
+// начиная с backoff. В режиме write-through повторы задерживают Set
+func WithWriteRetry(attempts int, backoff time.Duration) WriteOption {
+	return func(c *WriteCache) {
+		c.retries = attempts
+		c.backoff = backoff
+	}
+}
+
+// WithWriteErrorHandler задает обработчик ошибок записи, которые некому вернуть: отложенных записей
+// и Set без ошибки в режиме write-through. После ошибки значение остается только в кеше
+func WithWriteErrorHandler(fn func(key string, err error)) WriteOption {
+	return func(c *WriteCache) {
+		c.onError = fn
+	}
+}
+
+// WithLoadTTL задает время жизни значений, загруженных из хранилища при промахе.
+// По-умолчанию - время жизни кеша по-умолчанию
+func WithLoadTTL(ttl time.Duration) WriteOption {
+	return func(c *WriteCache) {
+		c.loadTTL = ttl
+	}
+}
+
+// NewWriteCache создает кеш cache перед хранилищем store. Close WriteCache сохраняет отложенные
+// записи, но не закрывает cache и store
+func NewWriteCache(cache Cache, store Store, opts ...WriteOption) *WriteCache {
+	c := &WriteCache{cache: cache, store: store, loadTTL: DefaultExpiration}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.interval > 0 {
+		c.pending = make(map[string]pendingWrite)
+		c.wake = make(chan struct{}, 1)
+		c.done = make(chan struct{})
+		c.stopped = make(chan struct{})
+		go c.run()
+	}
+
+	return c
+}
+
+func (c *WriteCache) Get(key string) (interface{}, bool) {
+	value, found, _ := c.GetCtx(context.Background(), key)
+	return value, found
+}
+
+// GetCtx возвращает значение из кеша, а при промахе загружает его из хранилища и записывает в кеш.
+// Одновременные промахи по одному ключу читают хранилище один раз, ошибка хранилища возвращается как есть.
+// С WithWriteBehind промах сначала проверяет очередь: ключ, удаление которого еще не сохранено,
+// отсутствует, а для отложенной записи возвращается ее значение, а не прежнее из хранилища
+func (c *WriteCache) GetCtx(ctx context.Context, key string) (interface{}, bool, error) {
+	if value, found := c.cache.Get(key); found {
+		return value, true, nil
+	}
+
+	if w, queued := c.queued(key); queued {
+		return w.value, !w.deleted, nil
+	}
+
+	value, _, err := c.loads.do(ctx, key, func() (interface{}, time.Duration, error) {
+		value, found, err := c.store.Load(ctx, key)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !found {
+			return nil, 0, errRemoteMiss
+		}
+
+		return c.cacheLoaded(key, value)
+	})
+	if errors.Is(err, errRemoteMiss) {
+		return nil, false, nil
+	}
+
+	return value, err == nil, err
+}
+
+// FetchCtx возвращает значение из кеша или хранилища, а если его нет и там, загружает его loader
+// и записывает как SetCtx на ttl
+func (c *WriteCache) FetchCtx(ctx context.Context, key string, ttl time.Duration, loader func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	value, found, err := c.GetCtx(ctx, key)
+	if err != nil || found {
+		return value, err
+	}
+
+	value, _, err = c.fetches.do(ctx, key, func() (interface{}, time.Duration, error) {
+		value, err := loader(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		return value, 0, c.SetCtx(ctx, key, value, ttl)
+	})
+
+	return value, err
+}
+
+// queued возвращает отложенное изменение ключа, в том числе сохраняемое в этот момент
+func (c *WriteCache) queued(key string) (pendingWrite, bool) {
+	if c.pending == nil {
+		return pendingWrite{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.queuedLocked(key)
+}
+
+// queuedLocked - см. queued, вызывается под c.mu
+func (c *WriteCache) queuedLocked(key string) (pendingWrite, bool) {
+	if w, found := c.pending[key]; found {
+		return w, true
+	}
+
+	w, found := c.flushing[key]
+
+	return w, found
+}
+
+// cacheLoaded записывает в кеш значение, загруженное из хранилища. Если за время загрузки
+// ключ изменили или удалили, в кеше уже новое значение, и загруженное отбрасывается
+func (c *WriteCache) cacheLoaded(key string, value interface{}) (interface{}, time.Duration, error) {
+	if c.pending == nil {
+		c.cache.Set(key, value, c.loadTTL)
+		return value, 0, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if w, queued := c.queuedLocked(key); queued {
+		if w.deleted {
+			return nil, 0, errRemoteMiss
+		}
+		return w.value, 0, nil
+	}
+
+	c.cache.Set(key, value, c.loadTTL)
+
+	return value, 0, nil
+}
+
+func (c *WriteCache) Has(key string) bool {
+	_, found := c.Get(key)
+	return found
+}
+
+// Count возвращает количество элементов кеша, хранилище не учитывается
+func (c *WriteCache) Count() int {
+	return c.cache.Count()
+}
+
+// Set записывает значение как SetCtx, ошибка записи передается в WithWriteErrorHandler
+func (c *WriteCache) Set(key string, value interface{}, duration time.Duration) {
+	if err := c.SetCtx(context.Background(), key, value, duration); err != nil {
+		c.reportError(key, err)
+	}
+}
+
+// SetCtx в режиме write-through сохраняет значение в хранилище и затем записывает в кеш,
+// а если сохранить не удалось, удаляет ключ из кеша и возвращает ошибку. С WithWriteBehind
+// записывает в кеш и ставит сохранение в очередь, после Close возвращает ErrClosed
+func (c *WriteCache) SetCtx(ctx context.Context, key string, value interface{}, duration time.Duration) error {
+	if c.pending == nil {
+		if err := c.retry(ctx, func() error { return c.store.Save(ctx, key, value) }); err != nil {
+			c.cache.Delete(key)
+			return err
+		}
+
+		c.cache.Set(key, value, duration)
+
+		return nil
+	}
+
+	return c.enqueue(key, pendingWrite{value: value}, func() {
+		c.cache.Set(key, value, duration)
+	})
+}
+
+func (c *WriteCache) Delete(key string) error {
+	return c.DeleteCtx(context.Background(), key)
+}
+
+// DeleteCtx удаляет ключ из кеша и хранилища (с WithWriteBehind - ставит удаление в очередь).
+// Отсутствие ключа в кеше ошибкой не считается
+func (c *WriteCache) DeleteCtx(ctx context.Context, key string) error {
+	if c.pending == nil {
+		c.cache.Delete(key)
+		return c.retry(ctx, func() error { return c.store.Delete(ctx, key) })
+	}
+
+	return c.enqueue(key, pendingWrite{deleted: true}, func() {
+		c.cache.Delete(key)
+	})
+}
+
+// Flush очищает только кеш, хранилище и отложенные записи не затрагиваются
+func (c *WriteCache) Flush() {
+	c.cache.Flush()
+}
+
+// Sync ждет, пока отложенные до вызова записи будут сохранены в хранилище или отброшены с ошибкой,
+// без WithWriteBehind возвращается сразу
+func (c *WriteCache) Sync(ctx context.Context) error {
+	if c.pending == nil {
+		return nil
+	}
+
+	flushed := make(chan struct{})
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	c.flushes = append(c.flushes, flushed)
+	c.mu.Unlock()
+	c.signal()
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close сохраняет отложенные записи и останавливает фоновую горутину. Повторный вызов ничего не делает
+func (c *WriteCache) Close() error {
+	if c.pending == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.mu.Unlock()
+
+	close(c.done)
+	<-c.stopped
+
+	return nil
+}
+
+// enqueue применяет изменение ключа к кешу через apply и ставит его в очередь на сохранение.
+// Оба действия выполняются под одной блокировкой, чтобы кеш и очередь получали изменения в одном порядке
+func (c *WriteCache) enqueue(key string, w pendingWrite, apply func()) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	apply()
+	c.pending[key] = w
+	full := c.batch > 0 && len(c.pending) >= c.batch
+	c.mu.Unlock()
+
+	if full {
+		c.signal()
+	}
+
+	return nil
+}
+
+func (c *WriteCache) signal() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run сохраняет отложенные записи по таймеру и по сигналу, при Close сохраняет оставшиеся
+func (c *WriteCache) run() {
+	defer close(c.stopped)
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			c.flushPending()
+			return
+		case <-ticker.C:
+		case <-c.wake:
+		}
+
+		c.flushPending()
+	}
+}
+
+// flushPending сохраняет все накопленные изменения и оповещает ожидающих Sync
+func (c *WriteCache) flushPending() {
+	c.mu.Lock()
+	pending, flushes := c.pending, c.flushes
+	c.pending, c.flushes, c.flushing = make(map[string]pendingWrite), nil, pending
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		c.flushing = nil
+		c.mu.Unlock()
+
+		for _, ch := range flushes {
+			close(ch)
+		}
+	}()
+
+	ctx := context.Background()
+	saves := make(map[string]interface{})
+
+	for key, w := range pending {
+		if !w.deleted {
+			saves[key] = w.value
+			continue
+		}
+
+		if err := c.retry(ctx, func() error { return c.store.Delete(ctx, key) }); err != nil {
+			c.reportError(key, err)
+		}
+	}
+
+	if batch, ok := c.store.(BatchStore); ok {
+		for _, chunk := range c.chunks(saves) {
+			if err := c.retry(ctx, func() error { return batch.SaveMany(ctx, chunk) }); err != nil {
+				for key := range chunk {
+					c.reportError(key, err)
+				}
+			}
+		}
+	} else {
+		for key, value := range saves {
+			if err := c.retry(ctx, func() error { return c.store.Save(ctx, key, value) }); err != nil {
+				c.reportError(key, err)
+			}
+		}
+	}
+}
+
+// chunks делит сохраняемые значения на пачки не больше WithWriteBehind batch
+func (c *WriteCache) chunks(values map[string]interface{}) []map[string]interface{} {
+	if len(values) == 0 {
+		return nil
+	}
+
+	if c.batch <= 0 || len(values) <= c.batch {
+		return []map[string]interface{}{values}
+	}
+
+	var chunks []map[string]interface{}
+	chunk := make(map[string]interface{}, c.batch)
+	for key, value := range values {
+		chunk[key] = value
+		if len(chunk) == c.batch {
+			chunks = append(chunks, chunk)
+			chunk = make(map[string]interface{}, c.batch)
+		}
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks
+}
+
+// retry выполняет op, повторяя его по WithWriteRetry, пока не истек ctx
+func (c *WriteCache) retry(ctx context.Context, op func() error) error {
+	err := op()
+
+	pause := c.backoff
+	for attempt := 0; err != nil && attempt < c.retries; attempt++ {
+		select {
+		case <-time.After(pause):
+		case <-ctx.Done():
+			return err
+		}
+
+		pause *= 2
+		err = op()
+	}
+
+	return err
+}
+
+func (c *WriteCache) reportError(key string, err error) {
+	if c.onError != nil {
+		c.onError(key, err)
+	}
+}
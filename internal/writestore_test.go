@@ -0,0 +1,198 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memStore - хранилище в памяти, считает сохранения и загрузки
+type memStore struct {
+	mu      sync.Mutex
+	values  map[string]interface{}
+	saves   map[string]int
+	loads   int
+	saveErr error
+	// deleting, если задан, получает сигнал в начале Delete и ждет release
+	deleting chan struct{}
+	release  chan struct{}
+}
+
+func newMemStore() *memStore {
+	return &memStore{values: make(map[string]interface{}), saves: make(map[string]int)}
+}
+
+func (s *memStore) Load(_ context.Context, key string) (interface{}, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.loads++
+	value, found := s.values[key]
+
+	return value, found, nil
+}
+
+func (s *memStore) Save(_ context.Context, key string, value interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.saveErr != nil {
+		return s.saveErr
+	}
+	s.values[key] = value
+	s.saves[key]++
+
+	return nil
+}
+
+func (s *memStore) Delete(_ context.Context, key string) error {
+	if s.deleting != nil {
+		s.deleting <- struct{}{}
+		<-s.release
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.values, key)
+
+	return nil
+}
+
+func (s *memStore) get(key string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, found := s.values[key]
+
+	return value, found
+}
+
+func TestWriteCacheWriteThroughRollback(t *testing.T) {
+	store := newMemStore()
+	c := NewWriteCache(NewInMemoryCache(0, 0), store)
+
+	c.Set("key", "old", NoExpiration)
+
+	store.saveErr = errors.New("store is down")
+	if err := c.SetCtx(context.Background(), "key", "new", NoExpiration); !errors.Is(err, store.saveErr) {
+		t.Fatalf("SetCtx err = %v, want %v", err, store.saveErr)
+	}
+
+	// Ключ удаляется из кеша, следующий Get читает хранилище
+	store.saveErr = nil
+	if value, _ := c.Get("key"); value != "old" {
+		t.Errorf("Get = %v, want the value still in the store", value)
+	}
+	if store.loads != 1 {
+		t.Errorf("store loads = %d, want 1", store.loads)
+	}
+}
+
+func TestWriteCacheWriteBehindMergesQueuedWrites(t *testing.T) {
+	store := newMemStore()
+	c := NewWriteCache(NewInMemoryCache(0, 0), store, WithWriteBehind(time.Hour, 0))
+	defer c.Close()
+
+	c.Set("key", 1, NoExpiration)
+	c.Set("key", 2, NoExpiration)
+	c.Set("other", 3, NoExpiration)
+
+	if _, found := store.get("key"); found {
+		t.Fatal("write-behind saved before Sync")
+	}
+
+	if err := c.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	if value, _ := store.get("key"); value != 2 || store.saves["key"] != 1 {
+		t.Errorf("store key = %v after %d saves, want 2 after 1 save", value, store.saves["key"])
+	}
+	if value, _ := store.get("other"); value != 3 {
+		t.Errorf("store other = %v, want 3", value)
+	}
+}
+
+func TestWriteCacheCloseFlushes(t *testing.T) {
+	store := newMemStore()
+	c := NewWriteCache(NewInMemoryCache(0, 0), store, WithWriteBehind(time.Hour, 0))
+
+	c.Set("key", 1, NoExpiration)
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if value, _ := store.get("key"); value != 1 {
+		t.Errorf("store key = %v after Close, want 1", value)
+	}
+	if err := c.SetCtx(context.Background(), "key", 2, NoExpiration); !errors.Is(err, ErrClosed) {
+		t.Errorf("SetCtx after Close err = %v, want %v", err, ErrClosed)
+	}
+	if err := c.Sync(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("Sync after Close err = %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestWriteCacheReadWhileQueued(t *testing.T) {
+	store := newMemStore()
+	store.values["deleted"] = "old"
+	store.values["updated"] = "old"
+
+	cache := NewInMemoryCache(0, 0)
+	c := NewWriteCache(cache, store, WithWriteBehind(time.Hour, 0))
+	defer c.Close()
+
+	c.Delete("deleted")
+	if value, found := c.Get("deleted"); found {
+		t.Errorf("Get of a key with a queued delete = %v, want a miss", value)
+	}
+
+	// Отложенная запись, вытесненная из кеша, читается из очереди, а не из хранилища
+	c.Set("updated", "new", NoExpiration)
+	cache.Delete("updated")
+	if value, _ := c.Get("updated"); value != "new" {
+		t.Errorf("Get of an evicted queued write = %v, want new", value)
+	}
+
+	if store.loads != 0 {
+		t.Errorf("store loads = %d, want 0", store.loads)
+	}
+
+	if err := c.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if _, found := c.Get("deleted"); found {
+		t.Error("deleted key came back after Sync")
+	}
+}
+
+func TestWriteCacheReadWhileDeleteIsSaved(t *testing.T) {
+	store := newMemStore()
+	store.values["key"] = "old"
+	store.deleting = make(chan struct{})
+	store.release = make(chan struct{})
+
+	c := NewWriteCache(NewInMemoryCache(0, 0), store, WithWriteBehind(time.Hour, 0))
+	defer c.Close()
+
+	c.Delete("key")
+	synced := make(chan error, 1)
+	go func() { synced <- c.Sync(context.Background()) }()
+
+	// Удаление уже забрано из очереди, но хранилище еще не изменено
+	<-store.deleting
+	if value, found := c.Get("key"); found {
+		t.Errorf("Get during the flush = %v, want a miss", value)
+	}
+	close(store.release)
+
+	if err := <-synced; err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if _, found := c.Get("key"); found {
+		t.Error("deleted key came back after the flush")
+	}
+}